@@ -1,16 +1,23 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"github.com/paulschick/disclosureupdater/cmds"
+	"github.com/paulschick/disclosureupdater/common/constants"
 	"github.com/paulschick/disclosureupdater/common/logger"
+	"github.com/paulschick/disclosureupdater/compliance"
 	"github.com/paulschick/disclosureupdater/config"
+	"github.com/paulschick/disclosureupdater/export"
 	"github.com/paulschick/disclosureupdater/model"
+	"github.com/paulschick/disclosureupdater/ptr"
+	"github.com/paulschick/disclosureupdater/s3client"
 	"github.com/urfave/cli/v2"
 	"go.uber.org/zap"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"strings"
 )
 
 // main
@@ -39,6 +46,14 @@ func main() {
 		Name:                   "Disclosure Download CLI",
 		Version:                "0.0.1",
 		UseShortOptionHandling: true,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "profile",
+				Usage:   "Configuration profile of the S3 settings",
+				EnvVars: []string{"DISCLOSURECLI_PROFILE"},
+				Value:   constants.DefaultProfile,
+			},
+		},
 		Commands: []*cli.Command{
 			{
 				Name:    "initialize",
@@ -48,7 +63,7 @@ func main() {
 					"Use this command to create the initial environment.",
 				Action: func(cCtx *cli.Context) error {
 					log.Info("Initializing environment")
-					return initialize(commonDirs)
+					return initialize(commonDirs, config.ProfileFromCtx(cCtx))
 				},
 			},
 			{
@@ -57,9 +72,10 @@ func main() {
 				Usage:     "Initialize environment configuration",
 				UsageText: "Write S3 configuration values to file",
 				Action: func(cCtx *cli.Context) error {
-					fmt.Printf("Updating S3 configuration\n")
+					profile := config.ProfileFromCtx(cCtx)
+					fmt.Printf("Updating S3 configuration of profile %s\n", profile)
 					s3Profile := config.S3ProfileFromCtx(cCtx)
-					err := config.UpdateS3Config(s3Profile, commonDirs)
+					err := config.UpdateS3Config(profile, s3Profile, commonDirs)
 					if err != nil {
 						return err
 					}
@@ -97,6 +113,81 @@ func main() {
 						Usage:   "S3 secret key",
 						EnvVars: []string{"S3_SECRET_KEY"},
 					},
+					&cli.StringFlag{
+						Name:    "s3-key-prefix",
+						Usage:   "Prefix of every object key",
+						EnvVars: []string{"S3_KEY_PREFIX"},
+					},
+					&cli.StringFlag{
+						Name: "s3-key-template",
+						Usage: "Object key of disclosure PDFs, with {year}, {filingType}, {docId}, {stateDst}, {last}, " +
+							"{first} and {fileName} placeholders",
+						EnvVars: []string{"S3_KEY_TEMPLATE"},
+						Value:   s3client.DefaultKeyTemplate,
+					},
+					&cli.Int64Flag{
+						Name:    "s3-part-size",
+						Usage:   "Part size of multipart uploads in MiB, files smaller than a part are uploaded at once",
+						EnvVars: []string{"S3_PART_SIZE"},
+						Value:   s3client.DefaultPartSize,
+					},
+					&cli.IntFlag{
+						Name:    "s3-upload-concurrency",
+						Usage:   "Number of parts of a file uploaded at the same time",
+						EnvVars: []string{"S3_UPLOAD_CONCURRENCY"},
+						Value:   s3client.DefaultUploadConcurrency,
+					},
+					&cli.StringFlag{
+						Name:    "s3-checksum",
+						Usage:   "Checksum of uploads, crc32c, or md5 for hosts without additional checksum support",
+						EnvVars: []string{"S3_CHECKSUM"},
+						Value:   s3client.ChecksumCrc32c,
+					},
+					&cli.IntFlag{
+						Name:    "s3-max-attempts",
+						Usage:   "Maximum number of attempts of each S3 request",
+						EnvVars: []string{"S3_MAX_ATTEMPTS"},
+						Value:   s3client.DefaultMaxAttempts,
+					},
+					&cli.StringFlag{
+						Name:    "s3-encryption",
+						Usage:   "Server-side encryption of uploads, sse-s3 or sse-kms, the bucket's default if not set",
+						EnvVars: []string{"S3_ENCRYPTION"},
+					},
+					&cli.StringFlag{
+						Name:    "s3-kms-key-id",
+						Usage:   "ID, ARN or alias of the KMS key of sse-kms encryption",
+						EnvVars: []string{"S3_KMS_KEY_ID"},
+					},
+					&cli.StringSliceFlag{
+						Name:  "s3-tag",
+						Usage: "Tag of every uploaded object as key=value, can be repeated",
+					},
+					&cli.StringFlag{
+						Name:    "s3-aws-profile",
+						Usage:   "Named profile of the shared AWS configuration, used without an API key",
+						EnvVars: []string{"S3_AWS_PROFILE"},
+					},
+					&cli.StringFlag{
+						Name:    "s3-role-arn",
+						Usage:   "ARN of a role assumed with the profile's credentials",
+						EnvVars: []string{"S3_ROLE_ARN"},
+					},
+					&cli.StringFlag{
+						Name:    "s3-external-id",
+						Usage:   "External ID required by the trust policy of the role",
+						EnvVars: []string{"S3_EXTERNAL_ID"},
+					},
+					&cli.StringFlag{
+						Name:    "s3-role-session-name",
+						Usage:   "Session name of the assumed role",
+						EnvVars: []string{"S3_ROLE_SESSION_NAME"},
+					},
+					&cli.BoolFlag{
+						Name:    "s3-path-style",
+						Usage:   "Address buckets by path instead of by hostname, for hosts like MinIO",
+						EnvVars: []string{"S3_PATH_STYLE"},
+					},
 				},
 			},
 			{
@@ -127,9 +218,11 @@ func main() {
 				},
 			},
 			{
-				Name:      "upload-s3",
-				Usage:     "Upload PDFs to S3 that are not present",
-				UsageText: "disclosurecli upload-s3\n",
+				Name:  "upload-s3",
+				Usage: "Upload PDFs, or other artifact classes, to S3 that are not present",
+				UsageText: "Upload the files of each artifact class with the prefix, content type and storage class\n" +
+					"set in the classes of the S3 configuration\n" +
+					"   disclosurecli upload-s3 --class images --class csv\n",
 				Action: func(cCtx *cli.Context) error {
 					return cmds.UploadPdfs(commonDirs)(cCtx)
 				},
@@ -139,6 +232,85 @@ func main() {
 						Aliases: []string{"u"},
 						Usage:   "Update the list of bucket items",
 					},
+					&cli.BoolFlag{
+						Name:  "searchable",
+						Usage: "Upload the searchable PDFs created by make-searchable",
+					},
+					&cli.StringSliceFlag{
+						Name:  "class",
+						Usage: "Artifact classes to upload: " + strings.Join(s3client.ArtifactClassNames, ", ") + " or all, defaults to disclosures",
+					},
+				},
+			},
+			{
+				Name:  "migrate-s3-keys",
+				Usage: "Copy objects uploaded under their local path to the configured key scheme",
+				UsageText: "Copy objects whose key is the absolute local path of the file to the key built from\n" +
+					"the key prefix and template of the S3 configuration\n" +
+					"   disclosurecli migrate-s3-keys --dry-run\n",
+				Action: func(cCtx *cli.Context) error {
+					return cmds.MigrateS3Keys(commonDirs)(cCtx)
+				},
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Print the objects that would be copied without changing the bucket",
+					},
+					&cli.BoolFlag{
+						Name:  "delete",
+						Usage: "Delete the objects with the old keys after copying them",
+					},
+				},
+			},
+			{
+				Name:  "sync",
+				Usage: "Sync the data folders with S3",
+				UsageText: "Upload new and changed files, download new and changed objects, or both,\n" +
+					"comparing files and objects by key, size and checksum\n" +
+					"   disclosurecli sync --mode pull --folder disclosures --folder csv\n",
+				Action: func(cCtx *cli.Context) error {
+					return cmds.SyncS3(commonDirs)(cCtx)
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "mode",
+						Aliases: []string{"m"},
+						Usage:   "Sync mode: push, pull or both",
+						Value:   s3client.SyncPush,
+					},
+					&cli.StringSliceFlag{
+						Name:    "folder",
+						Aliases: []string{"f"},
+						Usage:   "Folders to sync: " + strings.Join(s3client.ArtifactClassNames, ", ") + ", defaults to disclosures",
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Print the changes without applying them",
+					},
+					&cli.BoolFlag{
+						Name:  "delete",
+						Usage: "Delete objects missing from the folder when pushing, or files missing from the bucket when pulling",
+					},
+				},
+			},
+			{
+				Name:  "verify-s3",
+				Usage: "Verify the uploaded objects against the local files",
+				UsageText: "Compare the local files with their objects by size and SHA-256, or MD5 for objects uploaded\n" +
+					"without SHA-256, and report missing, corrupt, orphaned and unverified objects\n" +
+					"   disclosurecli verify-s3 --class disclosures --reupload\n",
+				Action: func(cCtx *cli.Context) error {
+					return cmds.VerifyS3(commonDirs)(cCtx)
+				},
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{
+						Name:  "class",
+						Usage: "Artifact classes to verify: " + strings.Join(s3client.ArtifactClassNames, ", ") + " or all, defaults to all",
+					},
+					&cli.BoolFlag{
+						Name:  "reupload",
+						Usage: "Upload missing and corrupt files again",
+					},
 				},
 			},
 			{
@@ -184,6 +356,324 @@ func main() {
 						Usage:   "Limit the number of images to process",
 						Value:   0,
 					},
+					&cli.StringSliceFlag{
+						Name:    "format",
+						Aliases: []string{"f"},
+						Usage: "Output formats to write: tsv, hocr, alto\n" +
+							"   disclosurecli ocr-images --format tsv,hocr,alto\n",
+					},
+					&cli.StringSliceFlag{
+						Name:    "preprocess",
+						Aliases: []string{"p"},
+						Usage: "Preprocessing steps to run before OCR: grayscale, binarize, deskew, border, despeckle, all\n" +
+							"   disclosurecli ocr-images --preprocess binarize,deskew\n",
+					},
+					&cli.BoolFlag{
+						Name:  "preprocess-stats",
+						Usage: "OCR each image before and after --preprocess and record the confidence in csv/preprocess_stats.csv",
+					},
+					&cli.IntFlag{
+						Name:  "despeckle-size",
+						Usage: "Largest group of dark pixels removed by despeckle",
+					},
+					&cli.Float64Flag{
+						Name:  "max-skew",
+						Usage: "Largest skew angle in degrees corrected by deskew",
+					},
+					&cli.BoolFlag{
+						Name: "retry-failed",
+						Usage: "Only process the images in the failure ledger csv/ocr_failures.csv\n" +
+							"   disclosurecli ocr-images --retry-failed\n",
+					},
+					&cli.IntFlag{
+						Name:  "max-attempts",
+						Usage: "Stop retrying an image after it failed this many times, 0 retries forever",
+						Value: constants.MaxOcrAttempts,
+					},
+					&cli.Float64Flag{
+						Name: "retry-below",
+						Usage: "Re-OCR pages whose mean word confidence is below this value and keep the best result\n" +
+							"   disclosurecli ocr-images --retry-below 75\n",
+					},
+					&cli.Float64Flag{
+						Name:  "retry-dpi",
+						Usage: "DPI to render pages at when re-OCR'ing low confidence pages",
+						Value: 400,
+					},
+					&cli.IntFlag{
+						Name:  "retry-psm",
+						Usage: "Tesseract page segmentation mode used when re-OCR'ing low confidence pages",
+						Value: 6,
+					},
+					&cli.StringSliceFlag{
+						Name:  "retry-preprocess",
+						Usage: "Preprocessing steps used when re-OCR'ing low confidence pages",
+						Value: cli.NewStringSlice("binarize", "deskew"),
+					},
+				},
+			},
+			{
+				Name:  "parse-ptr",
+				Usage: "Extract PTR transactions from the PDF text layer or OCR output",
+				UsageText: "Parse the PTR transaction tables from the text layer of e-filed PDFs, or reconstruct them\n" +
+					"from the OCR word boxes, and write one record per trade to csv/transactions.csv\n" +
+					"   disclosurecli parse-ptr --method auto\n",
+				Action: func(cCtx *cli.Context) error {
+					return cmds.ParsePtr(commonDirs)(cCtx)
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "target",
+						Usage: "Read the PDFs and OCR output from, and write the transactions to, the local folders (local) or the bucket (s3)",
+						Value: cmds.TargetLocal,
+					},
+					&cli.StringFlag{
+						Name:    "method",
+						Aliases: []string{"m"},
+						Usage:   "Extraction method: text, ocr or auto (text layer if present, otherwise OCR)",
+						Value:   cmds.ParseMethodAuto,
+					},
+					&cli.StringFlag{
+						Name:  "securities",
+						Usage: "Security master CSV with ticker, name and assetType columns, defaults to csv/securities.csv",
+					},
+					&cli.Float64Flag{
+						Name:  "min-match",
+						Usage: "Minimum name similarity (0-1) to take a ticker from the security master",
+						Value: ptr.DefaultMinMatchScore,
+					},
+					&cli.IntFlag{
+						Name:    "limit",
+						Aliases: []string{"l"},
+						Usage:   "Limit the number of PTRs to parse",
+						Value:   0,
+					},
+				},
+			},
+			{
+				Name:  "update-members",
+				Usage: "Assign stable member IDs across years and name variants",
+				UsageText: "Match the filings of the disclosure index to members by normalized name and district\n" +
+					"and write the member registry to csv/members.csv\n" +
+					"   disclosurecli update-members\n",
+				Action: func(cCtx *cli.Context) error {
+					return cmds.UpdateMembers(commonDirs)(cCtx)
+				},
+			},
+			{
+				Name:  "link-filings",
+				Usage: "Link amendments to the filings they amend",
+				UsageText: "Group the disclosure index by member, year and type, link amendments to their original\n" +
+					"filing and write csv/filings.csv and the transactions of the latest version of every PTR\n" +
+					"   disclosurecli link-filings\n",
+				Action: func(cCtx *cli.Context) error {
+					return cmds.LinkFilings(commonDirs)(cCtx)
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "target",
+						Usage: "Read the transactions from, and write the linked filings to, the local folders (local) or the bucket (s3)",
+						Value: cmds.TargetLocal,
+					},
+				},
+			},
+			{
+				Name:  "compliance-report",
+				Usage: "Flag PTR transactions reported after the STOCK Act deadlines",
+				UsageText: "Compare the transaction and notification dates from parse-ptr with the filing dates\n" +
+					"of the disclosure index and report late filings by member and year\n" +
+					"   disclosurecli compliance-report --format json\n",
+				Action: func(cCtx *cli.Context) error {
+					return cmds.ComplianceReport(commonDirs)(cCtx)
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "target",
+						Usage: "Read the transactions from, and write the report to, the local folders (local) or the bucket (s3)",
+						Value: cmds.TargetLocal,
+					},
+					&cli.StringFlag{
+						Name:    "format",
+						Aliases: []string{"f"},
+						Usage:   "Output format: csv or json",
+						Value:   cmds.ReportFormatCsv,
+					},
+					&cli.IntFlag{
+						Name:  "transaction-days",
+						Usage: "Days after the transaction a trade must be reported in",
+						Value: compliance.DefaultTransactionDays,
+					},
+					&cli.IntFlag{
+						Name:  "notice-days",
+						Usage: "Days after the filer was notified of a trade it must be reported in",
+						Value: compliance.DefaultNoticeDays,
+					},
+				},
+			},
+			{
+				Name:  "export-index",
+				Usage: "Export the disclosure index with PDF URLs and pipeline state",
+				UsageText: "Write every filing of the disclosure index with its PDF URL, local path, member ID\n" +
+					"and pipeline state to CSV, JSON Lines or Parquet\n" +
+					"   disclosurecli export-index --format parquet --year 2023 --filing-type P\n",
+				Action: func(cCtx *cli.Context) error {
+					return cmds.ExportIndex(commonDirs)(cCtx)
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "format",
+						Aliases: []string{"f"},
+						Usage:   "Output format: csv, jsonl or parquet",
+						Value:   export.FormatCsv,
+					},
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "Output file, or object key under the exports prefix with --target s3, defaults to index.<format> in the exports folder",
+					},
+					&cli.StringFlag{
+						Name:  "target",
+						Usage: "Write the export to the local exports folder (local) or the bucket (s3)",
+						Value: cmds.TargetLocal,
+					},
+					&cli.IntSliceFlag{
+						Name:    "year",
+						Aliases: []string{"y"},
+						Usage:   "Only export filings of these years",
+					},
+					&cli.StringSliceFlag{
+						Name:    "filing-type",
+						Aliases: []string{"t"},
+						Usage:   "Only export filings of these types, e.g. P for PTRs",
+					},
+				},
+			},
+			{
+				Name:  "export-transactions",
+				Usage: "Export the parsed PTR transactions with their provenance",
+				UsageText: "Write the transactions from parse-ptr with the source PDF, page, page image, extraction\n" +
+					"method, confidence and parser version of each to CSV, JSON Lines or Parquet\n" +
+					"   disclosurecli export-transactions --format parquet --effective\n",
+				Action: func(cCtx *cli.Context) error {
+					return cmds.ExportTransactions(commonDirs)(cCtx)
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "format",
+						Aliases: []string{"f"},
+						Usage:   "Output format: csv, jsonl or parquet",
+						Value:   export.FormatCsv,
+					},
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "Output file, or object key under the exports prefix with --target s3, defaults to transactions.<format> in the exports folder",
+					},
+					&cli.StringFlag{
+						Name:  "target",
+						Usage: "Read the transactions from, and write the export to, the local folders (local) or the bucket (s3)",
+						Value: cmds.TargetLocal,
+					},
+					&cli.IntSliceFlag{
+						Name:    "year",
+						Aliases: []string{"y"},
+						Usage:   "Only export transactions of filings from these years",
+					},
+					&cli.BoolFlag{
+						Name:  "effective",
+						Usage: "Only export transactions of the latest version of every PTR, run link-filings first",
+					},
+				},
+			},
+			{
+				Name:  "update-search-index",
+				Usage: "Build the full text search index of the disclosure PDFs",
+				UsageText: "Index the text layer of new PDFs, or the OCR output of pages without one\n" +
+					"   disclosurecli update-search-index\n" +
+					"   disclosurecli update-search-index --rebuild\n",
+				Action: func(cCtx *cli.Context) error {
+					return cmds.UpdateSearchIndex(commonDirs)(cCtx)
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "target",
+						Usage: "Read the PDFs and OCR output from the local folders (local) or the bucket (s3)",
+						Value: cmds.TargetLocal,
+					},
+					&cli.IntFlag{
+						Name:    "limit",
+						Aliases: []string{"l"},
+						Usage:   "Limit the number of PDFs to index",
+						Value:   0,
+					},
+					&cli.BoolFlag{
+						Name:  "rebuild",
+						Usage: "Index every PDF again, e.g. after OCR of pages that were skipped",
+					},
+				},
+			},
+			{
+				Name:      "search",
+				Usage:     "Search the text of the disclosure PDFs",
+				ArgsUsage: "<query>",
+				UsageText: "Find the filings and pages that contain every word of the query, quote phrases\n" +
+					"   disclosurecli search --year 2023 '\"Apple Inc\"'\n",
+				Action: func(cCtx *cli.Context) error {
+					return cmds.Search(commonDirs)(cCtx)
+				},
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:    "limit",
+						Aliases: []string{"l"},
+						Usage:   "Maximum number of pages to return, 0 returns every match",
+						Value:   20,
+					},
+					&cli.IntSliceFlag{
+						Name:    "year",
+						Aliases: []string{"y"},
+						Usage:   "Only search filings of these years",
+					},
+					&cli.StringSliceFlag{
+						Name:    "filing-type",
+						Aliases: []string{"t"},
+						Usage:   "Only search filings of these types, e.g. P for PTRs",
+					},
+					&cli.StringFlag{
+						Name:    "member",
+						Aliases: []string{"m"},
+						Usage:   "Only search filings of this member ID, see update-members",
+					},
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "Print one JSON object per matching page",
+					},
+				},
+			},
+			{
+				Name:  "make-searchable",
+				Usage: "Create searchable PDFs from page images and OCR output",
+				UsageText: "Combine each PDF's page images and OCR word boxes into a new PDF " +
+					"with an invisible text layer\n" +
+					"   disclosurecli make-searchable\n",
+				Action: func(cCtx *cli.Context) error {
+					return cmds.MakeSearchable(commonDirs)(cCtx)
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "target",
+						Usage: "Read the PDFs, page images and OCR output from, and write the searchable PDFs to, the local folders (local) or the bucket (s3)",
+						Value: cmds.TargetLocal,
+					},
+					&cli.IntFlag{
+						Name:    "limit",
+						Aliases: []string{"l"},
+						Usage:   "Limit the number of PDFs to process",
+						Value:   0,
+					},
+					&cli.BoolFlag{
+						Name:  "overwrite",
+						Usage: "Rebuild searchable PDFs that already exist",
+					},
 				},
 			},
 			{
@@ -235,9 +725,8 @@ func getCommonDirs() *config.CommonDirs {
 	return config.NewCommonDirs(config.GetBaseFolder())
 }
 
-func initialize(commonDirs *config.CommonDirs) error {
+func initialize(commonDirs *config.CommonDirs, profile string) error {
 	var err error
-	profile := "default"
 	err = commonDirs.CreateDirectories()
 	if err != nil {
 		return err
@@ -248,6 +737,10 @@ func initialize(commonDirs *config.CommonDirs) error {
 		S3Hostname: "<hostname>",
 	}
 	v := config.BuildViper(profile, commonDirs, &s3Default)
+	// keep the other profiles of an existing configuration file
+	if err = v.MergeInConfig(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
 	err = v.WriteConfig()
 	if err != nil {
 		return err
@@ -64,6 +64,14 @@ func main() {
 						Aliases: []string{"s"},
 						Usage:   "folder to store s3 files within the data folder",
 					},
+					&cli.StringFlag{
+						Name:  "searchable",
+						Usage: "folder to store searchable pdfs within the data folder",
+					},
+					&cli.StringFlag{
+						Name:  "exports",
+						Usage: "folder to store exported datasets within the data folder",
+					},
 				},
 			},
 		},
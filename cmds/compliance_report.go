@@ -0,0 +1,126 @@
+package cmds
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/paulschick/disclosureupdater/compliance"
+	"github.com/paulschick/disclosureupdater/config"
+	"github.com/paulschick/disclosureupdater/model"
+	"github.com/paulschick/disclosureupdater/registry"
+	"github.com/paulschick/disclosureupdater/storage"
+	"github.com/urfave/cli/v2"
+	"io"
+	"strings"
+)
+
+// Values of the compliance-report --format flag
+const (
+	ReportFormatCsv  = "csv"
+	ReportFormatJson = "json"
+)
+
+const (
+	complianceReportName = "compliance_report"
+	complianceLateName   = "compliance_late"
+)
+
+// ComplianceReport flags transactions reported after the STOCK Act deadlines, grouped by member and year.
+// Filing dates are taken from the disclosure index XML files, transactions from parse-ptr.
+func ComplianceReport(commonDirs *config.CommonDirs) model.CliFunc {
+	return func(c *cli.Context) error {
+		format := strings.ToLower(c.String("format"))
+		if format != ReportFormatCsv && format != ReportFormatJson {
+			return fmt.Errorf("invalid format %q, expected %s or %s", format, ReportFormatCsv, ReportFormatJson)
+		}
+		rules := compliance.Rules{
+			TransactionDays: c.Int("transaction-days"),
+			NoticeDays:      c.Int("notice-days"),
+		}
+
+		indexMembers, err := loadIndexMembers(commonDirs)
+		if err != nil {
+			fmt.Printf("Error reading disclosure index: %s\n", err)
+			return err
+		}
+		reg, err := loadRegistry(commonDirs, indexMembers)
+		if err != nil {
+			return err
+		}
+		store, err := artifactStoreFromCtx(c, commonDirs)
+		if err != nil {
+			return err
+		}
+		return complianceReport(c.Context, store, rules, format, indexMembers, reg)
+	}
+}
+
+// complianceReport checks the transactions of transactions.csv in the csv backend. The report is written to
+// compliance_report.json in the reports backend, or as tab separated files to the csv backend.
+func complianceReport(ctx context.Context, store *artifactStore, rules compliance.Rules, format string,
+	indexMembers []*model.Member, reg *registry.Registry) error {
+	transactions, err := readTransactions(ctx, store.Csv, TransactionsFileName)
+	if err != nil {
+		fmt.Printf("Error reading transactions, run parse-ptr first: %s\n", err)
+		return err
+	}
+	members := make(map[int]*model.Member, len(indexMembers))
+	for _, member := range indexMembers {
+		members[member.DocId] = member
+	}
+
+	report, skipped := rules.Report(transactions, members, reg)
+	for i, reason := range skipped {
+		if i == 10 {
+			fmt.Printf("... and %d more\n", len(skipped)-i)
+			break
+		}
+		fmt.Printf("Skipping transaction: %s\n", reason)
+	}
+	late := 0
+	for _, group := range report {
+		late += group.Late
+	}
+	fmt.Printf("Checked %d transactions, %d reported late, %d skipped\n",
+		len(transactions)-len(skipped), late, len(skipped))
+
+	if format == ReportFormatJson {
+		return writeComplianceJson(ctx, store.Reports, report)
+	}
+	return writeComplianceCsv(ctx, store.Csv, report)
+}
+
+func writeComplianceJson(ctx context.Context, b storage.Backend, report []*model.ComplianceGroup) error {
+	key := complianceReportName + ".json"
+	opts := &storage.PutOptions{ContentType: "application/json"}
+	err := storage.Write(ctx, b, key, opts, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s\n", objectPath(b, key))
+	return nil
+}
+
+// writeComplianceCsv writes the per member and year summary and the late transactions to separate files
+func writeComplianceCsv(ctx context.Context, b storage.Backend, report []*model.ComplianceGroup) error {
+	findings := make([]*model.ComplianceFinding, 0)
+	for _, group := range report {
+		findings = append(findings, group.Findings...)
+	}
+	files := map[string]interface{}{
+		complianceReportName: &report,
+		complianceLateName:   &findings,
+	}
+	for name, records := range files {
+		key := name + ".csv"
+		if err := writeTsv(ctx, b, key, records); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %s\n", objectPath(b, key))
+	}
+	return nil
+}
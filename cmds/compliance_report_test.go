@@ -0,0 +1,86 @@
+package cmds
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/paulschick/disclosureupdater/compliance"
+	"github.com/paulschick/disclosureupdater/model"
+	"github.com/paulschick/disclosureupdater/registry"
+	"io"
+	"testing"
+)
+
+func TestComplianceReport(t *testing.T) {
+	ctx := context.Background()
+	members := []*model.Member{
+		{First: "Jane", Last: "Doe", StateDst: "CA12", Year: 2023, FilingDate: "3/1/2023", DocId: 1},
+		{First: "John", Last: "Roe", StateDst: "TX07", Year: 2022, FilingDate: "6/1/2022", DocId: 3},
+	}
+	reg := registry.NewRegistry(nil)
+	reg.Add(members)
+	transactions := []*model.Transaction{
+		{DocId: 1, Date: "02/20/2023", NotificationDate: "02/20/2023"},
+		{DocId: 1, Date: "01/02/2023", NotificationDate: "01/02/2023"},
+		{DocId: 3, Date: "05/30/2022", NotificationDate: "05/30/2022"},
+	}
+
+	t.Run(ReportFormatJson, func(t *testing.T) {
+		store := memoryArtifactStore()
+		if err := writeTsv(ctx, store.Csv, TransactionsFileName, &transactions); err != nil {
+			t.Fatal(err)
+		}
+		if err := complianceReport(ctx, store, compliance.DefaultRules(), ReportFormatJson, members, reg); err != nil {
+			t.Fatal(err)
+		}
+		r, err := store.Reports.Get(ctx, complianceReportName+".json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			_ = r.Close()
+		}()
+		b, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var report []*model.ComplianceGroup
+		if err = json.Unmarshal(b, &report); err != nil {
+			t.Fatal(err)
+		}
+		late := 0
+		for _, group := range report {
+			late += group.Late
+		}
+		if len(report) != 2 || late != 1 {
+			t.Errorf("complianceReport() = %d groups, %d late; want 2 groups, 1 late", len(report), late)
+		}
+	})
+
+	t.Run(ReportFormatCsv, func(t *testing.T) {
+		store := memoryArtifactStore()
+		if err := writeTsv(ctx, store.Csv, TransactionsFileName, &transactions); err != nil {
+			t.Fatal(err)
+		}
+		if err := complianceReport(ctx, store, compliance.DefaultRules(), ReportFormatCsv, members, reg); err != nil {
+			t.Fatal(err)
+		}
+		report := make([]*model.ComplianceGroup, 0)
+		if err := readTsv(ctx, store.Csv, complianceReportName+".csv", &report); err != nil {
+			t.Fatal(err)
+		}
+		findings := make([]*model.ComplianceFinding, 0)
+		if err := readTsv(ctx, store.Csv, complianceLateName+".csv", &findings); err != nil {
+			t.Fatal(err)
+		}
+		if len(report) != 2 || len(findings) != 1 {
+			t.Errorf("complianceReport() = %d groups, %d findings; want 2 groups, 1 finding", len(report), len(findings))
+		}
+	})
+
+	t.Run("without transactions", func(t *testing.T) {
+		err := complianceReport(ctx, memoryArtifactStore(), compliance.DefaultRules(), ReportFormatCsv, members, reg)
+		if err == nil {
+			t.Errorf("complianceReport() error = nil; want an error without transactions.csv")
+		}
+	})
+}
@@ -0,0 +1,153 @@
+package cmds
+
+import (
+	"errors"
+	"fmt"
+	"github.com/paulschick/disclosureupdater/config"
+	"github.com/paulschick/disclosureupdater/export"
+	"github.com/paulschick/disclosureupdater/model"
+	"github.com/urfave/cli/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// indexExportName is the file name export-index writes to in the exports folder, without the extension
+const indexExportName = "index"
+
+// ExportIndex writes every entry of the disclosure index XML files, with the PDF URL, local path,
+// member ID and pipeline state of each filing, to CSV, JSON Lines or Parquet.
+// Entries can be filtered by year and filing type.
+func ExportIndex(commonDirs *config.CommonDirs) model.CliFunc {
+	return func(c *cli.Context) error {
+		format, err := export.ParseFormat(c.String("format"))
+		if err != nil {
+			return err
+		}
+
+		members, err := loadIndexMembers(commonDirs)
+		if err != nil {
+			fmt.Printf("Error reading disclosure index: %s\n", err)
+			return err
+		}
+		reg, err := loadRegistry(commonDirs, members)
+		if err != nil {
+			return err
+		}
+		members = filterMembers(members, c.IntSlice("year"), c.StringSlice("filing-type"))
+
+		// the pipeline state is read from the local folders
+		local := localArtifactStore(commonDirs)
+		pageImages, err := pageImagesByPdf(commonDirs.ImageFolder)
+		if err != nil {
+			return err
+		}
+		transactions, err := readTransactions(c.Context, local.Csv, TransactionsFileName)
+		if errors.Is(err, os.ErrNotExist) {
+			transactions = make([]*model.Transaction, 0)
+		} else if err != nil {
+			return err
+		}
+		transactionCounts := make(map[int]int)
+		for _, t := range transactions {
+			transactionCounts[t.DocId]++
+		}
+
+		records := make([]*model.IndexRecord, len(members))
+		for i, m := range members {
+			record := model.NewIndexRecord(m)
+			record.MemberId = reg.MemberId(m.DocId)
+			record.PdfPath = m.BuildPdfFilePath(commonDirs.DataFolder)
+			record.PdfDownloaded = m.PdfFileExists(commonDirs.DataFolder)
+			searchable := NewSearchablePdf(filepath.Base(record.PdfPath), local)
+			images := pageImages[searchable.BaseFileName]
+			record.PageImages = len(images)
+			record.OcrPages = countOcrPages(commonDirs, images)
+			record.Searchable = searchable.Exists(c.Context)
+			record.Transactions = transactionCounts[m.DocId]
+			record.SetPipelineState()
+			records[i] = record
+		}
+
+		outPath, err := writeExport(c, commonDirs, indexExportName, format, records)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %d filings to %s\n", len(records), outPath)
+		return nil
+	}
+}
+
+// filterMembers returns the members of the given years and filing types sorted by year and DocId.
+// Empty filters match every member.
+func filterMembers(members []*model.Member, years []int, filingTypes []string) []*model.Member {
+	yearSet := make(map[int]bool, len(years))
+	for _, year := range years {
+		yearSet[year] = true
+	}
+	typeSet := make(map[string]bool, len(filingTypes))
+	for _, filingType := range filingTypes {
+		typeSet[strings.ToUpper(strings.TrimSpace(filingType))] = true
+	}
+	filtered := make([]*model.Member, 0, len(members))
+	for _, m := range members {
+		if len(yearSet) > 0 && !yearSet[m.Year] {
+			continue
+		}
+		if len(typeSet) > 0 && !typeSet[m.FilingType] {
+			continue
+		}
+		filtered = append(filtered, m)
+	}
+	sort.Slice(filtered, func(i, j int) bool {
+		if filtered[i].Year != filtered[j].Year {
+			return filtered[i].Year < filtered[j].Year
+		}
+		return filtered[i].DocId < filtered[j].DocId
+	})
+	return filtered
+}
+
+// pageImagesByPdf returns the page images in the image folder by the base file name of their PDF.
+// Both the per-PDF folder layout and the flat layout of convert-pdfs are read.
+func pageImagesByPdf(imageFolder string) (map[string][]string, error) {
+	images := make(map[string][]string)
+	entries, err := os.ReadDir(imageFolder)
+	if errors.Is(err, os.ErrNotExist) {
+		return images, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		entryPath := filepath.Join(imageFolder, entry.Name())
+		if !entry.IsDir() {
+			name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+			if i := strings.LastIndex(name, "-"); i > 0 {
+				images[name[:i]] = append(images[name[:i]], entryPath)
+			}
+			continue
+		}
+		pageEntries, err := os.ReadDir(entryPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, pageEntry := range pageEntries {
+			if !pageEntry.IsDir() {
+				images[entry.Name()] = append(images[entry.Name()], filepath.Join(entryPath, pageEntry.Name()))
+			}
+		}
+	}
+	return images, nil
+}
+
+// countOcrPages returns the number of page images with TSV output in the csv folder
+func countOcrPages(commonDirs *config.CommonDirs, images []string) int {
+	count := 0
+	for _, imagePath := range images {
+		if _, err := os.Stat(filepath.Join(commonDirs.CsvFolder, csvPathFromImagePath(imagePath))); err == nil {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,82 @@
+package cmds
+
+import (
+	"fmt"
+	"github.com/paulschick/disclosureupdater/config"
+	"github.com/paulschick/disclosureupdater/export"
+	"github.com/paulschick/disclosureupdater/model"
+	"github.com/urfave/cli/v2"
+)
+
+// transactionsExportName is the file name export-transactions writes to in the exports folder, without the extension
+const transactionsExportName = "transactions"
+
+// ExportTransactions writes the transactions parsed by parse-ptr to CSV, JSON Lines or Parquet with the
+// provenance of every record: the DocId, source PDF, page and page image, extraction method, confidence
+// and parser version. With --effective only the transactions of the latest version of every PTR are written.
+func ExportTransactions(commonDirs *config.CommonDirs) model.CliFunc {
+	return func(c *cli.Context) error {
+		format, err := export.ParseFormat(c.String("format"))
+		if err != nil {
+			return err
+		}
+
+		fileName := TransactionsFileName
+		if c.Bool("effective") {
+			fileName = EffectiveTransactionsFileName
+		}
+		store, err := artifactStoreFromCtx(c, commonDirs)
+		if err != nil {
+			return err
+		}
+		transactions, err := readTransactions(c.Context, store.Csv, fileName)
+		if err != nil {
+			fmt.Printf("Error reading %s, run parse-ptr and link-filings first: %s\n", fileName, err)
+			return err
+		}
+		indexMembers, err := loadIndexMembers(commonDirs)
+		if err != nil {
+			fmt.Printf("Error reading disclosure index: %s\n", err)
+			return err
+		}
+		members := make(map[int]*model.Member, len(indexMembers))
+		for _, member := range indexMembers {
+			members[member.DocId] = member
+		}
+
+		years := make(map[int]bool)
+		for _, year := range c.IntSlice("year") {
+			years[year] = true
+		}
+		pdfs := make(map[string]*SearchablePdf)
+		records := make([]*model.TransactionRecord, 0, len(transactions))
+		for _, t := range transactions {
+			member := members[t.DocId]
+			if member == nil {
+				// filings missing from the index still have their year in the PDF file name
+				member, _ = model.ParsePdfFileName(t.SourcePdf)
+			}
+			record := model.NewTransactionRecord(t, member)
+			if len(years) > 0 && !years[record.Year] {
+				continue
+			}
+			pdf, ok := pdfs[t.SourcePdf]
+			if !ok {
+				pdf = NewSearchablePdf(t.SourcePdf, store)
+				pdfs[t.SourcePdf] = pdf
+			}
+			// empty if the PDF hasn't been converted, e.g. e-filed PTRs parsed from the text layer
+			if imageKey, err := pdf.pageImageKey(c.Context, t.Page); err == nil {
+				record.PageImage = objectPath(store.Images, imageKey)
+			}
+			records = append(records, record)
+		}
+
+		outPath, err := writeExport(c, commonDirs, transactionsExportName, format, records)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %d of %d transactions to %s\n", len(records), len(transactions), outPath)
+		return nil
+	}
+}
@@ -0,0 +1,96 @@
+package cmds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/paulschick/disclosureupdater/config"
+	"github.com/paulschick/disclosureupdater/downloader"
+	"github.com/paulschick/disclosureupdater/filings"
+	"github.com/paulschick/disclosureupdater/model"
+	"github.com/paulschick/disclosureupdater/registry"
+	"github.com/urfave/cli/v2"
+	"os"
+)
+
+const (
+	// FilingsFileName is the file link-filings writes the linked disclosure index to
+	FilingsFileName = "filings.csv"
+	// EffectiveTransactionsFileName holds the latest version of every trade of the PTRs
+	EffectiveTransactionsFileName = "transactions_effective.csv"
+)
+
+// LinkFilings links amendments in the disclosure index to the filings they amend and writes
+// csv/filings.csv, and the latest version of every trade of the PTRs
+func LinkFilings(commonDirs *config.CommonDirs) model.CliFunc {
+	return func(c *cli.Context) error {
+		members, err := loadIndexMembers(commonDirs)
+		if err != nil {
+			fmt.Printf("Error reading disclosure index: %s\n", err)
+			return err
+		}
+		reg, err := loadRegistry(commonDirs, members)
+		if err != nil {
+			return err
+		}
+		store, err := artifactStoreFromCtx(c, commonDirs)
+		if err != nil {
+			return err
+		}
+		return linkFilings(c.Context, store, members, reg)
+	}
+}
+
+// linkFilings links the filings of the disclosure index and writes filings.csv and the effective transactions
+// of transactions.csv to the csv backend
+func linkFilings(ctx context.Context, store *artifactStore, members []*model.Member, reg *registry.Registry) error {
+	transactions, err := readTransactions(ctx, store.Csv, TransactionsFileName)
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Println("No transactions found, PTR amendments are linked after running parse-ptr")
+		transactions = make([]*model.Transaction, 0)
+	} else if err != nil {
+		return err
+	}
+
+	linked := filings.Link(members, transactions, reg)
+	amendments, superseded := 0, 0
+	for _, f := range linked {
+		if f.Amendment {
+			amendments++
+		}
+		if f.Superseded() {
+			superseded++
+		}
+	}
+	fmt.Printf("Linked %d filings, %d amendments, %d superseded filings\n", len(linked), amendments, superseded)
+
+	err = writeTsv(ctx, store.Csv, FilingsFileName, &linked)
+	if err != nil {
+		return err
+	}
+	effective := filings.EffectiveTransactions(transactions, linked)
+	err = writeTsv(ctx, store.Csv, EffectiveTransactionsFileName, &effective)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote the latest version of %d of %d transactions\n", len(effective), len(transactions))
+	return nil
+}
+
+// loadIndexMembers returns every member entry of the downloaded disclosure index XML files
+func loadIndexMembers(commonDirs *config.CommonDirs) ([]*model.Member, error) {
+	downloadUrls := downloader.GenerateAllZipUrls()
+	disclosureDownloads := make([]*downloader.DisclosureDownload, len(downloadUrls))
+	for i := 0; i < len(downloadUrls); i++ {
+		disclosureDownloads[i] = downloader.NewDisclosureDownload(downloadUrls[i], commonDirs.DataFolder)
+	}
+	byDocId, err := downloader.GetMembersByDocId(disclosureDownloads)
+	if err != nil {
+		return nil, err
+	}
+	members := make([]*model.Member, 0, len(byDocId))
+	for _, member := range byDocId {
+		members = append(members, member)
+	}
+	return members, nil
+}
@@ -0,0 +1,64 @@
+package cmds
+
+import (
+	"context"
+	"github.com/paulschick/disclosureupdater/model"
+	"github.com/paulschick/disclosureupdater/registry"
+	"reflect"
+	"testing"
+)
+
+func TestLinkFilings(t *testing.T) {
+	ctx := context.Background()
+	members := []*model.Member{
+		{DocId: 10, First: "Jane", Last: "Doe", StateDst: "CA12", Year: 2023, FilingType: "P", FilingDate: "2/1/2023"},
+		{DocId: 12, First: "Jane", Last: "Doe", StateDst: "CA12", Year: 2023, FilingType: "P", FilingDate: "4/1/2023"},
+	}
+	reg := registry.NewRegistry(nil)
+	reg.Add(members)
+
+	tests := []struct {
+		name         string
+		transactions []*model.Transaction
+		effective    []int
+	}{
+		{"without transactions", nil, []int{}},
+		{"amended ptr", []*model.Transaction{
+			{DocId: 10, Date: "01/10/2023", Ticker: "AAPL", FilingStatus: model.FilingStatusNew},
+			{DocId: 10, Date: "01/11/2023", Ticker: "MSFT", FilingStatus: model.FilingStatusNew},
+			{DocId: 12, Date: "01/10/2023", Ticker: "AAPL", FilingStatus: model.FilingStatusAmended},
+		}, []int{10, 12}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := memoryArtifactStore()
+			if test.transactions != nil {
+				if err := writeTsv(ctx, store.Csv, TransactionsFileName, &test.transactions); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := linkFilings(ctx, store, members, reg); err != nil {
+				t.Fatal(err)
+			}
+
+			linked := make([]*model.Filing, 0)
+			if err := readTsv(ctx, store.Csv, FilingsFileName, &linked); err != nil {
+				t.Fatal(err)
+			}
+			if len(linked) != len(members) {
+				t.Errorf("%s has %d filings; want %d", FilingsFileName, len(linked), len(members))
+			}
+			effective, err := readTransactions(ctx, store.Csv, EffectiveTransactionsFileName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			docIds := make([]int, len(effective))
+			for i, transaction := range effective {
+				docIds[i] = transaction.DocId
+			}
+			if !reflect.DeepEqual(docIds, test.effective) {
+				t.Errorf("%s DocIds = %v; want %v", EffectiveTransactionsFileName, docIds, test.effective)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package cmds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gen2brain/go-fitz"
+	"github.com/paulschick/disclosureupdater/common/constants"
+	"github.com/paulschick/disclosureupdater/common/logger"
+	"github.com/paulschick/disclosureupdater/common/workerpool"
+	"github.com/paulschick/disclosureupdater/config"
+	"github.com/paulschick/disclosureupdater/model"
+	"github.com/paulschick/disclosureupdater/pdfwriter"
+	"github.com/paulschick/disclosureupdater/storage"
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+	"image"
+	"io"
+	"math"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+)
+
+// errPageNotReady is returned when a page has not been converted or OCR'd yet
+var errPageNotReady = errors.New("page image or ocr output not found")
+
+// SearchablePdf builds a searchable copy of a disclosure PDF from its page images and OCR word boxes
+type SearchablePdf struct {
+	PdfName      string
+	BaseFileName string
+	Store        *artifactStore
+}
+
+func NewSearchablePdf(pdfName string, store *artifactStore) *SearchablePdf {
+	return &SearchablePdf{
+		PdfName:      pdfName,
+		BaseFileName: strings.TrimSuffix(pdfName, ".pdf"),
+		Store:        store,
+	}
+}
+
+func (s *SearchablePdf) Exists(ctx context.Context) bool {
+	_, err := s.Store.Searchable.Stat(ctx, s.PdfName)
+	return !errors.Is(err, os.ErrNotExist)
+}
+
+// pageImageKey returns the key of the converted page image in the images backend.
+// Both the flat layout of convert-pdfs and the per-PDF folder layout are checked.
+func (s *SearchablePdf) pageImageKey(ctx context.Context, pageNumber int) (string, error) {
+	imageName := fmt.Sprintf("%s-%d.png", s.BaseFileName, pageNumber)
+	candidates := []string{imageName, path.Join(s.BaseFileName, imageName)}
+	for _, candidate := range candidates {
+		if _, err := s.Store.Images.Stat(ctx, candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", errPageNotReady, imageName)
+}
+
+// Build reads every page of the original PDF and writes the searchable PDF to the searchable backend.
+// Page sizes are taken from the original so the output matches it page for page.
+func (s *SearchablePdf) Build(ctx context.Context) error {
+	r, err := s.Store.Disclosures.Get(ctx, s.PdfName)
+	if err != nil {
+		return err
+	}
+	doc, err := fitz.NewFromReader(r)
+	_ = r.Close()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = doc.Close()
+	}()
+
+	out := pdfwriter.NewDocument()
+	for n := 0; n < doc.NumPage(); n++ {
+		bounds, err := doc.Bound(n)
+		if err != nil {
+			return err
+		}
+		imageKey, err := s.pageImageKey(ctx, n)
+		if err != nil {
+			return err
+		}
+		csvName := csvPathFromImagePath(imageKey)
+		words, err := readOcrResults(ctx, s.Store.Csv, csvName)
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %s", errPageNotReady, csvName)
+		} else if err != nil {
+			return err
+		}
+		img, err := readImageFrom(ctx, s.Store.Images, imageKey)
+		if err != nil {
+			return err
+		}
+		out.AddPage(float64(bounds.Dx()), float64(bounds.Dy()), img, words)
+	}
+
+	opts := &storage.PutOptions{ContentType: "application/pdf"}
+	return storage.Write(ctx, s.Store.Searchable, s.PdfName, opts, func(w io.Writer) error {
+		_, err := out.WriteTo(w)
+		return err
+	})
+}
+
+func readImage(imagePath string) (image.Image, error) {
+	f, err := os.Open(imagePath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	img, _, err := image.Decode(f)
+	return img, err
+}
+
+// readImageFrom decodes an image of a backend
+func readImageFrom(ctx context.Context, b storage.Backend, key string) (image.Image, error) {
+	r, err := b.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+	img, _, err := image.Decode(r)
+	return img, err
+}
+
+// MakeSearchable combines each disclosure PDF's page images with its OCR word boxes
+// into a new PDF with an invisible text layer, written to the searchable folder.
+// PDFs that have not been converted and OCR'd yet are skipped.
+func MakeSearchable(commonDirs *config.CommonDirs) model.CliFunc {
+	return func(c *cli.Context) error {
+		limit := c.Int("limit")
+		if limit == 0 {
+			limit = math.MaxInt
+		}
+		overwrite := c.Bool("overwrite")
+
+		store, err := artifactStoreFromCtx(c, commonDirs)
+		if err != nil {
+			return err
+		}
+		return makeSearchable(c.Context, store, limit, overwrite)
+	}
+}
+
+// makeSearchable builds the searchable PDFs of the disclosures backend that are ready, at most limit of them
+func makeSearchable(ctx context.Context, store *artifactStore, limit int, overwrite bool) error {
+	pdfs, err := listPdfs(ctx, store.Disclosures)
+	if err != nil {
+		return err
+	}
+	searchables := make([]*SearchablePdf, 0)
+	for _, pdfName := range pdfs {
+		if len(searchables) >= limit {
+			break
+		}
+		searchable := NewSearchablePdf(pdfName, store)
+		if !overwrite && searchable.Exists(ctx) {
+			continue
+		}
+		searchables = append(searchables, searchable)
+	}
+	logger.Logger.Info("Building searchable PDFs", zap.Int("count", len(searchables)))
+
+	tasks := make([]*workerpool.Task, len(searchables))
+	for i, searchable := range searchables {
+		tasks[i] = workerpool.NewTask(func(data interface{}) error {
+			s := data.(*SearchablePdf)
+			return s.Build(ctx)
+		}, searchable, i)
+	}
+	poolSize := int(math.Max(2, math.Floor(float64(runtime.NumCPU())*constants.CpuUtilization)))
+	pool := workerpool.NewPool(tasks, poolSize, len(tasks))
+	pool.Run()
+
+	created, skipped := 0, 0
+	var errStr string
+	for i, task := range tasks {
+		pdfPath := objectPath(store.Disclosures, searchables[i].PdfName)
+		switch {
+		case task.Err == nil:
+			created++
+		case errors.Is(task.Err, errPageNotReady):
+			skipped++
+			logger.Logger.Info("Skipping PDF that is not ready",
+				zap.String("pdf_path", pdfPath),
+				zap.Error(task.Err))
+		default:
+			logger.Logger.Error("Error building searchable PDF",
+				zap.String("pdf_path", pdfPath),
+				zap.Error(task.Err))
+			errStr = errStr + " " + task.Err.Error()
+		}
+	}
+	fmt.Printf("Created %d searchable PDFs, skipped %d\n", created, skipped)
+	if errStr != "" {
+		return errors.New(errStr)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package cmds
+
+import (
+	"context"
+	"github.com/paulschick/disclosureupdater/common/logger"
+	"github.com/paulschick/disclosureupdater/ptr"
+	"go.uber.org/zap"
+	"strings"
+	"testing"
+)
+
+func TestMakeSearchable(t *testing.T) {
+	logger.Logger = zap.NewNop()
+	ctx := context.Background()
+	store := memoryArtifactStore()
+	// page images in the flat layout of convert-pdfs and in a folder per PDF
+	flat := "2023.ptr-pdfs.CA12.Doe.Jane.20012345.pdf"
+	words := putTestPdf(t, store, flat, []string{"Apple Inc. (AAPL)", "Microsoft Corporation (MSFT)"})
+	putOcrPage(t, store, "2023.ptr-pdfs.CA12.Doe.Jane.20012345-0.png", words[0])
+	putOcrPage(t, store, "2023.ptr-pdfs.CA12.Doe.Jane.20012345-1.png", words[1])
+	folder := "2023.ptr-pdfs.TX07.Roe.John.20012346.pdf"
+	words = putTestPdf(t, store, folder, []string{"Tesla, Inc. (TSLA)"})
+	putOcrPage(t, store, "2023.ptr-pdfs.TX07.Roe.John.20012346/2023.ptr-pdfs.TX07.Roe.John.20012346-0.png", words[0])
+	// not OCR'd yet
+	putTestPdf(t, store, "2023.ptr-pdfs.NY07.Poe.Ann.20012347.pdf", []string{"Filing ID #20012347"})
+
+	if err := makeSearchable(ctx, store, 10, false); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{flat: "Microsoft", folder: "TSLA"}
+	names, err := listPdfs(ctx, store.Searchable)
+	if err != nil || len(names) != len(want) {
+		t.Fatalf("searchable PDFs = %v, %v; want %d", names, err, len(want))
+	}
+	for name, word := range want {
+		r, err := store.Searchable.Get(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages, err := ptr.ReadTextPages(r)
+		_ = r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(strings.Join(pages, "\n"), word) {
+			t.Errorf("text layer of %s = %q; want it to contain %q", name, pages, word)
+		}
+	}
+}
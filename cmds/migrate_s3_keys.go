@@ -0,0 +1,69 @@
+package cmds
+
+import (
+	"errors"
+	"fmt"
+	"github.com/paulschick/disclosureupdater/config"
+	"github.com/paulschick/disclosureupdater/model"
+	"github.com/urfave/cli/v2"
+)
+
+// MigrateS3Keys copies objects uploaded under their absolute local path to the keys of the profile's key scheme.
+// With --delete the old objects are removed after they're copied, with --dry-run nothing is changed.
+func MigrateS3Keys(commonDirs *config.CommonDirs) model.CliFunc {
+	return func(cCtx *cli.Context) error {
+		dryRun := cCtx.Bool("dry-run")
+		deleteOld := cCtx.Bool("delete")
+		service, err := newS3Service(cCtx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Operating on %s Bucket\n", service.S3Profile.GetBucket())
+
+		keys, err := service.ListKeys()
+		if err != nil {
+			fmt.Printf("Error listing bucket objects: %s\n", err)
+			return err
+		}
+		migrations, err := service.PlanKeyMigration(commonDirs, keys)
+		if err != nil {
+			fmt.Printf("Error reading the artifact classes: %s\n", err)
+			return err
+		}
+		fmt.Printf("Found %d objects with legacy keys of %d objects\n", len(migrations), len(keys))
+
+		migrated := 0
+		var errStr string
+		for _, migration := range migrations {
+			action := "Copying"
+			if migration.Exists {
+				action = "Already copied"
+			}
+			fmt.Printf("%s %s to %s\n", action, migration.OldKey, migration.NewKey)
+			if dryRun {
+				continue
+			}
+			if err = service.MigrateKey(migration, deleteOld); err != nil {
+				fmt.Printf("Error migrating %s: %s\n", migration.OldKey, err)
+				errStr = errStr + " " + err.Error()
+				continue
+			}
+			migrated++
+		}
+		if dryRun {
+			fmt.Println("Dry run, no objects were changed")
+			return nil
+		}
+		fmt.Printf("Migrated %d objects\n", migrated)
+
+		// the bucket index holds the old keys
+		if err = service.WriteBucketObjects(commonDirs); err != nil {
+			fmt.Printf("Error writing bucket objects: %s\n", err)
+			return err
+		}
+		if errStr != "" {
+			return errors.New(errStr)
+		}
+		return nil
+	}
+}
@@ -10,14 +10,20 @@ import (
 	"github.com/paulschick/disclosureupdater/config"
 	"github.com/paulschick/disclosureupdater/model"
 	"github.com/urfave/cli/v2"
+	"image"
 	"io"
 	"io/fs"
 	"math"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
+// hocrBboxPattern matches the bounding box property in the title of hOCR elements
+var hocrBboxPattern = regexp.MustCompile(`bbox \d+ \d+ \d+ \d+`)
+
 // OcrImages
 // TODO 1. Reuse client, no need to re-create. Just SetImage for each
 // TODO 2. Error Groups golang.org/x/sync/errgroup
@@ -35,7 +41,15 @@ func OcrImages(commonDirs *config.CommonDirs) model.CliFunc {
 			limit = math.MaxInt
 		}
 		fmt.Printf("Limiting to %d\n", limit)
-		imageDir := commonDirs.ImageFolder
+		opts, err := ocrOptionsFromCtx(c, commonDirs)
+		if err != nil {
+			return err
+		}
+		formats := opts.formats
+		fmt.Printf("Writing formats %s\n", strings.Join(formats, ", "))
+		if opts.pipeline != nil {
+			fmt.Printf("Preprocessing steps %s\n", strings.Join(opts.pipeline.Options.Steps(), ", "))
+		}
 
 		// ---- Example Implementation ----- //
 
@@ -91,100 +105,88 @@ func OcrImages(commonDirs *config.CommonDirs) model.CliFunc {
 
 		// ---- Original Working Code ----- //
 
-		imageSubDirs, err := os.ReadDir(imageDir)
+		maxAttempts := c.Int("max-attempts")
+		ledger, err := loadFailureLedger(filepath.Join(commonDirs.CsvFolder, "ocr_failures.csv"))
 		if err != nil {
 			return err
 		}
-		i := 0
 
-		imagePaths := make([]string, 0)
-		for _, imageSubDir := range imageSubDirs {
-			if i >= limit {
-				break
+		var imagePaths []string
+		if c.Bool("retry-failed") {
+			imagePaths = ledger.Retryable(maxAttempts)
+			if len(imagePaths) > limit {
+				imagePaths = imagePaths[:limit]
 			}
-			subDirPath := filepath.Join(imageDir, imageSubDir.Name())
-			imageSubDirContents, err := os.ReadDir(subDirPath)
+			fmt.Printf("Retrying %d of %d failed images\n", len(imagePaths), ledger.Len())
+		} else {
+			imagePaths, err = collectOcrImagePaths(commonDirs, formats, limit, ledger, maxAttempts)
 			if err != nil {
 				return err
 			}
-			for _, imageFile := range imageSubDirContents {
-				imagePath := filepath.Join(subDirPath, imageFile.Name())
-				csvPath := filepath.Join(commonDirs.CsvFolder, csvPathFromImagePath(imagePath))
-				_, err := os.Stat(csvPath)
-				if err == nil {
-					fmt.Printf("Skipping %s\n", imagePath)
-					continue
-				} else if !os.IsNotExist(err) {
-					fmt.Printf("Error checking for csv: %s\n", err.Error())
-					return err
-				}
-				imagePaths = append(imagePaths, imagePath)
-				fmt.Printf("Adding %s\n", imagePath)
-				i++
-			}
 		}
 
+		type ocrOutcome struct {
+			imgPath string
+			err     error
+		}
 		waitChan := make(chan struct{}, constants.MaxConversions)
-		done := make(chan bool, len(imagePaths))
-		failed := make(chan string, len(imagePaths))
-		errs := make(chan error, len(imagePaths))
+		outcomes := make(chan ocrOutcome, len(imagePaths))
 
 		index := 0
 		for _, imgPath := range imagePaths {
 			waitChan <- struct{}{}
 			go func(imgPath string) {
-				csvPath := filepath.Join(commonDirs.CsvFolder, csvPathFromImagePath(imgPath))
-				created, err := extractImageToCsvIfNotExists(imgPath, csvPath)
+				defer func() {
+					<-waitChan
+				}()
+				created, err := extractImageIfNotExists(commonDirs, imgPath, opts)
 				if err != nil {
-					fmt.Printf("Error extracting image to csv: %s\n", err.Error())
+					fmt.Printf("Error extracting image: %s\n", err.Error())
 					fmt.Printf("Failed Image Path: %s\n", imgPath)
-					errs <- err
-					done <- false
-					failed <- imgPath
+					outcomes <- ocrOutcome{imgPath: imgPath, err: err}
 					return
 				}
 				if created {
-					fmt.Printf("(%d) Created %s\n", index, csvPath)
+					fmt.Printf("(%d) Created output for %s\n", index, imgPath)
 					index++
 				} else {
-					fmt.Printf("(%d) Already Exists %s\n", index, csvPath)
+					fmt.Printf("(%d) Already Exists %s\n", index, imgPath)
 					index++
 				}
-				errs <- nil
-				done <- true
-				failed <- ""
-				<-waitChan
+				outcomes <- ocrOutcome{imgPath: imgPath}
 			}(imgPath)
 		}
-		failedImages := make([]string, 0)
 		var errStr string
+		failedCount := 0
 		for i := 0; i < len(imagePaths); i++ {
-			if err := <-errs; err != nil {
-				errStr = errStr + " " + err.Error()
-			}
-			if <-done == false {
-				failedImages = append(failedImages, <-failed)
+			outcome := <-outcomes
+			if outcome.err != nil {
+				errStr = errStr + " " + outcome.err.Error()
+				ledger.Record(outcome.imgPath, outcome.err)
+				failedCount++
 			} else {
-				// drain failed channel
-				<-failed
+				ledger.Resolve(outcome.imgPath)
 			}
 		}
 
-		failedPath := filepath.Join(commonDirs.CsvFolder, "failed.txt")
-		failedFile, err := os.OpenFile(failedPath, os.O_CREATE|os.O_RDWR, os.ModePerm)
+		fmt.Printf("%d images failed, %d entries in the failure ledger\n", failedCount, ledger.Len())
+		err = ledger.Write()
 		if err != nil {
 			return err
 		}
-		for _, failedImage := range failedImages {
-			_, err = failedFile.WriteString(failedImage + "\n")
+
+		if opts.stats != nil {
+			fmt.Println(opts.stats.Summary())
+			err = opts.stats.Write()
 			if err != nil {
 				return err
 			}
 		}
-
-		err = failedFile.Close()
-		if err != nil {
-			return err
+		if opts.retry != nil {
+			err = opts.retry.Catalog.Write()
+			if err != nil {
+				return err
+			}
 		}
 
 		if errStr != "" {
@@ -197,42 +199,248 @@ func OcrImages(commonDirs *config.CommonDirs) model.CliFunc {
 	}
 }
 
+// collectOcrImagePaths returns up to limit images that are missing output for any of the formats.
+// Images that have failed maxAttempts times are skipped, use --retry-failed with a higher --max-attempts for those.
+func collectOcrImagePaths(commonDirs *config.CommonDirs, formats []string, limit int,
+	ledger *failureLedger, maxAttempts int) ([]string, error) {
+	imageSubDirs, err := os.ReadDir(commonDirs.ImageFolder)
+	if err != nil {
+		return nil, err
+	}
+	i := 0
+
+	imagePaths := make([]string, 0)
+	for _, imageSubDir := range imageSubDirs {
+		if i >= limit {
+			break
+		}
+		subDirPath := filepath.Join(commonDirs.ImageFolder, imageSubDir.Name())
+		imageSubDirContents, err := os.ReadDir(subDirPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, imageFile := range imageSubDirContents {
+			imagePath := filepath.Join(subDirPath, imageFile.Name())
+			if ledger.Exhausted(imagePath, maxAttempts) {
+				fmt.Printf("Skipping %s, failed %d times\n", imagePath, maxAttempts)
+				continue
+			}
+			missing, err := missingOcrFormats(commonDirs, imagePath, formats)
+			if err != nil {
+				fmt.Printf("Error checking for ocr output: %s\n", err.Error())
+				return nil, err
+			}
+			if len(missing) == 0 {
+				fmt.Printf("Skipping %s\n", imagePath)
+				continue
+			}
+			imagePaths = append(imagePaths, imagePath)
+			fmt.Printf("Adding %s\n", imagePath)
+			i++
+		}
+	}
+	return imagePaths, nil
+}
+
 func csvPathFromImagePath(imagePath string) string {
 	basePath := filepath.Base(imagePath)
 	return strings.ReplaceAll(basePath, ".png", ".csv")
 }
 
-// extractImageToCsvIfNotExists returns true if the csv file was created, false if it already existed
-// and an error if one occurred
-func extractImageToCsvIfNotExists(imagePath, csvPath string) (bool, error) {
-	var err error
+// ocrFormatsFromCtx returns the validated output formats requested with --format.
+// Defaults to tsv when no format is given.
+func ocrFormatsFromCtx(c *cli.Context) ([]string, error) {
+	formats := c.StringSlice("format")
+	if len(formats) == 0 {
+		return []string{constants.OcrFormatTsv}, nil
+	}
+	for i, format := range formats {
+		format = strings.ToLower(strings.TrimSpace(format))
+		switch format {
+		case constants.OcrFormatTsv, constants.OcrFormatHocr, constants.OcrFormatAlto:
+			formats[i] = format
+		default:
+			return nil, fmt.Errorf("unsupported ocr format %q", format)
+		}
+	}
+	return formats, nil
+}
+
+// ocrOutputPath returns the file path the given format is written to for an image.
+// TSV output is written to the csv folder, hOCR and ALTO to the ocr folder.
+func ocrOutputPath(commonDirs *config.CommonDirs, imagePath, format string) string {
+	switch format {
+	case constants.OcrFormatHocr:
+		return filepath.Join(commonDirs.OcrFolder, outputNameFromImagePath(imagePath, ".hocr"))
+	case constants.OcrFormatAlto:
+		return filepath.Join(commonDirs.OcrFolder, outputNameFromImagePath(imagePath, ".alto.xml"))
+	default:
+		return filepath.Join(commonDirs.CsvFolder, csvPathFromImagePath(imagePath))
+	}
+}
+
+// missingOcrFormats returns the formats that do not yet have an output file for the image
+func missingOcrFormats(commonDirs *config.CommonDirs, imagePath string, formats []string) ([]string, error) {
+	missing := make([]string, 0, len(formats))
+	for _, format := range formats {
+		_, err := os.Stat(ocrOutputPath(commonDirs, imagePath, format))
+		if err == nil {
+			continue
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+		missing = append(missing, format)
+	}
+	return missing, nil
+}
+
+func outputNameFromImagePath(imagePath, extension string) string {
+	basePath := filepath.Base(imagePath)
+	return strings.TrimSuffix(basePath, filepath.Ext(basePath)) + extension
+}
+
+// pageNumberFromImagePath returns the page number from an image named {name}-{page no}.png
+func pageNumberFromImagePath(imagePath string) (int, error) {
+	name := strings.TrimSuffix(filepath.Base(imagePath), filepath.Ext(imagePath))
+	idx := strings.LastIndex(name, "-")
+	if idx < 0 {
+		return 0, fmt.Errorf("no page number in image name %s", filepath.Base(imagePath))
+	}
+	pageNumber, err := strconv.Atoi(name[idx+1:])
+	if err != nil {
+		return 0, fmt.Errorf("no page number in image name %s: %w", filepath.Base(imagePath), err)
+	}
+	return pageNumber, nil
+}
+
+// extractImageIfNotExists returns true if any output file was created, false if all already existed
+// and an error if one occurred.
+// The image is only run through Tesseract once, all missing formats are written from the same client.
+// When a preprocessing pipeline is configured the preprocessed image is used for OCR instead of the raw image.
+func extractImageIfNotExists(commonDirs *config.CommonDirs, imagePath string, opts *ocrOptions) (bool, error) {
+	missing, err := missingOcrFormats(commonDirs, imagePath, opts.formats)
+	if err != nil {
+		return false, err
+	}
+	if len(missing) == 0 {
+		return false, nil
+	}
 	var client *gosseract.Client
 	client, err = initializeClient(imagePath)
 	if err != nil {
 		return false, err
 	}
-	gocsv.SetCSVWriter(func(out io.Writer) *gocsv.SafeCSVWriter {
-		writer := csv.NewWriter(out)
-		writer.Comma = '\t'
-		return gocsv.NewSafeCSVWriter(writer)
-	})
-	ocrResults := extractOcrResults(client)
-	if _, err := os.Stat(csvPath); os.IsNotExist(err) {
-		csvFile, err := os.OpenFile(csvPath, os.O_CREATE|os.O_RDWR, os.ModePerm)
+	defer func() {
+		_ = client.Close()
+	}()
+
+	var ocrResults []*model.OcrResult
+	if opts.pipeline != nil {
+		ocrResults, err = preprocessClientImage(client, imagePath, opts)
 		if err != nil {
 			return false, err
 		}
-		err = gocsv.MarshalFile(&ocrResults, csvFile)
+	}
+	// hocrScale scales the coordinates of the client's image to the stored page image
+	hocrScale := 1.0
+	if opts.retry != nil {
+		if ocrResults == nil {
+			ocrResults = extractOcrResults(client)
+		}
+		ocrResults, hocrScale, err = opts.retry.Run(client, commonDirs, imagePath, opts.pipeline, ocrResults)
 		if err != nil {
 			return false, err
 		}
-		err = csvFile.Close()
+	}
+	for _, format := range missing {
+		outPath := ocrOutputPath(commonDirs, imagePath, format)
+		switch format {
+		case constants.OcrFormatHocr:
+			err = writeHocr(client, outPath, hocrScale)
+		case constants.OcrFormatAlto:
+			if ocrResults == nil {
+				ocrResults = extractOcrResults(client)
+			}
+			err = writeAlto(imagePath, outPath, ocrResults)
+		default:
+			if ocrResults == nil {
+				ocrResults = extractOcrResults(client)
+			}
+			err = writeOcrResultsCsv(outPath, ocrResults)
+		}
 		if err != nil {
 			return false, err
 		}
-		return true, nil
 	}
-	return false, nil
+	return true, nil
+}
+
+func writeOcrResultsCsv(csvPath string, ocrResults []*model.OcrResult) error {
+	gocsv.SetCSVWriter(func(out io.Writer) *gocsv.SafeCSVWriter {
+		writer := csv.NewWriter(out)
+		writer.Comma = '\t'
+		return gocsv.NewSafeCSVWriter(writer)
+	})
+	csvFile, err := os.OpenFile(csvPath, os.O_CREATE|os.O_RDWR, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	err = gocsv.MarshalFile(&ocrResults, csvFile)
+	if err != nil {
+		_ = csvFile.Close()
+		return err
+	}
+	return csvFile.Close()
+}
+
+// writeHocr writes the Tesseract hOCR output for the current client image.
+// The bounding boxes are multiplied by scale, so they match the stored page image when the client
+// holds a rendering at another resolution.
+func writeHocr(client *gosseract.Client, hocrPath string, scale float64) error {
+	out, err := client.HOCRText()
+	if err != nil {
+		return err
+	}
+	if scale != 1 {
+		out = scaleHocrBoxes(out, scale)
+	}
+	return os.WriteFile(hocrPath, []byte(out), 0644)
+}
+
+// scaleHocrBoxes multiplies the coordinates of every bbox property in the hOCR by scale
+func scaleHocrBoxes(hocr string, scale float64) string {
+	return hocrBboxPattern.ReplaceAllStringFunc(hocr, func(bbox string) string {
+		fields := strings.Fields(bbox)
+		for i, field := range fields[1:] {
+			coordinate, _ := strconv.Atoi(field)
+			fields[i+1] = strconv.Itoa(int(float64(coordinate) * scale))
+		}
+		return strings.Join(fields, " ")
+	})
+}
+
+// writeAlto writes an ALTO XML document built from the word bounding boxes of the image
+func writeAlto(imagePath, altoPath string, ocrResults []*model.OcrResult) error {
+	f, err := os.Open(imagePath)
+	if err != nil {
+		return err
+	}
+	imgConfig, _, err := image.DecodeConfig(f)
+	_ = f.Close()
+	if err != nil {
+		return err
+	}
+	pageNumber, err := pageNumberFromImagePath(imagePath)
+	if err != nil {
+		return err
+	}
+	doc := model.NewAltoDocument(filepath.Base(imagePath), pageNumber,
+		imgConfig.Width, imgConfig.Height, ocrResults)
+	out, err := doc.Marshal()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(altoPath, out, 0644)
 }
 
 func initializeClient(imagePath string) (*gosseract.Client, error) {
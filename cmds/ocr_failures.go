@@ -0,0 +1,120 @@
+package cmds
+
+import (
+	"encoding/csv"
+	"errors"
+	"github.com/gocarina/gocsv"
+	"github.com/paulschick/disclosureupdater/model"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// failureLedger tracks images that failed OCR so they can be retried with --retry-failed.
+// Entries are keyed by image path, an image that succeeds is removed from the ledger.
+type failureLedger struct {
+	path     string
+	failures map[string]*model.OcrFailure
+}
+
+// loadFailureLedger reads the ledger at path, an empty ledger is returned if it doesn't exist yet
+func loadFailureLedger(path string) (*failureLedger, error) {
+	ledger := &failureLedger{
+		path:     path,
+		failures: make(map[string]*model.OcrFailure),
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ledger, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	gocsv.SetCSVReader(func(in io.Reader) gocsv.CSVReader {
+		reader := csv.NewReader(in)
+		reader.Comma = '\t'
+		return reader
+	})
+	failures := make([]*model.OcrFailure, 0)
+	err = gocsv.UnmarshalFile(f, &failures)
+	if err != nil && !errors.Is(err, gocsv.ErrEmptyCSVFile) {
+		return nil, err
+	}
+	for _, failure := range failures {
+		ledger.failures[failure.ImagePath] = failure
+	}
+	return ledger, nil
+}
+
+// Record adds a failed attempt for the image
+func (l *failureLedger) Record(imagePath string, err error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	failure, ok := l.failures[imagePath]
+	if !ok {
+		failure = &model.OcrFailure{
+			ImagePath:    imagePath,
+			FirstFailure: now,
+		}
+		l.failures[imagePath] = failure
+	}
+	failure.Attempts++
+	failure.LastFailure = now
+	failure.Error = strings.ReplaceAll(err.Error(), "\n", " ")
+}
+
+// Resolve removes the image from the ledger after it was processed successfully
+func (l *failureLedger) Resolve(imagePath string) {
+	delete(l.failures, imagePath)
+}
+
+// Exhausted returns true if the image has failed maxAttempts times or more
+func (l *failureLedger) Exhausted(imagePath string, maxAttempts int) bool {
+	failure, ok := l.failures[imagePath]
+	return ok && maxAttempts > 0 && failure.Attempts >= maxAttempts
+}
+
+// Retryable returns the image paths that have not reached maxAttempts yet
+func (l *failureLedger) Retryable(maxAttempts int) []string {
+	imagePaths := make([]string, 0, len(l.failures))
+	for imagePath := range l.failures {
+		if !l.Exhausted(imagePath, maxAttempts) {
+			imagePaths = append(imagePaths, imagePath)
+		}
+	}
+	sort.Strings(imagePaths)
+	return imagePaths
+}
+
+func (l *failureLedger) Len() int {
+	return len(l.failures)
+}
+
+// Write replaces the ledger file with the current entries
+func (l *failureLedger) Write() error {
+	gocsv.SetCSVWriter(func(out io.Writer) *gocsv.SafeCSVWriter {
+		writer := csv.NewWriter(out)
+		writer.Comma = '\t'
+		return gocsv.NewSafeCSVWriter(writer)
+	})
+	failures := make([]*model.OcrFailure, 0, len(l.failures))
+	for _, failure := range l.failures {
+		failures = append(failures, failure)
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		return failures[i].ImagePath < failures[j].ImagePath
+	})
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	err = gocsv.MarshalFile(&failures, f)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,126 @@
+package cmds
+
+import (
+	"errors"
+	"fmt"
+	"github.com/otiai10/gosseract/v2"
+	"github.com/paulschick/disclosureupdater/config"
+	"github.com/paulschick/disclosureupdater/model"
+	"github.com/paulschick/disclosureupdater/preprocess"
+	"github.com/urfave/cli/v2"
+	"path/filepath"
+	"strings"
+)
+
+// ocrOptions holds the settings shared by every image in an ocr-images run
+type ocrOptions struct {
+	formats  []string
+	pipeline *preprocess.Pipeline
+	stats    *preprocessStats
+	retry    *ocrRetry
+}
+
+func ocrOptionsFromCtx(c *cli.Context, commonDirs *config.CommonDirs) (*ocrOptions, error) {
+	formats, err := ocrFormatsFromCtx(c)
+	if err != nil {
+		return nil, err
+	}
+	opts := &ocrOptions{
+		formats: formats,
+	}
+	steps := c.StringSlice("preprocess")
+	if c.Bool("preprocess-stats") && len(steps) == 0 {
+		return nil, errors.New("--preprocess-stats needs the preprocessing steps of --preprocess")
+	}
+	if len(steps) > 0 {
+		preprocessOpts, err := preprocess.OptionsFromSteps(steps)
+		if err != nil {
+			return nil, err
+		}
+		if c.IsSet("despeckle-size") {
+			preprocessOpts.DespeckleSize = c.Int("despeckle-size")
+		}
+		if c.IsSet("max-skew") {
+			preprocessOpts.MaxSkewAngle = c.Float64("max-skew")
+		}
+		opts.pipeline = preprocess.NewPipeline(preprocessOpts)
+		if c.Bool("preprocess-stats") {
+			opts.stats = newPreprocessStats(filepath.Join(commonDirs.CsvFolder, "preprocess_stats.csv"))
+		}
+	}
+	opts.retry, err = ocrRetryFromCtx(c, commonDirs, opts.pipeline)
+	if err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// preprocessClientImage replaces the client image with the preprocessed version of the image.
+// When stats are collected the image is run through OCR before and after preprocessing,
+// the results after preprocessing are returned so they don't have to be extracted again.
+func preprocessClientImage(client *gosseract.Client, imagePath string, opts *ocrOptions) ([]*model.OcrResult, error) {
+	var before []*model.OcrResult
+	if opts.stats != nil {
+		before = extractOcrResults(client)
+	}
+	report, err := applyPipeline(client, imagePath, opts.pipeline)
+	if err != nil {
+		return nil, err
+	}
+	if opts.stats == nil {
+		return nil, nil
+	}
+	after := extractOcrResults(client)
+	opts.stats.Add(&model.PreprocessStat{
+		Image:            filepath.Base(imagePath),
+		Steps:            strings.Join(report.Steps, ","),
+		Threshold:        int(report.Threshold),
+		SkewAngle:        report.SkewAngle,
+		WordsBefore:      len(before),
+		ConfidenceBefore: model.MeanConfidence(before),
+		WordsAfter:       len(after),
+		ConfidenceAfter:  model.MeanConfidence(after),
+	})
+	return after, nil
+}
+
+// applyPipeline sets the client image to the preprocessed version of the image at imagePath
+func applyPipeline(client *gosseract.Client, imagePath string, pipeline *preprocess.Pipeline) (*preprocess.Report, error) {
+	img, err := readImage(imagePath)
+	if err != nil {
+		return nil, err
+	}
+	processed, report, err := pipeline.ApplyPng(img)
+	if err != nil {
+		return nil, err
+	}
+	return report, client.SetImageFromBytes(processed)
+}
+
+// preprocessStats collects before and after confidence for each preprocessed image
+// and appends them to a tab separated file at the end of the run
+type preprocessStats struct {
+	*tsvAppender[model.PreprocessStat]
+}
+
+func newPreprocessStats(path string) *preprocessStats {
+	return &preprocessStats{
+		tsvAppender: newTsvAppender[model.PreprocessStat](path),
+	}
+}
+
+// Summary returns the mean confidence before and after preprocessing across all images
+func (p *preprocessStats) Summary() string {
+	stats := p.Records()
+	if len(stats) == 0 {
+		return "No preprocessing stats collected"
+	}
+	var before, after float64
+	for _, stat := range stats {
+		before += stat.ConfidenceBefore
+		after += stat.ConfidenceAfter
+	}
+	n := float64(len(stats))
+	return fmt.Sprintf("Preprocessed %d images, mean confidence before %.2f after %.2f",
+		len(stats), before/n, after/n)
+}
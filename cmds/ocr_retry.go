@@ -0,0 +1,242 @@
+package cmds
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gen2brain/go-fitz"
+	"github.com/otiai10/gosseract/v2"
+	"github.com/paulschick/disclosureupdater/config"
+	"github.com/paulschick/disclosureupdater/model"
+	"github.com/paulschick/disclosureupdater/preprocess"
+	"github.com/urfave/cli/v2"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// fitzDefaultDpi is the resolution go-fitz renders pages at for convert-pdfs
+const fitzDefaultDpi = 300
+
+// ocrAttempt describes one alternate way of running OCR over a page.
+// A Dpi of 0 uses the existing page image, otherwise the page is rendered again from the original PDF.
+type ocrAttempt struct {
+	Name     string
+	Dpi      float64
+	Psm      gosseract.PageSegMode
+	Pipeline *preprocess.Pipeline
+}
+
+// ocrRetry re-runs OCR with alternate settings for pages whose mean word confidence is below Threshold
+type ocrRetry struct {
+	Threshold float64
+	Attempts  []*ocrAttempt
+	Catalog   *tsvAppender[model.OcrAttempt]
+}
+
+func ocrRetryFromCtx(c *cli.Context, commonDirs *config.CommonDirs, pipeline *preprocess.Pipeline) (*ocrRetry, error) {
+	threshold := c.Float64("retry-below")
+	if threshold <= 0 {
+		return nil, nil
+	}
+	dpi := c.Float64("retry-dpi")
+	psm := gosseract.PageSegMode(c.Int("retry-psm"))
+	retryOpts, err := preprocess.OptionsFromSteps(c.StringSlice("retry-preprocess"))
+	if err != nil {
+		return nil, err
+	}
+	attempts := []*ocrAttempt{
+		{Name: "psm", Psm: psm, Pipeline: pipeline},
+		{Name: "dpi", Dpi: dpi, Psm: gosseract.PSM_AUTO, Pipeline: pipeline},
+	}
+	if retryOpts.Enabled() {
+		attempts = append(attempts, &ocrAttempt{
+			Name:     "dpi-preprocess",
+			Dpi:      dpi,
+			Psm:      psm,
+			Pipeline: preprocess.NewPipeline(retryOpts),
+		})
+	}
+	return &ocrRetry{
+		Threshold: threshold,
+		Attempts:  attempts,
+		Catalog:   newTsvAppender[model.OcrAttempt](filepath.Join(commonDirs.CsvFolder, "ocr_catalog.csv")),
+	}, nil
+}
+
+// Run records the initial results and, when their mean confidence is below the threshold,
+// tries each alternate attempt until one reaches the threshold.
+// The results with the highest mean confidence are returned and the client is left set up
+// with the matching image so formats read from the client, like hOCR, use the same attempt.
+// The returned scale converts the coordinates of the client's image to the stored page image.
+func (r *ocrRetry) Run(client *gosseract.Client, commonDirs *config.CommonDirs, imagePath string,
+	pipeline *preprocess.Pipeline, initial []*model.OcrResult) ([]*model.OcrResult, float64, error) {
+	createdAt := time.Now().UTC().Format(time.RFC3339)
+	records := []*model.OcrAttempt{
+		{
+			Image:          filepath.Base(imagePath),
+			Name:           "initial",
+			Dpi:            fitzDefaultDpi,
+			Psm:            int(gosseract.PSM_AUTO),
+			Preprocess:     pipelineSteps(pipeline),
+			Words:          len(initial),
+			MeanConfidence: model.MeanConfidence(initial),
+			CreatedAt:      createdAt,
+		},
+	}
+	best := initial
+	bestIdx := 0
+	var bestImage []byte
+	bestScale := 1.0
+
+	for _, attempt := range r.Attempts {
+		if records[bestIdx].MeanConfidence >= r.Threshold {
+			break
+		}
+		imgBytes, scale, err := attempt.image(commonDirs, imagePath)
+		if err != nil {
+			return nil, 0, err
+		}
+		results, err := attempt.run(client, imgBytes, scale)
+		if err != nil {
+			return nil, 0, err
+		}
+		records = append(records, &model.OcrAttempt{
+			Image:          filepath.Base(imagePath),
+			Attempt:        len(records),
+			Name:           attempt.Name,
+			Dpi:            attempt.dpi(),
+			Psm:            int(attempt.Psm),
+			Preprocess:     pipelineSteps(attempt.Pipeline),
+			Words:          len(results),
+			MeanConfidence: model.MeanConfidence(results),
+			CreatedAt:      createdAt,
+		})
+		if records[len(records)-1].MeanConfidence > records[bestIdx].MeanConfidence {
+			best = results
+			bestIdx = len(records) - 1
+			bestImage = imgBytes
+			bestScale = scale
+		}
+	}
+	records[bestIdx].Kept = true
+	for _, record := range records {
+		r.Catalog.Add(record)
+	}
+	if len(records) > 1 {
+		fmt.Printf("Re-OCR %s: kept %s with mean confidence %.2f\n",
+			imagePath, records[bestIdx].Name, records[bestIdx].MeanConfidence)
+	}
+
+	// leave the client on the kept attempt
+	switch {
+	case len(records) == 1:
+		return best, 1, nil
+	case bestIdx == 0:
+		err := client.SetPageSegMode(gosseract.PSM_AUTO)
+		if err != nil {
+			return nil, 0, err
+		}
+		if pipeline != nil {
+			_, err = applyPipeline(client, imagePath, pipeline)
+			return best, 1, err
+		}
+		return best, 1, client.SetImage(imagePath)
+	default:
+		err := client.SetPageSegMode(r.Attempts[bestIdx-1].Psm)
+		if err != nil {
+			return nil, 0, err
+		}
+		return best, bestScale, client.SetImageFromBytes(bestImage)
+	}
+}
+
+func (a *ocrAttempt) dpi() float64 {
+	if a.Dpi == 0 {
+		return fitzDefaultDpi
+	}
+	return a.Dpi
+}
+
+// image returns the PNG encoded image for the attempt and the factor that scales
+// its coordinates back to the coordinates of the stored page image
+func (a *ocrAttempt) image(commonDirs *config.CommonDirs, imagePath string) ([]byte, float64, error) {
+	pageImage, err := readImage(imagePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	img := pageImage
+	scale := 1.0
+	if a.Dpi > 0 {
+		pageNumber, err := pageNumberFromImagePath(imagePath)
+		if err != nil {
+			return nil, 0, err
+		}
+		img, err = renderPage(pdfPathFromImagePath(commonDirs, imagePath), pageNumber, a.Dpi)
+		if err != nil {
+			return nil, 0, err
+		}
+		scale = float64(pageImage.Bounds().Dx()) / float64(img.Bounds().Dx())
+	}
+	if a.Pipeline != nil {
+		out, _, err := a.Pipeline.ApplyPng(img)
+		return out, scale, err
+	}
+	var buf bytes.Buffer
+	err = png.Encode(&buf, img)
+	return buf.Bytes(), scale, err
+}
+
+// run runs OCR over the image and scales the word boxes back to page image coordinates
+func (a *ocrAttempt) run(client *gosseract.Client, imgBytes []byte, scale float64) ([]*model.OcrResult, error) {
+	err := client.SetPageSegMode(a.Psm)
+	if err != nil {
+		return nil, err
+	}
+	err = client.SetImageFromBytes(imgBytes)
+	if err != nil {
+		return nil, err
+	}
+	results := extractOcrResults(client)
+	if scale != 1 {
+		for _, result := range results {
+			result.Left = int(float64(result.Left) * scale)
+			result.Top = int(float64(result.Top) * scale)
+			result.Right = int(float64(result.Right) * scale)
+			result.Bottom = int(float64(result.Bottom) * scale)
+		}
+	}
+	return results, nil
+}
+
+// pdfPathFromImagePath returns the disclosure PDF a page image named {name}-{page no}.png was rendered from
+func pdfPathFromImagePath(commonDirs *config.CommonDirs, imagePath string) string {
+	name := strings.TrimSuffix(filepath.Base(imagePath), filepath.Ext(imagePath))
+	if idx := strings.LastIndex(name, "-"); idx >= 0 {
+		name = name[:idx]
+	}
+	return filepath.Join(commonDirs.DisclosuresFolder, name+".pdf")
+}
+
+func renderPage(pdfPath string, pageNumber int, dpi float64) (image.Image, error) {
+	if _, err := os.Stat(pdfPath); err != nil {
+		return nil, err
+	}
+	doc, err := fitz.New(pdfPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = doc.Close()
+	}()
+	return doc.ImageDPI(pageNumber, dpi)
+}
+
+func pipelineSteps(pipeline *preprocess.Pipeline) string {
+	if pipeline == nil {
+		return ""
+	}
+	return strings.Join(pipeline.Options.Steps(), ",")
+}
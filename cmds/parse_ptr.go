@@ -0,0 +1,183 @@
+package cmds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/paulschick/disclosureupdater/config"
+	"github.com/paulschick/disclosureupdater/model"
+	"github.com/paulschick/disclosureupdater/ptr"
+	"github.com/paulschick/disclosureupdater/registry"
+	"github.com/paulschick/disclosureupdater/storage"
+	"github.com/urfave/cli/v2"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TransactionsFileName is the file parse-ptr writes to the csv folder
+const TransactionsFileName = "transactions.csv"
+
+// SecurityMasterFileName is the security master parse-ptr reads from the csv folder if --securities isn't set
+const SecurityMasterFileName = "securities.csv"
+
+// Values of the parse-ptr --method flag
+const (
+	ParseMethodOcr  = "ocr"
+	ParseMethodText = "text"
+	ParseMethodAuto = "auto"
+)
+
+// errNoTextLayer is returned for PDFs without a PTR table in their text layer, i.e. scanned paper filings
+var errNoTextLayer = errors.New("no transaction table in the text layer")
+
+// ParsePtr extracts the transactions from every PTR and writes them to csv/transactions.csv.
+// E-filed PTRs are parsed from the PDF text layer, scanned PTRs from the OCR output.
+func ParsePtr(commonDirs *config.CommonDirs) model.CliFunc {
+	return func(c *cli.Context) error {
+		method := strings.ToLower(c.String("method"))
+		if method != ParseMethodOcr && method != ParseMethodText && method != ParseMethodAuto {
+			return fmt.Errorf("invalid method %q, expected %s, %s or %s",
+				method, ParseMethodOcr, ParseMethodText, ParseMethodAuto)
+		}
+		limit := c.Int("limit")
+		if limit == 0 {
+			limit = math.MaxInt
+		}
+		resolver, err := assetResolverFromCtx(c, commonDirs)
+		if err != nil {
+			return err
+		}
+		indexMembers, err := loadIndexMembers(commonDirs)
+		if err != nil {
+			fmt.Printf("Error reading disclosure index: %s\n", err)
+			return err
+		}
+		reg, err := loadRegistry(commonDirs, indexMembers)
+		if err != nil {
+			return err
+		}
+		store, err := artifactStoreFromCtx(c, commonDirs)
+		if err != nil {
+			return err
+		}
+		return parsePtrs(c.Context, store, method, limit, resolver, reg)
+	}
+}
+
+// parsePtrs parses the PTRs in the disclosures backend with the given method and writes their transactions to
+// transactions.csv in the csv backend
+func parsePtrs(ctx context.Context, store *artifactStore, method string, limit int, resolver *ptr.AssetResolver,
+	reg *registry.Registry) error {
+	pdfs, err := listPdfs(ctx, store.Disclosures)
+	if err != nil {
+		return err
+	}
+
+	transactions := make([]*model.Transaction, 0)
+	parsed, skipped := 0, 0
+	for _, pdfName := range pdfs {
+		if parsed >= limit {
+			break
+		}
+		member, err := model.ParsePdfFileName(pdfName)
+		if err != nil || member.FilingType != "P" {
+			continue
+		}
+		docTransactions, err := parsePtrPdf(ctx, store, pdfName, member, method)
+		if errors.Is(err, errPageNotReady) || errors.Is(err, errNoTextLayer) {
+			skipped++
+			continue
+		} else if err != nil {
+			fmt.Printf("Error parsing %s: %s\n", pdfName, err)
+			return err
+		}
+		for _, transaction := range docTransactions {
+			transaction.MemberId = reg.MemberId(transaction.DocId)
+			resolver.Resolve(transaction)
+		}
+		fmt.Printf("Parsed %d transactions from %s\n", len(docTransactions), pdfName)
+		transactions = append(transactions, docTransactions...)
+		parsed++
+	}
+	fmt.Printf("Parsed %d PTRs, skipped %d without a text layer or OCR output\n", parsed, skipped)
+
+	err = writeTsv(ctx, store.Csv, TransactionsFileName, &transactions)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d transactions to %s\n", len(transactions), objectPath(store.Csv, TransactionsFileName))
+	return nil
+}
+
+// assetResolverFromCtx loads the security master from --securities, or from the csv folder if it exists there
+func assetResolverFromCtx(c *cli.Context, commonDirs *config.CommonDirs) (*ptr.AssetResolver, error) {
+	minScore := c.Float64("min-match")
+	if minScore == 0 {
+		minScore = ptr.DefaultMinMatchScore
+	}
+	path := c.String("securities")
+	if path == "" {
+		path = filepath.Join(commonDirs.CsvFolder, SecurityMasterFileName)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			fmt.Println("No security master found, tickers are only taken from asset names")
+			return ptr.NewAssetResolver(nil, minScore), nil
+		}
+	}
+	securities, err := ptr.LoadSecurityMaster(path)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Loaded %d securities from %s\n", len(securities), path)
+	return ptr.NewAssetResolver(securities, minScore), nil
+}
+
+// parsePtrPdf parses the PDF with the given method, auto uses the text layer if it has the transaction table
+func parsePtrPdf(ctx context.Context, store *artifactStore, pdfName string, member *model.Member,
+	method string) ([]*model.Transaction, error) {
+	if method == ParseMethodOcr {
+		return parseOcrPtr(ctx, store, pdfName, member)
+	}
+	pages, err := readPdfTextPages(ctx, store.Disclosures, pdfName)
+	if err != nil {
+		return nil, err
+	}
+	if !ptr.ContainsTableHeader(pages) {
+		if method == ParseMethodAuto {
+			return parseOcrPtr(ctx, store, pdfName, member)
+		}
+		return nil, fmt.Errorf("%w: %s", errNoTextLayer, pdfName)
+	}
+	return ptr.NewTextParser(member.DocId, pdfName).Parse(pages), nil
+}
+
+// parseOcrPtr parses the OCR output of every page of the PDF in page order
+func parseOcrPtr(ctx context.Context, store *artifactStore, pdfName string, member *model.Member) ([]*model.Transaction, error) {
+	baseFileName := strings.TrimSuffix(pdfName, ".pdf")
+	parser := ptr.NewOcrTableParser(member.DocId, pdfName)
+	transactions := make([]*model.Transaction, 0)
+	for page := 0; ; page++ {
+		csvName := fmt.Sprintf("%s-%d.csv", baseFileName, page)
+		words, err := readOcrResults(ctx, store.Csv, csvName)
+		if errors.Is(err, os.ErrNotExist) {
+			if page == 0 {
+				return nil, fmt.Errorf("%w: %s", errPageNotReady, csvName)
+			}
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, parser.ParsePage(page, words)...)
+	}
+	return transactions, nil
+}
+
+// readTransactions reads the transactions written by parse-ptr
+func readTransactions(ctx context.Context, b storage.Backend, key string) ([]*model.Transaction, error) {
+	transactions := make([]*model.Transaction, 0)
+	if err := readTsv(ctx, b, key, &transactions); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
@@ -0,0 +1,77 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"github.com/paulschick/disclosureupdater/model"
+	"github.com/paulschick/disclosureupdater/ptr"
+	"github.com/paulschick/disclosureupdater/registry"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ocrLine lays out the words of every cell starting at its left edge on a line at top
+func ocrLine(top int, cells map[int]string) []*model.OcrResult {
+	results := make([]*model.OcrResult, 0)
+	for left, text := range cells {
+		x := left
+		for _, word := range strings.Fields(text) {
+			results = append(results, &model.OcrResult{Word: word, Confidence: 90, Left: x, Top: top,
+				Right: x + len(word)*10, Bottom: top + 20})
+			x += len(word)*10 + 10
+		}
+	}
+	return results
+}
+
+func TestParsePtrs(t *testing.T) {
+	ctx := context.Background()
+	store := memoryArtifactStore()
+	efiled, err := os.ReadFile(filepath.Join("..", "ptr", "testdata", "efiled_rows.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages := strings.Split(string(efiled), "\f")
+	putTestPdf(t, store, "2023.ptr-pdfs.TX07.Roe.John.20012345.pdf", pages)
+	// scanned PTRs only have a filing ID in the text layer
+	putTestPdf(t, store, "2023.ptr-pdfs.CA12.Doe.Jane.20012346.pdf", []string{"Filing ID #20012346"})
+	putTestPdf(t, store, "2023.ptr-pdfs.NY07.Poe.Ann.20012347.pdf", []string{"Filing ID #20012347"})
+	putTestPdf(t, store, "2023.financial-pdfs.TX07.Roe.John.10012345.pdf", pages)
+	words := append(ocrLine(200, map[int]string{
+		0: "ID", 50: "Owner", 150: "Asset", 600: "Transaction Type", 800: "Date",
+		950: "Notification Date", 1150: "Amount", 1400: "Cap. Gains > $200?",
+	}), ocrLine(240, map[int]string{
+		50: "SP", 150: "Apple Inc. (AAPL) [ST]", 600: "P", 800: "01/03/2023", 950: "01/20/2023",
+		1150: "$1,001 - $15,000",
+	})...)
+	putOcrPage(t, store, "2023.ptr-pdfs.CA12.Doe.Jane.20012346-0.png", words)
+
+	reg := registry.NewRegistry(nil)
+	reg.Add([]*model.Member{
+		{First: "John", Last: "Roe", StateDst: "TX07", Year: 2023, FilingType: "P", DocId: 20012345},
+		{First: "Jane", Last: "Doe", StateDst: "CA12", Year: 2023, FilingType: "P", DocId: 20012346},
+	})
+	resolver := ptr.NewAssetResolver(nil, ptr.DefaultMinMatchScore)
+	if err = parsePtrs(ctx, store, ParseMethodAuto, 10, resolver, reg); err != nil {
+		t.Fatal(err)
+	}
+
+	transactions, err := readTransactions(ctx, store.Csv, TransactionsFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	methods := make(map[int]string)
+	for _, transaction := range transactions {
+		methods[transaction.DocId] += string(transaction.Method) + " "
+		if transaction.MemberId == "" || transaction.MemberId != reg.MemberId(transaction.DocId) {
+			t.Errorf("MemberId of %d = %q; want %q", transaction.DocId, transaction.MemberId,
+				reg.MemberId(transaction.DocId))
+		}
+	}
+	want := map[int]string{20012345: "text text text text ", 20012346: "ocr "}
+	if fmt.Sprint(methods) != fmt.Sprint(want) {
+		t.Errorf("parsePtrs() methods by DocId = %v; want %v", methods, want)
+	}
+}
@@ -0,0 +1,199 @@
+package cmds
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/paulschick/disclosureupdater/config"
+	"github.com/paulschick/disclosureupdater/model"
+	"github.com/paulschick/disclosureupdater/registry"
+	"github.com/paulschick/disclosureupdater/search"
+	"github.com/paulschick/disclosureupdater/storage"
+	"github.com/urfave/cli/v2"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SearchIndexFileName is the file update-search-index writes the search index to in the data folder
+const SearchIndexFileName = "search_index.gob"
+
+// minTextLayerWords is the number of words a page's text layer needs to be indexed instead of its OCR output.
+// Scanned PDFs have no text layer, or only a stamp like "Filing ID #12345".
+const minTextLayerWords = 10
+
+// UpdateSearchIndex adds the disclosure PDFs that aren't in the full text search index yet, from their text layer.
+// Pages without a text layer are indexed from their OCR output, pages without either are skipped.
+// The metadata of filings already in the index is updated, with --rebuild every PDF is indexed again.
+func UpdateSearchIndex(commonDirs *config.CommonDirs) model.CliFunc {
+	return func(c *cli.Context) error {
+		limit := c.Int("limit")
+		if limit == 0 {
+			limit = math.MaxInt
+		}
+		indexMembers, err := loadIndexMembers(commonDirs)
+		if err != nil {
+			fmt.Printf("Error reading disclosure index: %s\n", err)
+			return err
+		}
+		members := make(map[int]*model.Member, len(indexMembers))
+		for _, member := range indexMembers {
+			members[member.DocId] = member
+		}
+		reg, err := loadRegistry(commonDirs, indexMembers)
+		if err != nil {
+			return err
+		}
+		store, err := artifactStoreFromCtx(c, commonDirs)
+		if err != nil {
+			return err
+		}
+
+		outPath := filepath.Join(commonDirs.DataFolder, SearchIndexFileName)
+		ix := search.NewIndex()
+		if !c.Bool("rebuild") {
+			ix, err = search.Load(outPath)
+			if errors.Is(err, os.ErrNotExist) {
+				ix = search.NewIndex()
+			} else if err != nil {
+				fmt.Printf("Error reading search index: %s\n", err)
+				return err
+			}
+		}
+		if err = indexPdfs(c.Context, store, ix, members, reg, limit); err != nil {
+			return err
+		}
+
+		err = ix.Save(outPath)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %d terms to %s\n", len(ix.Postings), outPath)
+		return nil
+	}
+}
+
+// indexPdfs adds at most limit PDFs of the disclosures backend that aren't in the index yet, and updates the
+// metadata of the ones that are. Page texts are read from the text layer, or the OCR TSVs of the csv backend.
+func indexPdfs(ctx context.Context, store *artifactStore, ix *search.Index, members map[int]*model.Member,
+	reg *registry.Registry, limit int) error {
+	pdfs, err := listPdfs(ctx, store.Disclosures)
+	if err != nil {
+		return err
+	}
+
+	indexed, unchanged, textPages, ocrPages, skippedPages := 0, 0, 0, 0, 0
+	for _, pdfName := range pdfs {
+		if indexed >= limit {
+			break
+		}
+		member, err := model.ParsePdfFileName(pdfName)
+		if err != nil {
+			continue
+		}
+		if indexMember, ok := members[member.DocId]; ok {
+			member = indexMember
+		}
+		filing := &search.Filing{
+			DocId:      member.DocId,
+			MemberId:   reg.MemberId(member.DocId),
+			Member:     member.FullName(),
+			StateDst:   member.StateDst,
+			Year:       member.Year,
+			FilingType: member.FilingType,
+			FilingDate: member.FilingDate,
+			SourcePdf:  pdfName,
+		}
+		if ix.Contains(member.DocId) {
+			ix.AddFiling(filing)
+			unchanged++
+			continue
+		}
+		pages, err := readPdfTextPages(ctx, store.Disclosures, pdfName)
+		if err != nil {
+			fmt.Printf("Error reading %s: %s\n", pdfName, err)
+			continue
+		}
+		ix.AddFiling(filing)
+		baseFileName := strings.TrimSuffix(pdfName, ".pdf")
+		for n, text := range pages {
+			page := &search.Page{DocId: member.DocId, Page: n, Method: model.ExtractionText, Text: text}
+			if len(strings.Fields(text)) < minTextLayerWords {
+				text, err = readOcrPageText(ctx, store.Csv, baseFileName, n)
+				if errors.Is(err, os.ErrNotExist) {
+					skippedPages++
+					continue
+				} else if err != nil {
+					return err
+				}
+				page.Method, page.Text = model.ExtractionOcr, text
+				ocrPages++
+			} else {
+				textPages++
+			}
+			ix.AddPage(page)
+		}
+		indexed++
+	}
+	fmt.Printf("Indexed %d PDFs, %d pages from the text layer, %d from OCR output, skipped %d pages without either\n",
+		indexed, textPages, ocrPages, skippedPages)
+	fmt.Printf("Updated the metadata of %d PDFs already in the index\n", unchanged)
+	return nil
+}
+
+// readOcrPageText returns the text of a page from the TSV output of ocr-images
+func readOcrPageText(ctx context.Context, b storage.Backend, baseFileName string, page int) (string, error) {
+	words, err := readOcrResults(ctx, b, fmt.Sprintf("%s-%d.csv", baseFileName, page))
+	if err != nil {
+		return "", err
+	}
+	return search.OcrText(words), nil
+}
+
+// Search prints the pages of the filings that match the query, with a snippet of the matching text.
+// Words in the query must all be on the page, words in quotes must be next to each other.
+func Search(commonDirs *config.CommonDirs) model.CliFunc {
+	return func(c *cli.Context) error {
+		text := strings.Join(c.Args().Slice(), " ")
+		if strings.TrimSpace(text) == "" {
+			return errors.New("missing search query")
+		}
+		ix, err := search.Load(filepath.Join(commonDirs.DataFolder, SearchIndexFileName))
+		if errors.Is(err, os.ErrNotExist) {
+			fmt.Println("No search index found, run update-search-index first")
+			return err
+		} else if err != nil {
+			return err
+		}
+
+		hits, err := ix.Search(search.Query{
+			Text:        text,
+			Years:       c.IntSlice("year"),
+			FilingTypes: c.StringSlice("filing-type"),
+			MemberId:    c.String("member"),
+			Limit:       c.Int("limit"),
+		})
+		if err != nil {
+			fmt.Printf("Error reading the page texts of the search index: %s\n", err)
+			return err
+		}
+		if c.Bool("json") {
+			encoder := json.NewEncoder(os.Stdout)
+			for _, hit := range hits {
+				if err = encoder.Encode(hit); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		for _, hit := range hits {
+			fmt.Printf("%d  %s (%s)  %d %s filed %s  page %d [%s]\n    %s\n",
+				hit.DocId, hit.Member, hit.StateDst, hit.Year, hit.FilingType, hit.FilingDate,
+				hit.Page, hit.Method, hit.Snippet)
+		}
+		fmt.Printf("%d matching pages\n", len(hits))
+		return nil
+	}
+}
@@ -0,0 +1,55 @@
+package cmds
+
+import (
+	"context"
+	"github.com/paulschick/disclosureupdater/model"
+	"github.com/paulschick/disclosureupdater/registry"
+	"github.com/paulschick/disclosureupdater/search"
+	"testing"
+)
+
+func TestIndexPdfs(t *testing.T) {
+	ctx := context.Background()
+	store := memoryArtifactStore()
+	putTestPdf(t, store, "2023.ptr-pdfs.TX07.Roe.John.20012345.pdf",
+		[]string{"Periodic Transaction Report\nApple Inc. Common Stock purchased on 02/01/2023 by the spouse of the filer"})
+	// scanned filings only have a filing ID in the text layer
+	putTestPdf(t, store, "2023.ptr-pdfs.CA12.Doe.Jane.20012346.pdf", []string{"Filing ID #20012346"})
+	putOcrPage(t, store, "2023.ptr-pdfs.CA12.Doe.Jane.20012346-0.png",
+		ocrLine(200, map[int]string{150: "Microsoft Corporation sold in joint account"}))
+	putTestPdf(t, store, "2023.ptr-pdfs.NY07.Poe.Ann.20012347.pdf", []string{"Filing ID #20012347"})
+
+	members := map[int]*model.Member{
+		20012345: {Prefix: "Hon.", First: "John", Last: "Roe", StateDst: "TX07", Year: 2023, FilingType: "P",
+			FilingDate: "2/15/2023", DocId: 20012345},
+	}
+	reg := registry.NewRegistry(nil)
+	ix := search.NewIndex()
+	if err := indexPdfs(ctx, store, ix, members, reg, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query  string
+		docId  int
+		method string
+	}{
+		{"apple spouse", 20012345, model.ExtractionText},
+		{"\"joint account\"", 20012346, model.ExtractionOcr},
+	}
+	for _, test := range tests {
+		hits, err := ix.Search(search.Query{Text: test.query})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hits) != 1 || hits[0].DocId != test.docId || hits[0].Method != test.method {
+			t.Errorf("Search(%s) = %+v; want page 0 of %d from %s", test.query, hits, test.docId, test.method)
+		}
+	}
+	if !ix.Contains(20012347) {
+		t.Errorf("Contains(20012347) = false; want the filing without OCR output indexed without pages")
+	}
+	if got := ix.Filings[20012345].Member; got != "Hon. John Roe" {
+		t.Errorf("Member = %q; want the name of the disclosure index", got)
+	}
+}
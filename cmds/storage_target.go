@@ -0,0 +1,215 @@
+package cmds
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/gocarina/gocsv"
+	"github.com/paulschick/disclosureupdater/common/methods"
+	"github.com/paulschick/disclosureupdater/config"
+	"github.com/paulschick/disclosureupdater/export"
+	"github.com/paulschick/disclosureupdater/model"
+	"github.com/paulschick/disclosureupdater/ptr"
+	"github.com/paulschick/disclosureupdater/s3client"
+	"github.com/paulschick/disclosureupdater/storage"
+	"github.com/urfave/cli/v2"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Storage targets of the --target flag
+const (
+	TargetLocal = "local"
+	TargetS3    = "s3"
+)
+
+// writeExport writes the records of an export command to the target given with --target, and returns where
+// they were written. Local exports are written to --output, or name.<format> in the exports folder. S3 exports
+// are streamed to the key given with --output, or name.<format>, under the prefix of the exports class.
+func writeExport[T any](c *cli.Context, commonDirs *config.CommonDirs, name, format string, records []*T) (string, error) {
+	output := c.String("output")
+	switch target := c.String("target"); target {
+	case TargetLocal, "":
+		if output == "" {
+			if err := methods.TryCreateDirectories(commonDirs.ExportFolder); err != nil {
+				return "", err
+			}
+			output = filepath.Join(commonDirs.ExportFolder, name+export.Extension(format))
+		}
+		return output, export.Write(output, format, records)
+	case TargetS3:
+		if output == "" {
+			output = name + export.Extension(format)
+		}
+		service, class, err := s3ClassService(c, s3client.ClassExports)
+		if err != nil {
+			return "", err
+		}
+		backend := service.Backend(class)
+		opts := &storage.PutOptions{ContentType: class.ContentTypeOf(output), StorageClass: class.StorageClass}
+		err = storage.Write(context.TODO(), backend, output, opts, func(w io.Writer) error {
+			return export.Encode(w, format, records)
+		})
+		return backend.URL(output), err
+	default:
+		return "", fmt.Errorf("invalid target %q, expected %s or %s", target, TargetLocal, TargetS3)
+	}
+}
+
+// s3ClassService returns the S3 service of the --profile profile and the artifact class configured in it
+func s3ClassService(c *cli.Context, className string) (*s3client.S3ServiceV2, *s3client.ArtifactClass, error) {
+	service, err := newS3Service(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	class, err := s3client.ArtifactClassFromProfile(service.S3Profile, className)
+	if err != nil {
+		return nil, nil, err
+	}
+	return service, class, nil
+}
+
+// artifactStore holds the backends the pipeline commands read PDFs, page images and OCR output from and write
+// their outputs to. Keys are the paths in the local folders, PDFs are keyed by their file names on both targets.
+type artifactStore struct {
+	Disclosures storage.Backend
+	Searchable  storage.Backend
+	Images      storage.Backend
+	// Csv holds the OCR TSVs and the tab separated outputs of parse-ptr, link-filings and compliance-report
+	Csv storage.Backend
+	// Reports holds JSON reports, the data folder locally and the exports class on S3
+	Reports storage.Backend
+}
+
+func localArtifactStore(commonDirs *config.CommonDirs) *artifactStore {
+	return &artifactStore{
+		Disclosures: storage.NewLocal(commonDirs.DisclosuresFolder),
+		Searchable:  storage.NewLocal(commonDirs.SearchableFolder),
+		Images:      storage.NewLocal(commonDirs.ImageFolder),
+		Csv:         storage.NewLocal(commonDirs.CsvFolder),
+		Reports:     storage.NewLocal(commonDirs.DataFolder),
+	}
+}
+
+// artifactStoreFromCtx returns the artifact store of the target given with --target. The S3 store uses the
+// artifact classes of the --profile profile, and reads the disclosure index to find PDFs by their file names.
+func artifactStoreFromCtx(c *cli.Context, commonDirs *config.CommonDirs) (*artifactStore, error) {
+	switch target := c.String("target"); target {
+	case TargetLocal, "":
+		return localArtifactStore(commonDirs), nil
+	case TargetS3:
+		service, err := newS3Service(c)
+		if err != nil {
+			return nil, err
+		}
+		members, err := setIndexMembers(service, commonDirs)
+		if err != nil {
+			return nil, err
+		}
+		fileNames := make([]string, len(members))
+		for i, member := range members {
+			fileNames[i] = member.BuildPdfFileName()
+		}
+		classes := make(map[string]*s3client.ArtifactClass)
+		for _, name := range []string{s3client.ClassDisclosures, s3client.ClassSearchable, s3client.ClassImages,
+			s3client.ClassCsv, s3client.ClassExports} {
+			if classes[name], err = s3client.ArtifactClassFromProfile(service.S3Profile, name); err != nil {
+				return nil, err
+			}
+		}
+		return &artifactStore{
+			Disclosures: service.PdfBackend(classes[s3client.ClassDisclosures], fileNames),
+			Searchable:  service.PdfBackend(classes[s3client.ClassSearchable], fileNames),
+			Images:      service.Backend(classes[s3client.ClassImages]),
+			Csv:         service.Backend(classes[s3client.ClassCsv]),
+			Reports:     service.Backend(classes[s3client.ClassExports]),
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid target %q, expected %s or %s", target, TargetLocal, TargetS3)
+	}
+}
+
+// objectPath returns the file path or URL of an object, for the messages of the commands
+func objectPath(b storage.Backend, key string) string {
+	switch b := b.(type) {
+	case *storage.Local:
+		return filepath.Join(b.Root, filepath.FromSlash(key))
+	case *storage.S3:
+		return b.URL(key)
+	case *storage.Named:
+		return objectPath(b.Backend, b.Key(key))
+	default:
+		return key
+	}
+}
+
+// listPdfs returns the file names of the PDFs at the top of a backend in lexical order
+func listPdfs(ctx context.Context, b storage.Backend) ([]string, error) {
+	names := make([]string, 0)
+	err := b.List(ctx, "", func(info *storage.ObjectInfo) error {
+		if !strings.Contains(info.Key, "/") && path.Ext(info.Key) == ".pdf" {
+			names = append(names, info.Key)
+		}
+		return nil
+	})
+	return names, err
+}
+
+// readPdfTextPages returns the text layer of every page of a PDF in a backend
+func readPdfTextPages(ctx context.Context, b storage.Backend, pdfName string) ([]string, error) {
+	r, err := b.Get(ctx, pdfName)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+	return ptr.ReadTextPages(r)
+}
+
+// writeTsv writes the records to the key as tab separated values
+func writeTsv(ctx context.Context, b storage.Backend, key string, records interface{}) error {
+	gocsv.SetCSVWriter(func(out io.Writer) *gocsv.SafeCSVWriter {
+		writer := csv.NewWriter(out)
+		writer.Comma = '\t'
+		return gocsv.NewSafeCSVWriter(writer)
+	})
+	opts := &storage.PutOptions{ContentType: "text/tab-separated-values"}
+	return storage.Write(ctx, b, key, opts, func(w io.Writer) error {
+		return gocsv.Marshal(records, w)
+	})
+}
+
+// readTsv reads the tab separated records of the key into out, an empty file has no records
+func readTsv(ctx context.Context, b storage.Backend, key string, out interface{}) error {
+	r, err := b.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+	gocsv.SetCSVReader(func(in io.Reader) gocsv.CSVReader {
+		reader := csv.NewReader(in)
+		reader.Comma = '\t'
+		reader.LazyQuotes = true
+		return reader
+	})
+	err = gocsv.Unmarshal(r, out)
+	if errors.Is(err, gocsv.ErrEmptyCSVFile) {
+		return nil
+	}
+	return err
+}
+
+// readOcrResults reads the OCR TSV of a page image written by ocr-images
+func readOcrResults(ctx context.Context, b storage.Backend, key string) ([]*model.OcrResult, error) {
+	results := make([]*model.OcrResult, 0)
+	if err := readTsv(ctx, b, key, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,115 @@
+package cmds
+
+import (
+	"bytes"
+	"context"
+	"github.com/paulschick/disclosureupdater/model"
+	"github.com/paulschick/disclosureupdater/pdfwriter"
+	"github.com/paulschick/disclosureupdater/storage"
+	"image"
+	"image/png"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// memoryArtifactStore returns an artifact store that keeps every artifact in memory
+func memoryArtifactStore() *artifactStore {
+	return &artifactStore{
+		Disclosures: storage.NewMemory(),
+		Searchable:  storage.NewMemory(),
+		Images:      storage.NewMemory(),
+		Csv:         storage.NewMemory(),
+		Reports:     storage.NewMemory(),
+	}
+}
+
+// putTestPdf writes a letter size PDF to the disclosures backend, one text line per line of a page, and returns
+// the word boxes of every page as ocr-images writes them for 150 dpi page images
+func putTestPdf(t *testing.T, store *artifactStore, pdfName string, pages []string) [][]*model.OcrResult {
+	t.Helper()
+	const charWidth, wordHeight, lineHeight, margin = 8, 16, 24, 60
+	img := blankPageImage()
+	doc := pdfwriter.NewDocument()
+	pageWords := make([][]*model.OcrResult, len(pages))
+	for i, text := range pages {
+		words := make([]*model.OcrResult, 0)
+		for n, line := range strings.Split(strings.Trim(text, "\n"), "\n") {
+			top := margin + n*lineHeight
+			left := margin
+			for _, word := range strings.Fields(line) {
+				right := left + len([]rune(word))*charWidth
+				words = append(words, &model.OcrResult{Word: word, Left: left, Top: top, Right: right,
+					Bottom: top + wordHeight, Confidence: 96})
+				left = right + charWidth
+			}
+		}
+		doc.AddPage(612, 792, img, words)
+		pageWords[i] = words
+	}
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Disclosures.Put(context.Background(), pdfName, &buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	return pageWords
+}
+
+// putOcrPage writes the page image and OCR TSV of a page as convert-pdfs and ocr-images do
+func putOcrPage(t *testing.T, store *artifactStore, imageName string, words []*model.OcrResult) {
+	t.Helper()
+	ctx := context.Background()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, blankPageImage()); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Images.Put(ctx, imageName, &buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeTsv(ctx, store.Csv, csvPathFromImagePath(imageName), &words); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func blankPageImage() *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, 1275, 1650))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	return img
+}
+
+func TestListPdfs(t *testing.T) {
+	ctx := context.Background()
+	b := storage.NewMemory()
+	for _, key := range []string{"b.pdf", "a.pdf", "a.pdf.sha256", "searchable/c.pdf", "notes.txt"} {
+		if err := b.Put(ctx, key, strings.NewReader("pdf"), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	names, err := listPdfs(ctx, b)
+	if want := []string{"a.pdf", "b.pdf"}; err != nil || !reflect.DeepEqual(names, want) {
+		t.Errorf("listPdfs() = %v, %v; want %v", names, err, want)
+	}
+}
+
+func TestObjectPath(t *testing.T) {
+	local := storage.NewLocal("/data/csv")
+	named := storage.NewNamed(local, strings.ToUpper, nil)
+	tests := []struct {
+		b    storage.Backend
+		key  string
+		want string
+	}{
+		{local, "transactions.csv", "/data/csv/transactions.csv"},
+		{named, "a.pdf", "/data/csv/A.PDF"},
+		{storage.NewMemory(), "transactions.csv", "transactions.csv"},
+	}
+	for _, test := range tests {
+		if got := objectPath(test.b, test.key); got != test.want {
+			t.Errorf("objectPath(%q) = %q; want %q", test.key, got, test.want)
+		}
+	}
+}
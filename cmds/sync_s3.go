@@ -0,0 +1,82 @@
+package cmds
+
+import (
+	"errors"
+	"fmt"
+	"github.com/paulschick/disclosureupdater/config"
+	"github.com/paulschick/disclosureupdater/model"
+	"github.com/paulschick/disclosureupdater/s3client"
+	"github.com/urfave/cli/v2"
+	"strings"
+)
+
+// SyncS3 syncs the data folders with the bucket. Push uploads new and changed files, pull downloads new and
+// changed objects, both does either for each file depending on which side was modified last.
+func SyncS3(commonDirs *config.CommonDirs) model.CliFunc {
+	return func(cCtx *cli.Context) error {
+		opts := s3client.SyncOptions{
+			Mode:   strings.ToLower(cCtx.String("mode")),
+			DryRun: cCtx.Bool("dry-run"),
+			Delete: cCtx.Bool("delete"),
+		}
+		folderNames := cCtx.StringSlice("folder")
+		if len(folderNames) == 0 {
+			folderNames = []string{s3client.ClassDisclosures}
+		}
+
+		service, err := newS3Service(cCtx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Operating on %s Bucket\n", service.S3Profile.GetBucket())
+
+		var errStr string
+		for _, name := range folderNames {
+			folder, err := syncFolder(commonDirs, service, name)
+			if err != nil {
+				return err
+			}
+			actions, err := service.Sync(folder, opts)
+			for _, action := range actions {
+				fmt.Printf("%s %s (%s)\n", action.Action, action.Key, action.Reason)
+			}
+			if err != nil {
+				fmt.Printf("Error syncing %s: %s\n", name, err)
+				errStr = errStr + " " + err.Error()
+				continue
+			}
+			if opts.DryRun {
+				fmt.Printf("Dry run, %d changes to %s not applied\n", len(actions), name)
+			} else {
+				fmt.Printf("Synced %s, %d changes\n", name, len(actions))
+			}
+		}
+		if errStr != "" {
+			return errors.New(errStr)
+		}
+		return nil
+	}
+}
+
+// syncFolder returns the sync folder of an artifact class. The keys of disclosure PDFs are mapped back to
+// file names with the disclosure index, so PDFs can only be pulled after running update-urls. The service is
+// given the index entries for the metadata of uploaded PDFs.
+func syncFolder(commonDirs *config.CommonDirs, service *s3client.S3ServiceV2, name string) (*s3client.SyncFolder, error) {
+	class, err := s3client.ArtifactClassFromProfile(service.S3Profile, name)
+	if err != nil {
+		return nil, err
+	}
+	if !class.IsPdf() {
+		return &s3client.SyncFolder{Dir: class.Dir(commonDirs), Class: class}, nil
+	}
+	members, err := setIndexMembers(service, commonDirs)
+	if err != nil {
+		return nil, err
+	}
+	fileNames := make([]string, len(members))
+	for i, member := range members {
+		fileNames[i] = member.BuildPdfFileName()
+	}
+	scheme := s3client.KeySchemeFromProfile(service.S3Profile)
+	return s3client.NewPdfSyncFolder(class.Dir(commonDirs), class, scheme, fileNames), nil
+}
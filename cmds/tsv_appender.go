@@ -0,0 +1,77 @@
+package cmds
+
+import (
+	"encoding/csv"
+	"errors"
+	"github.com/gocarina/gocsv"
+	"io"
+	"os"
+	"sync"
+)
+
+// tsvAppender collects records from concurrent workers and appends them
+// to a tab separated file once the run has finished
+type tsvAppender[T any] struct {
+	path    string
+	mu      sync.Mutex
+	records []*T
+}
+
+func newTsvAppender[T any](path string) *tsvAppender[T] {
+	return &tsvAppender[T]{
+		path:    path,
+		records: make([]*T, 0),
+	}
+}
+
+func (a *tsvAppender[T]) Add(record *T) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.records = append(a.records, record)
+}
+
+// Records returns a copy of the records collected so far
+func (a *tsvAppender[T]) Records() []*T {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	records := make([]*T, len(a.records))
+	copy(records, a.records)
+	return records
+}
+
+// Write appends the collected records to the file, creating it if necessary
+func (a *tsvAppender[T]) Write() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	gocsv.SetCSVReader(func(in io.Reader) gocsv.CSVReader {
+		reader := csv.NewReader(in)
+		reader.Comma = '\t'
+		return reader
+	})
+	gocsv.SetCSVWriter(func(out io.Writer) *gocsv.SafeCSVWriter {
+		writer := csv.NewWriter(out)
+		writer.Comma = '\t'
+		return gocsv.NewSafeCSVWriter(writer)
+	})
+	all := make([]*T, 0)
+	if existing, err := os.Open(a.path); err == nil {
+		err = gocsv.UnmarshalFile(existing, &all)
+		_ = existing.Close()
+		if err != nil && !errors.Is(err, gocsv.ErrEmptyCSVFile) {
+			return err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	all = append(all, a.records...)
+	f, err := os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	err = gocsv.MarshalFile(&all, f)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,81 @@
+package cmds
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/gocarina/gocsv"
+	"github.com/paulschick/disclosureupdater/config"
+	"github.com/paulschick/disclosureupdater/model"
+	"github.com/paulschick/disclosureupdater/registry"
+	"github.com/urfave/cli/v2"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// MembersFileName is the file update-members writes the member registry to
+	MembersFileName = "members.csv"
+	// MemberOverridesFileName is the manual override file of the member registry
+	MemberOverridesFileName = "member_overrides.csv"
+)
+
+// UpdateMembers assigns a stable member ID to every filing of the disclosure index and writes the registry
+// to csv/members.csv
+func UpdateMembers(commonDirs *config.CommonDirs) model.CliFunc {
+	return func(c *cli.Context) error {
+		members, err := loadIndexMembers(commonDirs)
+		if err != nil {
+			fmt.Printf("Error reading disclosure index: %s\n", err)
+			return err
+		}
+		reg, err := loadRegistry(commonDirs, members)
+		if err != nil {
+			return err
+		}
+		registered := reg.Members()
+		fmt.Printf("Registered %d members from %d filings\n", len(registered), len(members))
+
+		gocsv.SetCSVWriter(func(out io.Writer) *gocsv.SafeCSVWriter {
+			writer := csv.NewWriter(out)
+			writer.Comma = '\t'
+			return gocsv.NewSafeCSVWriter(writer)
+		})
+		path := filepath.Join(commonDirs.CsvFolder, MembersFileName)
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+		err = gocsv.MarshalFile(&registered, f)
+		if err != nil {
+			_ = f.Close()
+			return err
+		}
+		fmt.Printf("Wrote %s\n", path)
+		return f.Close()
+	}
+}
+
+// loadRegistry registers the index members, applying csv/member_overrides.csv if it exists. The registry is
+// seeded with csv/members.csv of the last update-members, so members keep their IDs.
+func loadRegistry(commonDirs *config.CommonDirs, members []*model.Member) (*registry.Registry, error) {
+	overrides, err := registry.LoadOverrides(filepath.Join(commonDirs.CsvFolder, MemberOverridesFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		overrides = nil
+	} else if err != nil {
+		fmt.Printf("Error reading member overrides: %s\n", err)
+		return nil, err
+	}
+	registered, err := registry.LoadMembers(filepath.Join(commonDirs.CsvFolder, MembersFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		registered = nil
+	} else if err != nil {
+		fmt.Printf("Error reading members: %s\n", err)
+		return nil, err
+	}
+	reg := registry.NewRegistry(overrides)
+	reg.Seed(registered)
+	reg.Add(members)
+	return reg, nil
+}
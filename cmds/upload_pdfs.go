@@ -1,22 +1,50 @@
 package cmds
 
 import (
+	"errors"
 	"fmt"
 	"github.com/paulschick/disclosureupdater/config"
 	"github.com/paulschick/disclosureupdater/model"
 	"github.com/paulschick/disclosureupdater/s3client"
 	"github.com/urfave/cli/v2"
+	"slices"
 )
 
-func updateBucketItemIndex(commonDirs *config.CommonDirs) (*s3client.S3ServiceV2, error) {
-	var err error
-	s3Profile := config.S3ProfileFromConfig("default")
-	var service *s3client.S3ServiceV2
-	service, err = s3client.NewS3ServiceV2(s3Profile)
+// newS3Service returns the S3 service of the configuration profile given with --profile
+func newS3Service(cCtx *cli.Context) (*s3client.S3ServiceV2, error) {
+	profile := config.ProfileFromCtx(cCtx)
+	s3Profile, err := config.S3ProfileFromConfig(profile)
+	if err != nil {
+		fmt.Printf("Error reading S3 configuration of profile %s: %s\n", profile, err)
+		return nil, err
+	}
+	service, err := s3client.NewS3ServiceV2(s3Profile)
 	if err != nil {
 		fmt.Printf("Error creating S3ServiceV2 instance: %s\n", err)
 		return nil, err
 	}
+	return service, nil
+}
+
+// setIndexMembers gives the service the disclosure index entries, for the metadata and tags of uploaded PDFs
+func setIndexMembers(service *s3client.S3ServiceV2, commonDirs *config.CommonDirs) ([]*model.Member, error) {
+	members, err := loadIndexMembers(commonDirs)
+	if err != nil {
+		fmt.Printf("Error reading disclosure index: %s\n", err)
+		return nil, err
+	}
+	service.Members = make(map[int]*model.Member, len(members))
+	for _, member := range members {
+		service.Members[member.DocId] = member
+	}
+	return members, nil
+}
+
+func updateBucketItemIndex(cCtx *cli.Context, commonDirs *config.CommonDirs) (*s3client.S3ServiceV2, error) {
+	service, err := newS3Service(cCtx)
+	if err != nil {
+		return nil, err
+	}
 	fmt.Printf("Writing current bucket objects\n")
 	err = service.WriteBucketObjects(commonDirs)
 	if err != nil {
@@ -29,7 +57,7 @@ func updateBucketItemIndex(commonDirs *config.CommonDirs) (*s3client.S3ServiceV2
 
 func UpdateBucketItemIndex(commonDirs *config.CommonDirs) model.CliFunc {
 	return func(cCtx *cli.Context) error {
-		_, err := updateBucketItemIndex(commonDirs)
+		_, err := updateBucketItemIndex(cCtx, commonDirs)
 		return err
 	}
 }
@@ -41,23 +69,52 @@ func UploadPdfs(commonDirs *config.CommonDirs) model.CliFunc {
 		var service *s3client.S3ServiceV2
 		if shouldUpdateIndex {
 			fmt.Printf("Updating bucket item index\n")
-			service, err = updateBucketItemIndex(commonDirs)
+			service, err = updateBucketItemIndex(cCtx, commonDirs)
 			if err != nil {
 				fmt.Printf("Error updating bucket item index: %s\n", err)
 				return err
 			}
 		} else {
 			fmt.Printf("Not updating bucket item index\n")
-			s3Profile := config.S3ProfileFromConfig("default")
-			service, err = s3client.NewS3ServiceV2(s3Profile)
+			service, err = newS3Service(cCtx)
 			if err != nil {
-				fmt.Printf("Error creating S3ServiceV2 instance: %s\n", err)
 				return err
 			}
 		}
 		fmt.Printf("Operating on %s Bucket\n", service.S3Profile.GetBucket())
 
-		err = service.UploadPdfsS3(commonDirs)
-		return err
+		classNames := cCtx.StringSlice("class")
+		if cCtx.Bool("searchable") {
+			classNames = append(classNames, s3client.ClassSearchable)
+		}
+		if slices.Contains(classNames, "all") {
+			classNames = s3client.ArtifactClassNames
+		}
+		if len(classNames) == 0 || slices.Contains(classNames, s3client.ClassDisclosures) ||
+			slices.Contains(classNames, s3client.ClassSearchable) {
+			if _, err = setIndexMembers(service, commonDirs); err != nil {
+				return err
+			}
+		}
+		if len(classNames) == 0 {
+			err = service.UploadPdfsS3(commonDirs)
+			return err
+		}
+		var errStr string
+		for _, name := range classNames {
+			class, err := s3client.ArtifactClassFromProfile(service.S3Profile, name)
+			if err != nil {
+				fmt.Printf("Error reading artifact class: %s\n", err)
+				return err
+			}
+			fmt.Printf("Uploading %s to %s/\n", name, class.Prefix)
+			if err = service.UploadClassS3(commonDirs, class); err != nil {
+				errStr = errStr + " " + err.Error()
+			}
+		}
+		if errStr != "" {
+			return errors.New(errStr)
+		}
+		return nil
 	}
 }
@@ -0,0 +1,93 @@
+package cmds
+
+import (
+	"errors"
+	"fmt"
+	"github.com/paulschick/disclosureupdater/config"
+	"github.com/paulschick/disclosureupdater/model"
+	"github.com/paulschick/disclosureupdater/s3client"
+	"github.com/urfave/cli/v2"
+	"os"
+	"slices"
+)
+
+// VerifyS3 compares the local files of each artifact class with their objects, and reports missing, corrupt,
+// orphaned and unverified objects and objects that couldn't be compared.
+// With --reupload the missing and corrupt files are uploaded again.
+func VerifyS3(commonDirs *config.CommonDirs) model.CliFunc {
+	return func(cCtx *cli.Context) error {
+		reupload := cCtx.Bool("reupload")
+		classNames := cCtx.StringSlice("class")
+		if len(classNames) == 0 || slices.Contains(classNames, "all") {
+			classNames = s3client.ArtifactClassNames
+		}
+
+		service, err := newS3Service(cCtx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Operating on %s Bucket\n", service.S3Profile.GetBucket())
+
+		// re-uploaded files are added to the bucket index if there is one
+		index, err := s3client.LoadBucketIndex(commonDirs)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			fmt.Printf("Error reading bucket index: %s\n", err)
+			return err
+		}
+
+		counts := make(map[string]int)
+		reuploaded := 0
+		var errStr string
+		for _, name := range classNames {
+			folder, err := syncFolder(commonDirs, service, name)
+			if err != nil {
+				return err
+			}
+			results, err := service.Verify(folder)
+			if err != nil {
+				fmt.Printf("Error verifying %s: %s\n", name, err)
+				errStr = errStr + " " + err.Error()
+			}
+			for _, result := range results {
+				counts[result.Status]++
+				if result.Status == s3client.VerifyOk {
+					continue
+				}
+				fmt.Printf("%s %s %s\n", result.Status, result.Key, result.Reason)
+				if !reupload || (result.Status != s3client.VerifyMissing && result.Status != s3client.VerifyCorrupt) {
+					continue
+				}
+				obj, err := service.Reupload(folder, result)
+				if err != nil {
+					fmt.Printf("Error uploading %s: %s\n", result.Key, err)
+					errStr = errStr + " " + err.Error()
+					continue
+				}
+				reuploaded++
+				if index != nil {
+					index[obj.Key] = obj
+				}
+			}
+		}
+		fmt.Printf("%d ok, %d missing, %d corrupt, %d orphaned, %d unverified, %d errors\n",
+			counts[s3client.VerifyOk], counts[s3client.VerifyMissing], counts[s3client.VerifyCorrupt],
+			counts[s3client.VerifyOrphaned], counts[s3client.VerifyUnverified], counts[s3client.VerifyError])
+		if reupload {
+			fmt.Printf("Uploaded %d files again\n", reuploaded)
+			if index != nil && reuploaded > 0 {
+				if err = s3client.SaveBucketIndex(commonDirs, index); err != nil {
+					fmt.Printf("Error saving bucket index: %s\n", err)
+					errStr = errStr + " " + err.Error()
+				}
+			}
+		}
+		if errStr != "" {
+			return errors.New(errStr)
+		}
+		if !reupload && counts[s3client.VerifyMissing]+counts[s3client.VerifyCorrupt] > 0 {
+			return fmt.Errorf("%d missing and %d corrupt objects, run verify-s3 --reupload to upload them again",
+				counts[s3client.VerifyMissing], counts[s3client.VerifyCorrupt])
+		}
+		return nil
+	}
+}
@@ -12,8 +12,14 @@ const (
 	DefaultOcrFolder         = "ocr"
 	DefaultCsvFolder         = "csv"
 	DefaultS3Folder          = "s3"
+	DefaultSearchableFolder  = "searchable"
+	DefaultExportFolder      = "exports"
 	DefaultProfile           = "default"
 	MaxJobs                  = 25
 	CpuUtilization           = 0.7
 	BatchSize                = 100
+	OcrFormatTsv             = "tsv"
+	OcrFormatHocr            = "hocr"
+	OcrFormatAlto            = "alto"
+	MaxOcrAttempts           = 3
 )
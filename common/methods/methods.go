@@ -1,9 +1,12 @@
 package methods
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -21,3 +24,20 @@ func CurrentYear() int {
 func GetCurrentYearString() string {
 	return fmt.Sprintf("%d", CurrentYear())
 }
+
+// SniffDelimiter returns the field separator of a delimited file from its header line:
+// a tab or pipe if the header contains one, otherwise a comma. The reader is not advanced.
+func SniffDelimiter(reader *bufio.Reader) (rune, error) {
+	header, err := reader.Peek(512)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return 0, err
+	}
+	line, _, _ := strings.Cut(string(header), "\n")
+	if strings.Contains(line, "\t") {
+		return '\t', nil
+	}
+	if strings.Contains(line, "|") {
+		return '|', nil
+	}
+	return ',', nil
+}
@@ -0,0 +1,139 @@
+package compliance
+
+import (
+	"fmt"
+	"github.com/paulschick/disclosureupdater/model"
+	"github.com/paulschick/disclosureupdater/registry"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// STOCK Act deadlines: a transaction must be reported within 30 days of the filer being notified of it,
+// and no later than 45 days after the transaction
+const (
+	DefaultTransactionDays = 45
+	DefaultNoticeDays      = 30
+)
+
+// Rules are the number of days after the transaction and after the notification a transaction must be reported in
+type Rules struct {
+	TransactionDays int
+	NoticeDays      int
+}
+
+func DefaultRules() Rules {
+	return Rules{
+		TransactionDays: DefaultTransactionDays,
+		NoticeDays:      DefaultNoticeDays,
+	}
+}
+
+// Check returns the filing delay of the transaction, which was reported in the member's filing.
+// A missing notification date is treated as notification on the transaction date.
+func (r Rules) Check(t *model.Transaction, member *model.Member) (*model.ComplianceFinding, error) {
+	filed, err := model.ParseDate(member.FilingDate)
+	if err != nil {
+		return nil, fmt.Errorf("filing date of %d: %w", member.DocId, err)
+	}
+	traded, err := model.ParseDate(t.Date)
+	if err != nil {
+		return nil, fmt.Errorf("transaction date in %d: %w", t.DocId, err)
+	}
+	notified := traded
+	if strings.TrimSpace(t.NotificationDate) != "" {
+		notified, err = model.ParseDate(t.NotificationDate)
+		if err != nil {
+			return nil, fmt.Errorf("notification date in %d: %w", t.DocId, err)
+		}
+	}
+	finding := &model.ComplianceFinding{
+		DocId:                t.DocId,
+		Member:               member.FullName(),
+		StateDst:             member.StateDst,
+		Year:                 member.Year,
+		Asset:                t.Asset,
+		Ticker:               t.Ticker,
+		TransactionType:      t.TransactionType,
+		AmountBracket:        t.AmountBracket,
+		TransactionDate:      t.Date,
+		NotificationDate:     t.NotificationDate,
+		FilingDate:           member.FilingDate,
+		DaysAfterTransaction: daysBetween(traded, filed),
+		DaysAfterNotice:      daysBetween(notified, filed),
+	}
+	finding.LateTransaction = finding.DaysAfterTransaction > r.TransactionDays
+	finding.LateNotice = finding.DaysAfterNotice > r.NoticeDays
+	return finding, nil
+}
+
+// Report checks every transaction and groups the late ones by registered member and year.
+// Transactions whose filing isn't in members, or whose dates can't be parsed, are returned as skipped.
+func (r Rules) Report(transactions []*model.Transaction, members map[int]*model.Member,
+	reg *registry.Registry) ([]*model.ComplianceGroup, []error) {
+	groups := make(map[string]*model.ComplianceGroup)
+	skipped := make([]error, 0)
+	for _, t := range transactions {
+		member, ok := members[t.DocId]
+		if !ok {
+			skipped = append(skipped, fmt.Errorf("no filing for %d in the disclosure index", t.DocId))
+			continue
+		}
+		finding, err := r.Check(t, member)
+		if err != nil {
+			skipped = append(skipped, err)
+			continue
+		}
+		finding.MemberId = reg.MemberId(t.DocId)
+		if registered := reg.Member(t.DocId); registered != nil {
+			finding.Member = registered.Name
+		}
+		key := fmt.Sprintf("%s|%d", finding.MemberId, finding.Year)
+		group, ok := groups[key]
+		if !ok {
+			group = &model.ComplianceGroup{
+				MemberId: finding.MemberId,
+				Member:   finding.Member,
+				StateDst: finding.StateDst,
+				Year:     finding.Year,
+				Findings: make([]*model.ComplianceFinding, 0),
+			}
+			groups[key] = group
+		}
+		group.Transactions++
+		if finding.DaysAfterTransaction > group.MaxDaysAfterTransaction {
+			group.MaxDaysAfterTransaction = finding.DaysAfterTransaction
+		}
+		if !finding.Late() {
+			continue
+		}
+		group.Late++
+		if finding.LateTransaction {
+			group.LateTransactions++
+		}
+		if finding.LateNotice {
+			group.LateNotices++
+		}
+		group.Findings = append(group.Findings, finding)
+	}
+
+	report := make([]*model.ComplianceGroup, 0, len(groups))
+	for _, group := range groups {
+		report = append(report, group)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Member != report[j].Member {
+			return report[i].Member < report[j].Member
+		}
+		if report[i].Year != report[j].Year {
+			return report[i].Year < report[j].Year
+		}
+		return report[i].MemberId < report[j].MemberId
+	})
+	return report, skipped
+}
+
+func daysBetween(from, to time.Time) int {
+	return int(math.Round(to.Sub(from).Hours() / 24))
+}
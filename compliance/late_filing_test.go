@@ -0,0 +1,70 @@
+package compliance
+
+import (
+	"github.com/paulschick/disclosureupdater/model"
+	"github.com/paulschick/disclosureupdater/registry"
+	"testing"
+)
+
+func TestRules_Check(t *testing.T) {
+	member := &model.Member{Prefix: "Hon.", First: "Jane", Last: "Doe", StateDst: "CA12", Year: 2023, FilingDate: "3/1/2023", DocId: 1}
+	tests := []struct {
+		date             string
+		notificationDate string
+		lateTransaction  bool
+		lateNotice       bool
+		days             int
+	}{
+		{"02/10/2023", "02/10/2023", false, false, 19},
+		{"01/01/2023", "02/20/2023", true, false, 59},
+		{"01/20/2023", "01/20/2023", false, true, 40},
+		{"01/15/2023", "02/01/2023", false, false, 45},
+		{"1/1/23", "", true, true, 59},
+	}
+	for _, test := range tests {
+		transaction := &model.Transaction{DocId: 1, Date: test.date, NotificationDate: test.notificationDate}
+		finding, err := DefaultRules().Check(transaction, member)
+		if err != nil {
+			t.Fatalf("Check(%s) error = %v", test.date, err)
+		}
+		if finding.LateTransaction != test.lateTransaction || finding.LateNotice != test.lateNotice ||
+			finding.DaysAfterTransaction != test.days {
+			t.Errorf("Check(%s, %s) = %+v", test.date, test.notificationDate, *finding)
+		}
+		if finding.Member != "Hon. Jane Doe" {
+			t.Errorf("Check() member = %q", finding.Member)
+		}
+	}
+}
+
+func TestRules_Report(t *testing.T) {
+	members := map[int]*model.Member{
+		1: {First: "Jane", Last: "Doe", StateDst: "CA12", Year: 2023, FilingDate: "3/1/2023", DocId: 1},
+		2: {First: "Jane", Last: "Doe", StateDst: "CA12", Year: 2023, FilingDate: "6/1/2023", DocId: 2},
+		3: {First: "John", Last: "Roe", StateDst: "TX07", Year: 2022, FilingDate: "6/1/2022", DocId: 3},
+	}
+	transactions := []*model.Transaction{
+		{DocId: 1, Date: "02/20/2023", NotificationDate: "02/20/2023"},
+		{DocId: 2, Date: "01/02/2023", NotificationDate: "01/02/2023"},
+		{DocId: 3, Date: "05/30/2022", NotificationDate: "05/30/2022"},
+		{DocId: 4, Date: "05/30/2022"},
+		{DocId: 3, Date: "unreadable"},
+	}
+	reg := registry.NewRegistry(nil)
+	reg.Add([]*model.Member{members[1], members[2], members[3]})
+	report, skipped := DefaultRules().Report(transactions, members, reg)
+	if len(skipped) != 2 {
+		t.Errorf("Report() skipped %v; want 2", skipped)
+	}
+	if len(report) != 2 {
+		t.Fatalf("Report() returned %d groups; want 2", len(report))
+	}
+	doe := report[0]
+	if doe.MemberId != "doe-jane-ca" || doe.Member != "Jane Doe" || doe.Transactions != 2 || doe.Late != 1 || len(doe.Findings) != 1 ||
+		doe.Findings[0].DocId != 2 || doe.MaxDaysAfterTransaction != 150 {
+		t.Errorf("Report()[0] = %+v", *doe)
+	}
+	if roe := report[1]; roe.Member != "John Roe" || roe.Late != 0 {
+		t.Errorf("Report()[1] = %+v", *roe)
+	}
+}
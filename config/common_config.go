@@ -1,12 +1,14 @@
 package config
 
 import (
+	"fmt"
 	"github.com/paulschick/disclosureupdater/common/constants"
 	"github.com/paulschick/disclosureupdater/common/methods"
 	"github.com/paulschick/disclosureupdater/model"
 	"github.com/spf13/viper"
 	"github.com/urfave/cli/v2"
 	"path"
+	"strings"
 )
 
 type CommonDirs struct {
@@ -17,6 +19,8 @@ type CommonDirs struct {
 	ImageFolder       string
 	OcrFolder         string
 	CsvFolder         string
+	SearchableFolder  string
+	ExportFolder      string
 }
 
 func (c *CommonDirs) ToString() string {
@@ -28,6 +32,8 @@ func (c *CommonDirs) ToString() string {
 		"\tImageFolder: " + c.ImageFolder + ",\n" +
 		"\tOcrFolder: " + c.OcrFolder + ",\n" +
 		"\tCsvFolder: " + c.CsvFolder + ",\n" +
+		"\tSearchableFolder: " + c.SearchableFolder + ",\n" +
+		"\tExportFolder: " + c.ExportFolder + ",\n" +
 		"}"
 }
 
@@ -49,6 +55,14 @@ func NewCommonDirsFromCtx(c *cli.Context) *CommonDirs {
 	if csvFolder == "" {
 		csvFolder = path.Join(dataFolder, constants.DefaultCsvFolder)
 	}
+	searchableFolder := c.String("searchable")
+	if searchableFolder == "" {
+		searchableFolder = path.Join(dataFolder, constants.DefaultSearchableFolder)
+	}
+	exportFolder := c.String("exports")
+	if exportFolder == "" {
+		exportFolder = path.Join(dataFolder, constants.DefaultExportFolder)
+	}
 	return &CommonDirs{
 		BaseFolder:        GetBaseFolder(),
 		DataFolder:        dataFolder,
@@ -57,6 +71,8 @@ func NewCommonDirsFromCtx(c *cli.Context) *CommonDirs {
 		ImageFolder:       imagesFolder,
 		OcrFolder:         ocrFolder,
 		CsvFolder:         csvFolder,
+		SearchableFolder:  searchableFolder,
+		ExportFolder:      exportFolder,
 	}
 }
 
@@ -72,6 +88,8 @@ func NewCommonDirs(baseFolder string) *CommonDirs {
 		ImageFolder:       path.Join(dataFolder, "images"),
 		OcrFolder:         path.Join(dataFolder, "ocr"),
 		CsvFolder:         path.Join(dataFolder, "csv"),
+		SearchableFolder:  path.Join(dataFolder, "searchable"),
+		ExportFolder:      path.Join(dataFolder, "exports"),
 	}
 }
 
@@ -82,6 +100,8 @@ func (c *CommonDirs) CreateDirectories() error {
 		c.ImageFolder,
 		c.OcrFolder,
 		c.CsvFolder,
+		c.SearchableFolder,
+		c.ExportFolder,
 	}
 	for _, dir := range dirs {
 		if err := methods.TryCreateDirectories(dir); err != nil {
@@ -91,18 +111,57 @@ func (c *CommonDirs) CreateDirectories() error {
 	return nil
 }
 
-func S3ProfileFromConfig(profile string) model.S3Profile {
+// ProfileFromCtx returns the configuration profile given with --profile, the default profile if it isn't set
+func ProfileFromCtx(c *cli.Context) string {
+	if profile := c.String("profile"); profile != "" {
+		return profile
+	}
+	return constants.DefaultProfile
+}
+
+// S3ProfileFromConfig returns the S3 settings of the profile in the configuration file
+func S3ProfileFromConfig(profile string) (model.S3Profile, error) {
 	v, err := InitializeViper()
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	return S3ProfileFromViper(v, profile)
+}
+
+// S3ProfileFromViper returns the S3 settings of the profile, an error if the profile has no S3 settings
+func S3ProfileFromViper(v *viper.Viper, profile string) (model.S3Profile, error) {
+	if !v.IsSet(profile + ".s3") {
+		return nil, fmt.Errorf("profile %q has no S3 configuration, run configure --profile %s", profile, profile)
 	}
 	s3Bucket := v.GetString(profile + ".s3.s3bucket")
 	s3Region := v.GetString(profile + ".s3.s3region")
 	s3Hostname := v.GetString(profile + ".s3.s3hostname")
 	s3Default := model.S3DefaultProfile{
-		S3Bucket:   s3Bucket,
-		S3Region:   s3Region,
-		S3Hostname: s3Hostname,
+		S3Bucket:      s3Bucket,
+		S3Region:      s3Region,
+		S3Hostname:    s3Hostname,
+		S3KeyPrefix:   v.GetString(profile + ".s3.s3KeyPrefix"),
+		S3KeyTemplate: v.GetString(profile + ".s3.s3KeyTemplate"),
+		S3Upload: model.S3UploadConfig{
+			PartSize:    v.GetInt64(profile + ".s3.upload.partSize"),
+			Concurrency: v.GetInt(profile + ".s3.upload.concurrency"),
+			Checksum:    v.GetString(profile + ".s3.upload.checksum"),
+			MaxAttempts: v.GetInt(profile + ".s3.upload.maxAttempts"),
+			Encryption:  v.GetString(profile + ".s3.upload.encryption"),
+			KmsKeyId:    v.GetString(profile + ".s3.upload.kmsKeyId"),
+			Tags:        v.GetStringMapString(profile + ".s3.upload.tags"),
+		},
+		AwsProfile: v.GetString(profile + ".s3.awsProfile"),
+		AssumeRole: model.S3AssumeRoleConfig{
+			RoleArn:     v.GetString(profile + ".s3.assumeRole.roleArn"),
+			ExternalId:  v.GetString(profile + ".s3.assumeRole.externalId"),
+			SessionName: v.GetString(profile + ".s3.assumeRole.sessionName"),
+		},
+		UsePathStyle: v.GetBool(profile + ".s3.usePathStyle"),
+	}
+	// class names are lower case, viper keys are case-insensitive
+	if err := v.UnmarshalKey(profile+".s3.classes", &s3Default.S3Classes); err != nil {
+		return nil, err
 	}
 	s3ApiKey := v.GetString(profile + ".s3.s3ApiKey")
 	s3SecretKey := v.GetString(profile + ".s3.s3SecretKey")
@@ -111,9 +170,9 @@ func S3ProfileFromConfig(profile string) model.S3Profile {
 			S3DefaultProfile: s3Default,
 			S3ApiKey:         s3ApiKey,
 			S3SecretKey:      s3SecretKey,
-		}
+		}, nil
 	} else {
-		return &s3Default
+		return &s3Default, nil
 	}
 }
 
@@ -122,9 +181,27 @@ func S3ProfileFromCtx(c *cli.Context) model.S3Profile {
 	s3Region := c.String("s3-region")
 	s3Hostname := c.String("s3-hostname")
 	s3Default := model.S3DefaultProfile{
-		S3Bucket:   s3Bucket,
-		S3Region:   s3Region,
-		S3Hostname: s3Hostname,
+		S3Bucket:      s3Bucket,
+		S3Region:      s3Region,
+		S3Hostname:    s3Hostname,
+		S3KeyPrefix:   c.String("s3-key-prefix"),
+		S3KeyTemplate: c.String("s3-key-template"),
+		S3Upload: model.S3UploadConfig{
+			PartSize:    c.Int64("s3-part-size"),
+			Concurrency: c.Int("s3-upload-concurrency"),
+			Checksum:    c.String("s3-checksum"),
+			MaxAttempts: c.Int("s3-max-attempts"),
+			Encryption:  c.String("s3-encryption"),
+			KmsKeyId:    c.String("s3-kms-key-id"),
+			Tags:        tagsFromCtx(c.StringSlice("s3-tag")),
+		},
+		AwsProfile: c.String("s3-aws-profile"),
+		AssumeRole: model.S3AssumeRoleConfig{
+			RoleArn:     c.String("s3-role-arn"),
+			ExternalId:  c.String("s3-external-id"),
+			SessionName: c.String("s3-role-session-name"),
+		},
+		UsePathStyle: c.Bool("s3-path-style"),
 	}
 
 	// check if we have static credentials
@@ -141,22 +218,57 @@ func S3ProfileFromCtx(c *cli.Context) model.S3Profile {
 	}
 }
 
+// tagsFromCtx returns the key=value tags of the s3-tag flag, a tag without a value has an empty value
+func tagsFromCtx(values []string) map[string]string {
+	if len(values) == 0 {
+		return nil
+	}
+	tags := make(map[string]string, len(values))
+	for _, value := range values {
+		key, tagValue, _ := strings.Cut(value, "=")
+		tags[strings.TrimSpace(key)] = strings.TrimSpace(tagValue)
+	}
+	return tags
+}
+
 func BuildViper(profile string, dirs *CommonDirs, s3Profile model.S3Profile) *viper.Viper {
 	v := viper.New()
 	v.SetConfigType("yaml")
 	v.SetConfigFile(path.Join(dirs.BaseFolder, "config.yaml"))
 	v.Set(profile+".dataFolder", dirs.DataFolder)
+	setS3Profile(v, profile, s3Profile)
+	return v
+}
+
+// setS3Profile sets the S3 settings of the profile
+func setS3Profile(v *viper.Viper, profile string, s3Profile model.S3Profile) {
 	v.Set(profile+".s3.s3Bucket", s3Profile.GetBucket())
 	v.Set(profile+".s3.s3Region", s3Profile.GetRegion())
 	v.Set(profile+".s3.s3Hostname", s3Profile.GetHostname())
+	v.Set(profile+".s3.s3KeyPrefix", s3Profile.GetKeyPrefix())
+	v.Set(profile+".s3.s3KeyTemplate", s3Profile.GetKeyTemplate())
+	v.Set(profile+".s3.upload.partSize", s3Profile.GetUpload().PartSize)
+	v.Set(profile+".s3.upload.concurrency", s3Profile.GetUpload().Concurrency)
+	v.Set(profile+".s3.upload.checksum", s3Profile.GetUpload().Checksum)
+	v.Set(profile+".s3.upload.maxAttempts", s3Profile.GetUpload().MaxAttempts)
+	v.Set(profile+".s3.upload.encryption", s3Profile.GetUpload().Encryption)
+	v.Set(profile+".s3.upload.kmsKeyId", s3Profile.GetUpload().KmsKeyId)
+	v.Set(profile+".s3.upload.tags", s3Profile.GetUpload().Tags)
+	v.Set(profile+".s3.awsProfile", s3Profile.GetAwsProfile())
+	if assumeRole := s3Profile.GetAssumeRole(); assumeRole != nil {
+		v.Set(profile+".s3.assumeRole.roleArn", assumeRole.RoleArn)
+		v.Set(profile+".s3.assumeRole.externalId", assumeRole.ExternalId)
+		v.Set(profile+".s3.assumeRole.sessionName", assumeRole.SessionName)
+	}
+	v.Set(profile+".s3.usePathStyle", s3Profile.GetUsePathStyle())
 	if s3Profile.StaticAuthentication() {
 		v.Set(profile+".s3.s3ApiKey", s3Profile.(*model.S3StaticProfile).S3ApiKey)
 		v.Set(profile+".s3.s3SecretKey", s3Profile.(*model.S3StaticProfile).S3SecretKey)
 	}
-	return v
 }
 
-func UpdateS3Config(s3Profile model.S3Profile, dirs *CommonDirs) error {
+// UpdateS3Config writes the S3 settings of the profile to the configuration file, keeping the other profiles
+func UpdateS3Config(profile string, s3Profile model.S3Profile, dirs *CommonDirs) error {
 	v := viper.New()
 	v.SetConfigType("yaml")
 	v.SetConfigFile(path.Join(dirs.BaseFolder, "config.yaml"))
@@ -164,18 +276,11 @@ func UpdateS3Config(s3Profile model.S3Profile, dirs *CommonDirs) error {
 	if err != nil {
 		return err
 	}
-	profile := "default"
-	v.Set(profile+".s3.s3Bucket", s3Profile.GetBucket())
-	v.Set(profile+".s3.s3Region", s3Profile.GetRegion())
-	v.Set(profile+".s3.s3Hostname", s3Profile.GetHostname())
+	setS3Profile(v, profile, s3Profile)
 	v.Set(profile+".data.disclosuresFolder", dirs.DisclosuresFolder)
 	v.Set(profile+".data.imagesFolder", dirs.ImageFolder)
 	v.Set(profile+".data.ocrFolder", dirs.OcrFolder)
 	v.Set(profile+".data.csvFolder", dirs.CsvFolder)
-	if s3Profile.StaticAuthentication() {
-		v.Set(profile+".s3.s3ApiKey", s3Profile.(*model.S3StaticProfile).S3ApiKey)
-		v.Set(profile+".s3.s3SecretKey", s3Profile.(*model.S3StaticProfile).S3SecretKey)
-	}
 	err = v.WriteConfig()
 	if err != nil {
 		return err
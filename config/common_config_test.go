@@ -68,3 +68,39 @@ func TestBuildViper(t *testing.T) {
 	assert.Equal(t, "/tmp/config.yaml", v.ConfigFileUsed())
 	assert.Equal(t, "/tmp/data", v.GetString("default.dataFolder"))
 }
+
+func TestS3ProfileFromViper(t *testing.T) {
+	commonDirs := NewCommonDirs("/tmp")
+	s3Profile := &model.S3DefaultProfile{
+		S3Bucket:   "bucket",
+		S3Region:   "region",
+		S3Hostname: "hostname",
+		AwsProfile: "staging",
+		AssumeRole: model.S3AssumeRoleConfig{
+			RoleArn:     "arn:aws:iam::123456789012:role/uploader",
+			ExternalId:  "external",
+			SessionName: "disclosurecli",
+		},
+		UsePathStyle: true,
+		S3Upload: model.S3UploadConfig{
+			Encryption: "sse-kms",
+			KmsKeyId:   "alias/disclosures",
+			Tags:       map[string]string{"project": "disclosures"},
+		},
+	}
+	v := BuildViper("staging", commonDirs, s3Profile)
+
+	got, err := S3ProfileFromViper(v, "staging")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "bucket", got.GetBucket())
+	assert.Equal(t, "staging", got.GetAwsProfile())
+	assert.Equal(t, s3Profile.AssumeRole, *got.GetAssumeRole())
+	assert.Equal(t, true, got.GetUsePathStyle())
+	assert.Equal(t, "sse-kms", got.GetUpload().Encryption)
+	assert.Equal(t, "alias/disclosures", got.GetUpload().KmsKeyId)
+	assert.Equal(t, "disclosures", got.GetUpload().Tags["project"])
+	assert.Equal(t, false, got.StaticAuthentication())
+
+	_, err = S3ProfileFromViper(v, "default")
+	assert.Equal(t, true, err != nil)
+}
@@ -54,6 +54,8 @@ func BuildConfKeyMaps(profile string) *ConfKeys {
 		"ocrFolder":         constants.DefaultOcrFolder,
 		"csvFolder":         constants.DefaultCsvFolder,
 		"s3Folder":          constants.DefaultS3Folder,
+		"searchableFolder":  constants.DefaultSearchableFolder,
+		"exportFolder":      constants.DefaultExportFolder,
 	}
 
 	confKeys := make([]*ConfKeyMap, len(configKeys))
@@ -105,5 +107,7 @@ func (c *ConfKeys) GetCommonDirs() *CommonDirs {
 		ImageFolder:       c.GetKeyMap("images").GetCurrentVal(),
 		OcrFolder:         c.GetKeyMap("ocr").GetCurrentVal(),
 		CsvFolder:         c.GetKeyMap("csv").GetCurrentVal(),
+		SearchableFolder:  c.GetKeyMap("searchable").GetCurrentVal(),
+		ExportFolder:      c.GetKeyMap("exports").GetCurrentVal(),
 	}
 }
@@ -281,3 +281,22 @@ func GetTransactionReportMembers(downloads []*DisclosureDownload, dataFolder str
 	}
 	return downloadMembers, err
 }
+
+// GetMembersByDocId returns the members of every downloaded disclosure index keyed by DocId.
+// Years whose XML index hasn't been downloaded are skipped.
+func GetMembersByDocId(downloads []*DisclosureDownload) (map[int]*model.Member, error) {
+	members := make(map[int]*model.Member)
+	for _, disclosureDownload := range downloads {
+		if !disclosureDownload.XmlIsPresent() {
+			continue
+		}
+		disclosure, err := model.CreateFinancialDisclosure(disclosureDownload.XmlPath)
+		if err != nil {
+			return members, err
+		}
+		for _, member := range disclosure.Members {
+			members[member.DocId] = member
+		}
+	}
+	return members, nil
+}
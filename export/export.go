@@ -0,0 +1,94 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/gocarina/gocsv"
+	"github.com/parquet-go/parquet-go"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	FormatCsv     = "csv"
+	FormatJsonl   = "jsonl"
+	FormatParquet = "parquet"
+)
+
+// ParseFormat returns the export format for the name given on the command line
+func ParseFormat(name string) (string, error) {
+	switch format := strings.ToLower(strings.TrimSpace(name)); format {
+	case FormatCsv, FormatJsonl, FormatParquet:
+		return format, nil
+	case "json", "ndjson":
+		return FormatJsonl, nil
+	default:
+		return "", fmt.Errorf("unsupported export format %q", name)
+	}
+}
+
+// Extension returns the file extension of the format, including the leading dot
+func Extension(format string) string {
+	return "." + format
+}
+
+// Write writes the records to path in the given format, replacing the file if it exists.
+// CSV is comma separated with a header row, JSON Lines has one object per line, and Parquet has one column
+// per field. Columns are named by the csv, json and parquet struct tags of the record type.
+func Write[T any](path, format string, records []*T) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if err = Encode(f, format, records); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+// Encode writes the records to w in the given format, see Write
+func Encode[T any](w io.Writer, format string, records []*T) error {
+	switch format {
+	case FormatCsv:
+		return writeCsv(w, records)
+	case FormatJsonl:
+		return writeJsonl(w, records)
+	case FormatParquet:
+		return writeParquet(w, records)
+	}
+	return fmt.Errorf("unsupported export format %q", format)
+}
+
+func writeCsv[T any](out io.Writer, records []*T) error {
+	// a writer of its own, the package level gocsv writer is set to tab separated output by other commands
+	writer := gocsv.NewSafeCSVWriter(csv.NewWriter(out))
+	return gocsv.MarshalCSV(&records, writer)
+}
+
+func writeJsonl[T any](out io.Writer, records []*T) error {
+	w := bufio.NewWriter(out)
+	encoder := json.NewEncoder(w)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func writeParquet[T any](out io.Writer, records []*T) error {
+	writer := parquet.NewGenericWriter[T](out)
+	rows := make([]T, len(records))
+	for i, record := range records {
+		rows[i] = *record
+	}
+	if _, err := writer.Write(rows); err != nil {
+		return err
+	}
+	return writer.Close()
+}
@@ -0,0 +1,122 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/parquet-go/parquet-go"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testRecord struct {
+	DocId  int     `csv:"docId" json:"docId" parquet:"docId"`
+	Name   string  `csv:"name" json:"name" parquet:"name"`
+	Score  float64 `csv:"score" json:"score" parquet:"score"`
+	Parsed bool    `csv:"parsed" json:"parsed" parquet:"parsed"`
+}
+
+var testRecords = []*testRecord{
+	{DocId: 20012345, Name: "Smith, James", Score: 0.5, Parsed: true},
+	{DocId: 8220001, Name: "Doe", Score: 96.25},
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"csv", FormatCsv, false},
+		{" Parquet", FormatParquet, false},
+		{"jsonl", FormatJsonl, false},
+		{"ndjson", FormatJsonl, false},
+		{"xlsx", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFormat(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteCsv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.csv")
+	if err := Write(path, FormatCsv, testRecords); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "docId,name,score,parsed\n" +
+		"20012345,\"Smith, James\",0.5,true\n" +
+		"8220001,Doe,96.25,false\n"
+	if string(data) != want {
+		t.Errorf("csv = %q, want %q", data, want)
+	}
+}
+
+func TestWriteJsonl(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.jsonl")
+	if err := Write(path, FormatJsonl, testRecords); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	got := make([]*testRecord, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		record := &testRecord{}
+		if err = json.Unmarshal(scanner.Bytes(), record); err != nil {
+			t.Fatalf("line %q: %s", scanner.Text(), err)
+		}
+		got = append(got, record)
+	}
+	if !reflect.DeepEqual(got, testRecords) {
+		t.Errorf("jsonl records = %v, want %v", got, testRecords)
+	}
+}
+
+func TestWriteParquet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.parquet")
+	if err := Write(path, FormatParquet, testRecords); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := parquet.ReadFile[testRecord](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != len(testRecords) {
+		t.Fatalf("read %d rows, want %d", len(rows), len(testRecords))
+	}
+	for i, row := range rows {
+		if !reflect.DeepEqual(&row, testRecords[i]) {
+			t.Errorf("row %d = %v, want %v", i, row, testRecords[i])
+		}
+	}
+}
+
+func TestWriteUnsupportedFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.xlsx")
+	err := Write(path, "xlsx", testRecords)
+	if err == nil || !strings.Contains(err.Error(), "unsupported") {
+		t.Fatalf("Write() error = %v, want unsupported format", err)
+	}
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("file of failed export was not removed")
+	}
+}
@@ -0,0 +1,227 @@
+package filings
+
+import (
+	"fmt"
+	"github.com/paulschick/disclosureupdater/model"
+	"github.com/paulschick/disclosureupdater/registry"
+	"maps"
+	"sort"
+	"strings"
+	"time"
+)
+
+// filing is a disclosure index entry with the values used to link it
+type filing struct {
+	*model.Filing
+	date time.Time
+	// keys identify the trades of a PTR, amendments are linked to the PTR they share the most trades with
+	keys map[string]bool
+}
+
+// Link returns a filing for every member entry of the disclosure index, sorted by DocId.
+// Filings are grouped by registered member, year and kind (annual reports or PTRs). Annual amendments (type A) are
+// linked to the latest original report filed before them. A PTR is an amendment when most of its
+// transactions have the Amended filing status, it's linked to the earlier PTR it shares the most trades with.
+// The effective version of a filing is its latest amendment, which replaces the trades it files again in exports,
+// see EffectiveTransactions.
+func Link(members []*model.Member, transactions []*model.Transaction, reg *registry.Registry) []*model.Filing {
+	byDocId := make(map[int][]*model.Transaction)
+	for _, t := range transactions {
+		byDocId[t.DocId] = append(byDocId[t.DocId], t)
+	}
+
+	groups := make(map[string][]*filing)
+	all := make([]*model.Filing, 0, len(members))
+	for _, m := range members {
+		f := newFiling(m, byDocId[m.DocId])
+		f.MemberId = reg.MemberId(m.DocId)
+		all = append(all, f.Filing)
+		key := groupKey(f.MemberId, m)
+		groups[key] = append(groups[key], f)
+	}
+
+	for _, group := range groups {
+		sort.SliceStable(group, func(i, j int) bool {
+			if !group[i].date.Equal(group[j].date) {
+				return group[i].date.Before(group[j].date)
+			}
+			return group[i].DocId < group[j].DocId
+		})
+		linkGroup(group)
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].DocId < all[j].DocId
+	})
+	return all
+}
+
+// EffectiveTransactions returns the transactions of the latest version of every trade. An amendment often
+// re-files only some trades of a PTR, so a trade of a superseded filing is only dropped when a later version in
+// its chain of amendments files the same trade again, the trades it leaves out are kept.
+func EffectiveTransactions(transactions []*model.Transaction, filings []*model.Filing) []*model.Transaction {
+	keysByDocId := make(map[int]map[string]bool)
+	for _, t := range transactions {
+		if keysByDocId[t.DocId] == nil {
+			keysByDocId[t.DocId] = make(map[string]bool)
+		}
+		keysByDocId[t.DocId][tradeKey(t)] = true
+	}
+	chains := make(map[int][]*model.Filing)
+	for _, f := range filings {
+		if f.EffectiveDocId != 0 {
+			chains[f.EffectiveDocId] = append(chains[f.EffectiveDocId], f)
+		}
+	}
+	// refiled holds the trades filed again by a later version of each superseded filing
+	refiled := make(map[int]map[string]bool)
+	for _, chain := range chains {
+		if len(chain) < 2 {
+			continue
+		}
+		sortChain(chain)
+		later := make(map[string]bool)
+		for i := len(chain) - 1; i >= 0; i-- {
+			f := chain[i]
+			if f.Superseded() {
+				refiled[f.DocId] = maps.Clone(later)
+			}
+			for key := range keysByDocId[f.DocId] {
+				later[key] = true
+			}
+		}
+	}
+	effective := make([]*model.Transaction, 0, len(transactions))
+	for _, t := range transactions {
+		if refiled[t.DocId][tradeKey(t)] {
+			continue
+		}
+		effective = append(effective, t)
+	}
+	return effective
+}
+
+// sortChain sorts the filings of a chain of amendments in filing order, the original first
+func sortChain(chain []*model.Filing) {
+	sort.SliceStable(chain, func(i, j int) bool {
+		if chain[i].Amendment != chain[j].Amendment {
+			return !chain[i].Amendment
+		}
+		// unparseable dates sort first, like in Link
+		di, _ := model.ParseDate(chain[i].FilingDate)
+		dj, _ := model.ParseDate(chain[j].FilingDate)
+		if !di.Equal(dj) {
+			return di.Before(dj)
+		}
+		return chain[i].DocId < chain[j].DocId
+	})
+}
+
+func newFiling(m *model.Member, transactions []*model.Transaction) *filing {
+	f := &filing{
+		Filing: &model.Filing{
+			DocId:          m.DocId,
+			Member:         m.FullName(),
+			StateDst:       m.StateDst,
+			Year:           m.Year,
+			FilingType:     m.FilingType,
+			FilingDate:     m.FilingDate,
+			EffectiveDocId: m.DocId,
+		},
+		keys: make(map[string]bool),
+	}
+	// unparseable dates sort first, the DocId breaks the tie
+	f.date, _ = model.ParseDate(m.FilingDate)
+	amended := 0
+	for _, t := range transactions {
+		f.keys[tradeKey(t)] = true
+		if t.FilingStatus == model.FilingStatusAmended {
+			amended++
+		}
+	}
+	switch m.FilingType {
+	case model.FilingTypeAmendment:
+		f.Amendment = true
+	case model.FilingTypePtr:
+		f.Amendment = amended > 0 && amended*2 > len(transactions)
+	}
+	return f
+}
+
+// linkGroup links the amendments of a group sorted by filing date to their originals, and sets the
+// effective version of every filing
+func linkGroup(group []*filing) {
+	amendments := make(map[int][]*filing)
+	for i, f := range group {
+		if !f.Amendment {
+			continue
+		}
+		if original := findOriginal(f, group[:i]); original != nil {
+			f.AmendsDocId = original.DocId
+			amendments[original.DocId] = append(amendments[original.DocId], f)
+		}
+	}
+	for _, original := range group {
+		chain := amendments[original.DocId]
+		if len(chain) == 0 {
+			continue
+		}
+		// amendments are in filing order, the last one is the effective version
+		effective := chain[len(chain)-1].DocId
+		original.EffectiveDocId = effective
+		for _, amendment := range chain {
+			amendment.EffectiveDocId = effective
+		}
+	}
+}
+
+// findOriginal returns the filing the amendment most likely amends from the filings before it
+func findOriginal(amendment *filing, earlier []*filing) *filing {
+	var best *filing
+	bestOverlap := 0
+	for i := len(earlier) - 1; i >= 0; i-- {
+		candidate := earlier[i]
+		if candidate.Amendment {
+			continue
+		}
+		if amendment.FilingType == model.FilingTypeAmendment {
+			if candidate.FilingType == model.FilingTypeOriginal {
+				return candidate
+			}
+			continue
+		}
+		overlap := 0
+		for key := range amendment.keys {
+			if candidate.keys[key] {
+				overlap++
+			}
+		}
+		// latest filing wins ties
+		if overlap > bestOverlap {
+			best, bestOverlap = candidate, overlap
+		}
+	}
+	return best
+}
+
+// groupKey identifies the filings of a member of one kind in one year
+func groupKey(memberId string, m *model.Member) string {
+	kind := "other-" + m.FilingType
+	switch m.FilingType {
+	case model.FilingTypeOriginal, model.FilingTypeAmendment:
+		kind = "annual"
+	case model.FilingTypePtr:
+		kind = "ptr"
+	}
+	return fmt.Sprintf("%s|%d|%s", memberId, m.Year, kind)
+}
+
+// tradeKey identifies a trade across the original and amended PTR by date, owner, transaction type and ticker,
+// or asset name, so a purchase and a sale of the same security on the same day are different trades
+func tradeKey(t *model.Transaction) string {
+	asset := t.Ticker
+	if asset == "" {
+		asset = strings.ToLower(t.Asset)
+	}
+	return strings.Join([]string{t.Date, string(t.Owner), string(t.TransactionType), asset}, "|")
+}
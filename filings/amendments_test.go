@@ -0,0 +1,135 @@
+package filings
+
+import (
+	"github.com/paulschick/disclosureupdater/model"
+	"github.com/paulschick/disclosureupdater/registry"
+	"reflect"
+	"testing"
+)
+
+func TestLink(t *testing.T) {
+	members := []*model.Member{
+		{DocId: 1, First: "Jane", Last: "Doe", StateDst: "CA12", Year: 2023, FilingType: "O", FilingDate: "5/15/2023"},
+		{DocId: 2, First: "Jane", Last: "Doe", StateDst: "CA12", Year: 2023, FilingType: "A", FilingDate: "6/1/2023"},
+		{DocId: 3, First: "Jane A.", Last: "Doe", StateDst: "CA12", Year: 2023, FilingType: "A", FilingDate: "7/1/2023"},
+		{DocId: 10, First: "Jane", Last: "Doe", StateDst: "CA12", Year: 2023, FilingType: "P", FilingDate: "2/1/2023"},
+		{DocId: 11, First: "Jane", Last: "Doe", StateDst: "CA12", Year: 2023, FilingType: "P", FilingDate: "3/1/2023"},
+		{DocId: 12, First: "Jane", Last: "Doe", StateDst: "CA12", Year: 2023, FilingType: "P", FilingDate: "4/1/2023"},
+		{DocId: 20, First: "John", Last: "Roe", StateDst: "TX07", Year: 2023, FilingType: "P", FilingDate: "4/2/2023"},
+	}
+	transactions := []*model.Transaction{
+		{DocId: 10, Date: "01/10/2023", Ticker: "AAPL", FilingStatus: model.FilingStatusNew},
+		{DocId: 10, Date: "01/11/2023", Ticker: "MSFT", FilingStatus: model.FilingStatusNew},
+		{DocId: 11, Date: "02/10/2023", Ticker: "NVDA", FilingStatus: model.FilingStatusNew},
+		// amends 10, not the more recent 11
+		{DocId: 12, Date: "01/10/2023", Ticker: "AAPL", FilingStatus: model.FilingStatusAmended},
+		{DocId: 12, Date: "01/11/2023", Ticker: "MSFT", FilingStatus: model.FilingStatusAmended},
+		// same trade by a different member
+		{DocId: 20, Date: "01/10/2023", Ticker: "AAPL", FilingStatus: model.FilingStatusAmended},
+	}
+	reg := registry.NewRegistry(nil)
+	reg.Add(members)
+	filings := Link(members, transactions, reg)
+	byDocId := make(map[int]*model.Filing)
+	for _, f := range filings {
+		byDocId[f.DocId] = f
+	}
+	tests := []struct {
+		docId     int
+		amendment bool
+		amends    int
+		effective int
+	}{
+		{1, false, 0, 3},
+		{2, true, 1, 3},
+		{3, true, 1, 3},
+		{10, false, 0, 12},
+		{11, false, 0, 11},
+		{12, true, 10, 12},
+		{20, true, 0, 20},
+	}
+	for _, test := range tests {
+		f := byDocId[test.docId]
+		if f.Amendment != test.amendment || f.AmendsDocId != test.amends || f.EffectiveDocId != test.effective {
+			t.Errorf("Link() %d = %+v; want amendment %t, amends %d, effective %d",
+				test.docId, *f, test.amendment, test.amends, test.effective)
+		}
+	}
+
+	effective := EffectiveTransactions(transactions, filings)
+	if len(effective) != 4 {
+		t.Errorf("EffectiveTransactions() returned %d transactions; want 4", len(effective))
+	}
+	for _, transaction := range effective {
+		if transaction.DocId == 10 {
+			t.Errorf("EffectiveTransactions() kept superseded %+v", *transaction)
+		}
+	}
+}
+
+func TestEffectiveTransactionsPartialAmendment(t *testing.T) {
+	members := []*model.Member{
+		{DocId: 10, First: "Jane", Last: "Doe", StateDst: "CA12", Year: 2023, FilingType: "P", FilingDate: "2/1/2023"},
+		{DocId: 12, First: "Jane", Last: "Doe", StateDst: "CA12", Year: 2023, FilingType: "P", FilingDate: "4/1/2023"},
+		{DocId: 13, First: "Jane", Last: "Doe", StateDst: "CA12", Year: 2023, FilingType: "P", FilingDate: "5/1/2023"},
+	}
+	transactions := []*model.Transaction{
+		{DocId: 10, Date: "01/10/2023", Ticker: "AAPL", Amount: "$1,001 - $15,000", FilingStatus: model.FilingStatusNew},
+		{DocId: 10, Date: "01/11/2023", Ticker: "MSFT", FilingStatus: model.FilingStatusNew},
+		{DocId: 10, Date: "01/12/2023", Ticker: "NVDA", FilingStatus: model.FilingStatusNew},
+		// re-files only the AAPL trade with a corrected amount
+		{DocId: 12, Date: "01/10/2023", Ticker: "AAPL", Amount: "$15,001 - $50,000", FilingStatus: model.FilingStatusAmended},
+		// re-files the MSFT trade, after 12
+		{DocId: 13, Date: "01/11/2023", Ticker: "MSFT", FilingStatus: model.FilingStatusAmended},
+	}
+	reg := registry.NewRegistry(nil)
+	reg.Add(members)
+	effective := EffectiveTransactions(transactions, Link(members, transactions, reg))
+
+	got := make(map[string]int)
+	for _, transaction := range effective {
+		got[transaction.Ticker] = transaction.DocId
+	}
+	want := map[string]int{"AAPL": 12, "MSFT": 13, "NVDA": 10}
+	if len(effective) != len(want) || !reflect.DeepEqual(got, want) {
+		t.Errorf("EffectiveTransactions() = %v from %d transactions; want %v", got, len(effective), want)
+	}
+}
+
+func TestEffectiveTransactionsSameDayTrades(t *testing.T) {
+	members := []*model.Member{
+		{DocId: 10, First: "Jane", Last: "Doe", StateDst: "CA12", Year: 2023, FilingType: "P", FilingDate: "2/1/2023"},
+		{DocId: 12, First: "Jane", Last: "Doe", StateDst: "CA12", Year: 2023, FilingType: "P", FilingDate: "4/1/2023"},
+	}
+	transactions := []*model.Transaction{
+		{DocId: 10, Date: "01/03/2023", Ticker: "AAPL", TransactionType: model.TransactionPurchase,
+			Amount: "$1,001 - $15,000"},
+		{DocId: 10, Date: "01/03/2023", Ticker: "AAPL", TransactionType: model.TransactionSale},
+		{DocId: 10, Date: "01/03/2023", Owner: model.OwnerSpouse, Ticker: "AAPL",
+			TransactionType: model.TransactionPurchase},
+		// re-files only the filer's purchase with a corrected amount
+		{DocId: 12, Date: "01/03/2023", Ticker: "AAPL", TransactionType: model.TransactionPurchase,
+			Amount: "$15,001 - $50,000", FilingStatus: model.FilingStatusAmended},
+	}
+	reg := registry.NewRegistry(nil)
+	reg.Add(members)
+	effective := EffectiveTransactions(transactions, Link(members, transactions, reg))
+
+	type trade struct {
+		docId           int
+		owner           model.OwnerCode
+		transactionType model.TransactionType
+	}
+	got := make([]trade, 0)
+	for _, transaction := range effective {
+		got = append(got, trade{transaction.DocId, transaction.Owner, transaction.TransactionType})
+	}
+	want := []trade{
+		{10, model.OwnerSelf, model.TransactionSale},
+		{10, model.OwnerSpouse, model.TransactionPurchase},
+		{12, model.OwnerSelf, model.TransactionPurchase},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("EffectiveTransactions() = %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,157 @@
+// Package s3test is an in-memory S3-compatible server for tests of the S3 clients
+package s3test
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Fake stores objects for path-style requests, it supports put, get, head, delete and list of objects
+type Fake struct {
+	mu       sync.Mutex
+	objects  map[string]*Object
+	requests []*http.Request
+}
+
+// Object is a stored object with the content type and user metadata headers it was put with
+type Object struct {
+	Data     []byte
+	Header   http.Header
+	Modified time.Time
+}
+
+// NewServer starts a fake server that is closed when the test ends
+func NewServer(t testing.TB) (*Fake, *httptest.Server) {
+	f := &Fake{objects: make(map[string]*Object)}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+// Object returns the object of the bucket, nil if there is none
+func (f *Fake) Object(bucket, key string) *Object {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.objects[bucket+"/"+key]
+}
+
+// Keys returns the keys of every object as bucket/key, sorted
+func (f *Fake) Keys() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := make([]string, 0, len(f.objects))
+	for key := range f.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Requests returns every request the server received
+func (f *Fake) Requests() []*http.Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*http.Request(nil), f.requests...)
+}
+
+// ETag returns the ETag of a single part upload, the hex md5 of the content
+func ETag(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (f *Fake) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r)
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == http.MethodPut && key != "":
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		header := make(http.Header)
+		for name, values := range r.Header {
+			if strings.HasPrefix(strings.ToLower(name), "x-amz-meta-") || name == "Content-Type" {
+				header[name] = values
+			}
+		}
+		f.objects[bucket+"/"+key] = &Object{Data: data, Header: header, Modified: time.Now().UTC()}
+		w.Header().Set("ETag", `"`+ETag(data)+`"`)
+	case (r.Method == http.MethodHead || r.Method == http.MethodGet) && key != "":
+		obj, ok := f.objects[bucket+"/"+key]
+		if !ok {
+			// HEAD responses have no body, S3 only returns the NotFound status
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			writeError(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		for name, values := range obj.Header {
+			w.Header()[name] = values
+		}
+		w.Header().Set("ETag", `"`+ETag(obj.Data)+`"`)
+		w.Header().Set("Last-Modified", obj.Modified.Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.Data)))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(obj.Data)
+		}
+	case r.Method == http.MethodDelete && key != "":
+		delete(f.objects, bucket+"/"+key)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && key == "" && r.URL.Query().Get("list-type") == "2":
+		f.list(w, bucket, r.URL.Query().Get("prefix"))
+	default:
+		writeError(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+// list writes every object under the prefix in one page
+func (f *Fake) list(w http.ResponseWriter, bucket, prefix string) {
+	type content struct {
+		Key          string
+		Size         int
+		ETag         string
+		LastModified string
+	}
+	result := struct {
+		XMLName  xml.Name `xml:"ListBucketResult"`
+		Name     string
+		Prefix   string
+		Contents []content
+	}{Name: bucket, Prefix: prefix}
+	for k, obj := range f.objects {
+		if rest, ok := strings.CutPrefix(k, bucket+"/"); ok && strings.HasPrefix(rest, prefix) {
+			result.Contents = append(result.Contents, content{Key: rest, Size: len(obj.Data), ETag: ETag(obj.Data),
+				LastModified: obj.Modified.Format(time.RFC3339)})
+		}
+	}
+	sort.Slice(result.Contents, func(i, j int) bool {
+		return result.Contents[i].Key < result.Contents[j].Key
+	})
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(result)
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_ = xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string
+	}{Code: code})
+}
@@ -0,0 +1,160 @@
+package model
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image"
+)
+
+const altoNamespace = "http://www.loc.gov/standards/alto/ns-v4#"
+
+// AltoDocument is the root of an ALTO v4 XML file for a single page image.
+// See https://www.loc.gov/standards/alto/ for the schema.
+type AltoDocument struct {
+	XMLName     xml.Name        `xml:"alto"`
+	Xmlns       string          `xml:"xmlns,attr"`
+	Description AltoDescription `xml:"Description"`
+	Layout      AltoLayout      `xml:"Layout"`
+}
+
+type AltoDescription struct {
+	MeasurementUnit string `xml:"MeasurementUnit"`
+	FileName        string `xml:"sourceImageInformation>fileName"`
+}
+
+type AltoLayout struct {
+	Page AltoPage `xml:"Page"`
+}
+
+type AltoPage struct {
+	ID            string         `xml:"ID,attr"`
+	Width         int            `xml:"WIDTH,attr"`
+	Height        int            `xml:"HEIGHT,attr"`
+	PhysicalImgNr int            `xml:"PHYSICAL_IMG_NR,attr"`
+	PrintSpace    AltoPrintSpace `xml:"PrintSpace"`
+}
+
+type AltoPrintSpace struct {
+	HPos       int              `xml:"HPOS,attr"`
+	VPos       int              `xml:"VPOS,attr"`
+	Width      int              `xml:"WIDTH,attr"`
+	Height     int              `xml:"HEIGHT,attr"`
+	TextBlocks []*AltoTextBlock `xml:"TextBlock"`
+}
+
+type AltoTextBlock struct {
+	ID        string          `xml:"ID,attr"`
+	HPos      int             `xml:"HPOS,attr"`
+	VPos      int             `xml:"VPOS,attr"`
+	Width     int             `xml:"WIDTH,attr"`
+	Height    int             `xml:"HEIGHT,attr"`
+	TextLines []*AltoTextLine `xml:"TextLine"`
+	bounds    image.Rectangle
+}
+
+type AltoTextLine struct {
+	ID      string        `xml:"ID,attr"`
+	HPos    int           `xml:"HPOS,attr"`
+	VPos    int           `xml:"VPOS,attr"`
+	Width   int           `xml:"WIDTH,attr"`
+	Height  int           `xml:"HEIGHT,attr"`
+	Strings []*AltoString `xml:"String"`
+	bounds  image.Rectangle
+}
+
+type AltoString struct {
+	ID      string  `xml:"ID,attr"`
+	HPos    int     `xml:"HPOS,attr"`
+	VPos    int     `xml:"VPOS,attr"`
+	Width   int     `xml:"WIDTH,attr"`
+	Height  int     `xml:"HEIGHT,attr"`
+	WC      float64 `xml:"WC,attr"`
+	Content string  `xml:"CONTENT,attr"`
+}
+
+// NewAltoDocument builds an ALTO document for one page from the OCR word results.
+// Words are grouped into blocks and lines using the Tesseract block, paragraph and line numbers,
+// and block and line extents are the union of the word boxes they contain.
+func NewAltoDocument(fileName string, pageNumber, width, height int, results []*OcrResult) *AltoDocument {
+	blocks := make([]*AltoTextBlock, 0)
+	blockIdx := make(map[int]*AltoTextBlock)
+	lineIdx := make(map[[3]int]*AltoTextLine)
+
+	for _, result := range results {
+		if result.Word == "" {
+			continue
+		}
+		block, ok := blockIdx[result.BlockNum]
+		if !ok {
+			block = &AltoTextBlock{
+				ID:        fmt.Sprintf("block_%d", len(blocks)+1),
+				TextLines: make([]*AltoTextLine, 0),
+			}
+			blockIdx[result.BlockNum] = block
+			blocks = append(blocks, block)
+		}
+		lineKey := [3]int{result.BlockNum, result.ParNum, result.LineNum}
+		line, ok := lineIdx[lineKey]
+		if !ok {
+			line = &AltoTextLine{
+				ID:      fmt.Sprintf("%s_line_%d", block.ID, len(block.TextLines)+1),
+				Strings: make([]*AltoString, 0),
+			}
+			lineIdx[lineKey] = line
+			block.TextLines = append(block.TextLines, line)
+		}
+		box := image.Rect(result.Left, result.Top, result.Right, result.Bottom)
+		line.Strings = append(line.Strings, &AltoString{
+			ID:      fmt.Sprintf("%s_word_%d", line.ID, len(line.Strings)+1),
+			HPos:    box.Min.X,
+			VPos:    box.Min.Y,
+			Width:   box.Dx(),
+			Height:  box.Dy(),
+			WC:      result.Confidence / 100,
+			Content: result.Word,
+		})
+		line.bounds = line.bounds.Union(box)
+		block.bounds = block.bounds.Union(box)
+	}
+
+	for _, block := range blocks {
+		block.HPos, block.VPos, block.Width, block.Height = rectToAlto(block.bounds)
+		for _, line := range block.TextLines {
+			line.HPos, line.VPos, line.Width, line.Height = rectToAlto(line.bounds)
+		}
+	}
+
+	return &AltoDocument{
+		Xmlns: altoNamespace,
+		Description: AltoDescription{
+			MeasurementUnit: "pixel",
+			FileName:        fileName,
+		},
+		Layout: AltoLayout{
+			Page: AltoPage{
+				ID:            fmt.Sprintf("page_%d", pageNumber),
+				Width:         width,
+				Height:        height,
+				PhysicalImgNr: pageNumber,
+				PrintSpace: AltoPrintSpace{
+					Width:      width,
+					Height:     height,
+					TextBlocks: blocks,
+				},
+			},
+		},
+	}
+}
+
+// Marshal returns the indented XML encoding of the document including the XML header
+func (a *AltoDocument) Marshal() ([]byte, error) {
+	out, err := xml.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+func rectToAlto(r image.Rectangle) (hPos, vPos, width, height int) {
+	return r.Min.X, r.Min.Y, r.Dx(), r.Dy()
+}
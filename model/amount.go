@@ -0,0 +1,145 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// AmountBracket is one of the value ranges filers report transaction amounts in
+type AmountBracket int
+
+const (
+	AmountUnknown AmountBracket = iota
+	Amount1To1000
+	Amount1001To15000
+	Amount15001To50000
+	Amount50001To100000
+	Amount100001To250000
+	Amount250001To500000
+	Amount500001To1000000
+	Amount1000001To5000000
+	Amount5000001To25000000
+	Amount25000001To50000000
+	AmountOver50000000
+	AmountSpouseOver1000000
+)
+
+// amountRange is the lower and upper bound of a bracket, max is 0 for open ended brackets
+type amountRange struct {
+	min   int64
+	max   int64
+	label string
+}
+
+var amountRanges = map[AmountBracket]amountRange{
+	AmountUnknown:            {0, 0, ""},
+	Amount1To1000:            {1, 1000, "$1 - $1,000"},
+	Amount1001To15000:        {1001, 15000, "$1,001 - $15,000"},
+	Amount15001To50000:       {15001, 50000, "$15,001 - $50,000"},
+	Amount50001To100000:      {50001, 100000, "$50,001 - $100,000"},
+	Amount100001To250000:     {100001, 250000, "$100,001 - $250,000"},
+	Amount250001To500000:     {250001, 500000, "$250,001 - $500,000"},
+	Amount500001To1000000:    {500001, 1000000, "$500,001 - $1,000,000"},
+	Amount1000001To5000000:   {1000001, 5000000, "$1,000,001 - $5,000,000"},
+	Amount5000001To25000000:  {5000001, 25000000, "$5,000,001 - $25,000,000"},
+	Amount25000001To50000000: {25000001, 50000000, "$25,000,001 - $50,000,000"},
+	AmountOver50000000:       {50000001, 0, "Over $50,000,000"},
+	AmountSpouseOver1000000:  {1000001, 0, "Spouse/DC Over $1,000,000"},
+}
+
+var (
+	// OCR reads the dollar sign as S or §
+	ocrDollarPattern   = regexp.MustCompile(`[S§]\s?([0-9lI|Oo])`)
+	amountValuePattern = regexp.MustCompile(
+		`\$\s*([0-9lI|Oo]{1,3}(?:\s?[,.]\s?[0-9lI|Oo]{3})+|[0-9lI|Oo]+)`)
+	ocrDigitReplacer = strings.NewReplacer("l", "1", "I", "1", "|", "1", "O", "0", "o", "0", ",", "", ".", "", " ", "")
+	dashReplacer     = strings.NewReplacer("–", "-", "—", "-", "~", "-")
+)
+
+// Min returns the lower bound of the bracket in dollars
+func (b AmountBracket) Min() int64 {
+	return amountRanges[b].min
+}
+
+// Max returns the upper bound of the bracket in dollars, 0 if the bracket is open ended
+func (b AmountBracket) Max() int64 {
+	return amountRanges[b].max
+}
+
+// Bounded returns false for the open ended "Over" brackets
+func (b AmountBracket) Bounded() bool {
+	return amountRanges[b].max > 0
+}
+
+// String returns the canonical label of the bracket as printed on the disclosure forms
+func (b AmountBracket) String() string {
+	return amountRanges[b].label
+}
+
+func (b AmountBracket) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+func (b *AmountBracket) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*b = AmountUnknown
+		return nil
+	}
+	bracket := ParseAmount(string(text))
+	if bracket == AmountUnknown {
+		return fmt.Errorf("unknown amount bracket %q", text)
+	}
+	*b = bracket
+	return nil
+}
+
+// ParseAmount maps the raw text of an Amount column, as read from the text layer or by OCR,
+// to its bracket. Common OCR errors are corrected first: S or § for $, l, I or | for 1 and O for 0.
+// When only one bound can be read, or one bound was misread, the bracket is matched on the other.
+// AmountUnknown is returned if the text doesn't match any bracket.
+func ParseAmount(text string) AmountBracket {
+	text = dashReplacer.Replace(text)
+	text = ocrDollarPattern.ReplaceAllString(text, "$$$1")
+	values := make([]int64, 0, 2)
+	for _, m := range amountValuePattern.FindAllStringSubmatch(text, -1) {
+		value, err := strconv.ParseInt(ocrDigitReplacer.Replace(m[1]), 10, 64)
+		if err == nil {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return AmountUnknown
+	}
+
+	lower := strings.ToLower(text)
+	if strings.Contains(lower, "over") {
+		if strings.Contains(lower, "spouse") || values[0] == 1000000 {
+			return AmountSpouseOver1000000
+		}
+		return AmountOver50000000
+	}
+	if len(values) == 1 {
+		// a range with a bound lost to OCR
+		return findBracket(func(r amountRange) bool { return r.min == values[0] || r.max == values[0] })
+	}
+	low, high := values[0], values[1]
+	if bracket := findBracket(func(r amountRange) bool { return r.min == low && r.max == high }); bracket != AmountUnknown {
+		return bracket
+	}
+	if bracket := findBracket(func(r amountRange) bool { return r.max == high }); bracket != AmountUnknown {
+		return bracket
+	}
+	return findBracket(func(r amountRange) bool { return r.min == low })
+}
+
+// findBracket returns the first bounded bracket, in ascending order, that matches
+func findBracket(match func(r amountRange) bool) AmountBracket {
+	for bracket := Amount1To1000; bracket <= Amount25000001To50000000; bracket++ {
+		if match(amountRanges[bracket]) {
+			return bracket
+		}
+	}
+	return AmountUnknown
+}
@@ -0,0 +1,57 @@
+package model
+
+import "testing"
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected AmountBracket
+	}{
+		{"$1,001 - $15,000", Amount1001To15000},
+		{"$15,001 -\n$50,000", Amount15001To50000},
+		{"$1,000,001 - $5,000,000", Amount1000001To5000000},
+		{"Over $50,000,000", AmountOver50000000},
+		{"Spouse/DC Over $1,000,000", AmountSpouseOver1000000},
+		{"$1 - $1,000", Amount1To1000},
+		// OCR errors
+		{"S1,001 - S15,000", Amount1001To15000},
+		{"$l5,00l - $5O,OOO", Amount15001To50000},
+		{"$250.001 – $500.000", Amount250001To500000},
+		{"$100, 001 - $250,000", Amount100001To250000},
+		{"§500,001 - $1,000,000", Amount500001To1000000},
+		// one bound misread or missing
+		{"$5,000,00 - $25,000,000", Amount5000001To25000000},
+		{"$50,001 -", Amount50001To100000},
+		{"", AmountUnknown},
+		{"None", AmountUnknown},
+		{"$12 - $34", AmountUnknown},
+	}
+	for _, test := range tests {
+		actual := ParseAmount(test.text)
+		if actual != test.expected {
+			t.Errorf("ParseAmount(%q) = %q; want %q", test.text, actual, test.expected)
+		}
+	}
+}
+
+func TestAmountBracket_Bounds(t *testing.T) {
+	if Amount1001To15000.Min() != 1001 || Amount1001To15000.Max() != 15000 || !Amount1001To15000.Bounded() {
+		t.Errorf("Amount1001To15000 bounds = %d, %d", Amount1001To15000.Min(), Amount1001To15000.Max())
+	}
+	if AmountOver50000000.Min() != 50000001 || AmountOver50000000.Bounded() {
+		t.Errorf("AmountOver50000000 bounds = %d, %d", AmountOver50000000.Min(), AmountOver50000000.Max())
+	}
+}
+
+func TestAmountBracket_UnmarshalText(t *testing.T) {
+	for bracket := Amount1To1000; bracket <= AmountSpouseOver1000000; bracket++ {
+		text, err := bracket.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actual AmountBracket
+		if err = actual.UnmarshalText(text); err != nil || actual != bracket {
+			t.Errorf("UnmarshalText(%q) = %q, %v; want %q", text, actual, err, bracket)
+		}
+	}
+}
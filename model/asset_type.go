@@ -0,0 +1,72 @@
+package model
+
+// assetTypeNames are the asset type codes printed in brackets after PTR asset names, e.g. [ST]
+var assetTypeNames = map[string]string{
+	"4K": "401K and Other Non-Federal Retirement Accounts",
+	"5C": "529 College Savings Plan",
+	"5F": "529 Portfolio",
+	"5P": "529 Prepaid Tuition Plan",
+	"AB": "Asset-Backed Securities",
+	"BA": "Bank Accounts, Money Market Accounts and CDs",
+	"BK": "Brokerage Accounts",
+	"CO": "Collectibles",
+	"CS": "Corporate Securities (Bonds and Notes)",
+	"CT": "Cryptocurrency",
+	"DB": "Defined Benefit Pension Plan",
+	"DO": "Debts Owed to the Filer",
+	"DS": "Delaware Statutory Trust",
+	"EF": "Exchange Traded Funds (ETF)",
+	"EQ": "Excepted/Qualified Blind Trust",
+	"ET": "Exchange Traded Notes",
+	"FA": "Farms",
+	"FE": "Foreign Exchange Positions (Currencies)",
+	"FN": "Fixed Annuity",
+	"FU": "Futures",
+	"GS": "Government Securities and Agency Debt",
+	"HE": "Hedge Funds & Private Equity Funds (EIF)",
+	"HN": "Hedge Funds & Private Equity Funds (non-EIF)",
+	"IC": "Investment Club",
+	"IH": "IRA (Held in Cash)",
+	"IP": "Intellectual Property & Royalties",
+	"IR": "IRA",
+	"MA": "Managed Accounts (e.g., SMA and UMA)",
+	"MF": "Mutual Funds",
+	"MO": "Mineral/Oil/Gas Royalties",
+	"OI": "Ownership Interest (Holding Investments)",
+	"OL": "Ownership Interest (Engaged in a Trade or Business)",
+	"OP": "Options",
+	"OT": "Other",
+	"PE": "Pensions",
+	"PM": "Precious Metals",
+	"PS": "Stock (Not Publicly Traded)",
+	"RE": "Real Estate Invest. Trust (REIT)",
+	"RP": "Real Property",
+	"RS": "Restricted Stock Units (RSUs)",
+	"SA": "Stock Appreciation Right",
+	"ST": "Stocks (including ADRs)",
+	"TR": "Trust",
+	"VA": "Variable Annuity",
+	"VI": "Variable Insurance",
+	"WU": "Whole/Universal Insurance",
+}
+
+// listedAssetTypes are the asset types that trade under a ticker
+var listedAssetTypes = map[string]bool{
+	"EF": true,
+	"ET": true,
+	"MF": true,
+	"OP": true,
+	"RE": true,
+	"ST": true,
+}
+
+// AssetClass returns the asset class for an asset type code, or an empty string for unknown codes
+func AssetClass(assetType string) string {
+	return assetTypeNames[assetType]
+}
+
+// IsListedAssetType returns true if assets of the type trade under a ticker.
+// An empty asset type is considered listed, the code is often lost on scanned filings.
+func IsListedAssetType(assetType string) bool {
+	return assetType == "" || listedAssetTypes[assetType]
+}
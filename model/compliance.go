@@ -0,0 +1,40 @@
+package model
+
+// ComplianceFinding is the filing delay of a single transaction
+type ComplianceFinding struct {
+	DocId                int             `csv:"docId" json:"docId"`
+	MemberId             string          `csv:"memberId" json:"memberId"`
+	Member               string          `csv:"member" json:"member"`
+	StateDst             string          `csv:"stateDst" json:"stateDst"`
+	Year                 int             `csv:"year" json:"year"`
+	Asset                string          `csv:"asset" json:"asset"`
+	Ticker               string          `csv:"ticker" json:"ticker"`
+	TransactionType      TransactionType `csv:"transactionType" json:"transactionType"`
+	AmountBracket        AmountBracket   `csv:"amountBracket" json:"amountBracket"`
+	TransactionDate      string          `csv:"transactionDate" json:"transactionDate"`
+	NotificationDate     string          `csv:"notificationDate" json:"notificationDate"`
+	FilingDate           string          `csv:"filingDate" json:"filingDate"`
+	DaysAfterTransaction int             `csv:"daysAfterTransaction" json:"daysAfterTransaction"`
+	DaysAfterNotice      int             `csv:"daysAfterNotice" json:"daysAfterNotice"`
+	LateTransaction      bool            `csv:"lateTransaction" json:"lateTransaction"`
+	LateNotice           bool            `csv:"lateNotice" json:"lateNotice"`
+}
+
+// Late returns true if the transaction was reported after either deadline
+func (f *ComplianceFinding) Late() bool {
+	return f.LateTransaction || f.LateNotice
+}
+
+// ComplianceGroup summarizes the late filings of a member in one year
+type ComplianceGroup struct {
+	MemberId                string               `csv:"memberId" json:"memberId"`
+	Member                  string               `csv:"member" json:"member"`
+	StateDst                string               `csv:"stateDst" json:"stateDst"`
+	Year                    int                  `csv:"year" json:"year"`
+	Transactions            int                  `csv:"transactions" json:"transactions"`
+	Late                    int                  `csv:"late" json:"late"`
+	LateTransactions        int                  `csv:"lateTransactions" json:"lateTransactions"`
+	LateNotices             int                  `csv:"lateNotices" json:"lateNotices"`
+	MaxDaysAfterTransaction int                  `csv:"maxDaysAfterTransaction" json:"maxDaysAfterTransaction"`
+	Findings                []*ComplianceFinding `csv:"-" json:"findings"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// dateLayouts are the date formats of the disclosure index and PTR tables, e.g. 1/3/2023 and 01/03/2023
+var dateLayouts = []string{"1/2/2006", "1/2/06", "2006-01-02"}
+
+// ParseDate parses the dates of the disclosure index and PTR tables
+func ParseDate(text string) (time.Time, error) {
+	text = strings.TrimSpace(text)
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, text); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unexpected date %q", text)
+}
@@ -2,9 +2,11 @@ package model
 
 import (
 	"encoding/xml"
+	"fmt"
 	"github.com/paulschick/disclosureupdater/common/constants"
 	"io"
 	"os"
+	"path"
 	"strconv"
 	"strings"
 )
@@ -70,6 +72,47 @@ func (m *Member) BuildPdfFileName() string {
 		"." + last + "." + first + "." + strconv.Itoa(m.DocId) + ".pdf"
 }
 
+// ParsePdfFileName reverses BuildPdfFileName, returning a Member with the year, filing type,
+// state district, names and DocId found in the file name.
+// Names have spaces replaced with underscores and periods removed, the filing type is only known for PTRs.
+func ParsePdfFileName(fileName string) (*Member, error) {
+	parts := strings.Split(strings.TrimSuffix(path.Base(fileName), ".pdf"), ".")
+	if len(parts) != 6 {
+		return nil, fmt.Errorf("unexpected pdf file name %q", fileName)
+	}
+	year, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("unexpected year in pdf file name %q: %w", fileName, err)
+	}
+	docId, err := strconv.Atoi(parts[5])
+	if err != nil {
+		return nil, fmt.Errorf("unexpected doc id in pdf file name %q: %w", fileName, err)
+	}
+	filingType := ""
+	if parts[1] == "ptr-pdfs" {
+		filingType = "P"
+	}
+	return &Member{
+		Year:       year,
+		FilingType: filingType,
+		StateDst:   parts[2],
+		Last:       strings.ReplaceAll(parts[3], "_", " "),
+		First:      strings.ReplaceAll(parts[4], "_", " "),
+		DocId:      docId,
+	}, nil
+}
+
+// FullName returns the member's name as "Prefix First Last Suffix", leaving out empty parts
+func (m *Member) FullName() string {
+	parts := make([]string, 0, 4)
+	for _, part := range []string{m.Prefix, m.First, m.Last, m.Suffix} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
 func (m *Member) BuildPdfFilePath(dataFolder string) string {
 	return dataFolder + "/" + constants.BasePdfDir + m.BuildPdfFileName()
 }
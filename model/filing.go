@@ -0,0 +1,27 @@
+package model
+
+// Filing types of the disclosure index
+const (
+	FilingTypeOriginal  = "O"
+	FilingTypeAmendment = "A"
+	FilingTypePtr       = "P"
+)
+
+// Filing is a disclosure index entry linked to the filing it amends and to the latest version of that filing
+type Filing struct {
+	DocId          int    `csv:"docId" json:"docId"`
+	MemberId       string `csv:"memberId" json:"memberId"`
+	Member         string `csv:"member" json:"member"`
+	StateDst       string `csv:"stateDst" json:"stateDst"`
+	Year           int    `csv:"year" json:"year"`
+	FilingType     string `csv:"filingType" json:"filingType"`
+	FilingDate     string `csv:"filingDate" json:"filingDate"`
+	Amendment      bool   `csv:"amendment" json:"amendment"`
+	AmendsDocId    int    `csv:"amendsDocId" json:"amendsDocId"`
+	EffectiveDocId int    `csv:"effectiveDocId" json:"effectiveDocId"`
+}
+
+// Superseded returns true if a later amendment replaces the filing
+func (f *Filing) Superseded() bool {
+	return f.EffectiveDocId != 0 && f.EffectiveDocId != f.DocId
+}
@@ -0,0 +1,68 @@
+package model
+
+// Pipeline states of a filing, in processing order
+const (
+	PipelineIndexed    = "indexed"
+	PipelineDownloaded = "downloaded"
+	PipelineConverted  = "converted"
+	PipelineOcr        = "ocr"
+	PipelineSearchable = "searchable"
+)
+
+// IndexRecord is a disclosure index entry with the values derived from it, as written by export-index.
+// PageImages and OcrPages count the converted page images and the pages with OCR output,
+// PipelineState is the last processing step completed for the whole filing.
+type IndexRecord struct {
+	DocId         int    `csv:"docId" json:"docId" parquet:"docId"`
+	MemberId      string `csv:"memberId" json:"memberId" parquet:"memberId"`
+	Year          int    `csv:"year" json:"year" parquet:"year"`
+	FilingType    string `csv:"filingType" json:"filingType" parquet:"filingType"`
+	FilingDate    string `csv:"filingDate" json:"filingDate" parquet:"filingDate"`
+	Prefix        string `csv:"prefix" json:"prefix" parquet:"prefix"`
+	First         string `csv:"first" json:"first" parquet:"first"`
+	Last          string `csv:"last" json:"last" parquet:"last"`
+	Suffix        string `csv:"suffix" json:"suffix" parquet:"suffix"`
+	StateDst      string `csv:"stateDst" json:"stateDst" parquet:"stateDst"`
+	PdfUrl        string `csv:"pdfUrl" json:"pdfUrl" parquet:"pdfUrl"`
+	PdfPath       string `csv:"pdfPath" json:"pdfPath" parquet:"pdfPath"`
+	PdfDownloaded bool   `csv:"pdfDownloaded" json:"pdfDownloaded" parquet:"pdfDownloaded"`
+	PageImages    int    `csv:"pageImages" json:"pageImages" parquet:"pageImages"`
+	OcrPages      int    `csv:"ocrPages" json:"ocrPages" parquet:"ocrPages"`
+	Searchable    bool   `csv:"searchable" json:"searchable" parquet:"searchable"`
+	Transactions  int    `csv:"transactions" json:"transactions" parquet:"transactions"`
+	PipelineState string `csv:"pipelineState" json:"pipelineState" parquet:"pipelineState"`
+}
+
+// NewIndexRecord returns the record of a disclosure index entry, without the pipeline state
+func NewIndexRecord(m *Member) *IndexRecord {
+	return &IndexRecord{
+		DocId:         m.DocId,
+		Year:          m.Year,
+		FilingType:    m.FilingType,
+		FilingDate:    m.FilingDate,
+		Prefix:        m.Prefix,
+		First:         m.First,
+		Last:          m.Last,
+		Suffix:        m.Suffix,
+		StateDst:      m.StateDst,
+		PdfUrl:        m.BuildPdfUrl(),
+		PipelineState: PipelineIndexed,
+	}
+}
+
+// SetPipelineState sets PipelineState from the pipeline fields.
+// A filing is converted once it has page images and OCR'd once every page image has OCR output.
+func (r *IndexRecord) SetPipelineState() {
+	switch {
+	case r.Searchable:
+		r.PipelineState = PipelineSearchable
+	case r.PageImages > 0 && r.OcrPages >= r.PageImages:
+		r.PipelineState = PipelineOcr
+	case r.PageImages > 0:
+		r.PipelineState = PipelineConverted
+	case r.PdfDownloaded:
+		r.PipelineState = PipelineDownloaded
+	default:
+		r.PipelineState = PipelineIndexed
+	}
+}
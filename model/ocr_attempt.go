@@ -0,0 +1,16 @@
+package model
+
+// OcrAttempt is one OCR run over a page image recorded in the OCR catalog.
+// Pages below the confidence threshold get several attempts, Kept marks the one whose output was written.
+type OcrAttempt struct {
+	Image          string  `csv:"image"`
+	Attempt        int     `csv:"attempt"`
+	Name           string  `csv:"name"`
+	Dpi            float64 `csv:"dpi"`
+	Psm            int     `csv:"psm"`
+	Preprocess     string  `csv:"preprocess"`
+	Words          int     `csv:"words"`
+	MeanConfidence float64 `csv:"meanConfidence"`
+	Kept           bool    `csv:"kept"`
+	CreatedAt      string  `csv:"createdAt"`
+}
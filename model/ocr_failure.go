@@ -0,0 +1,10 @@
+package model
+
+// OcrFailure is an entry in the OCR failure ledger for an image that could not be processed
+type OcrFailure struct {
+	ImagePath    string `csv:"imagePath"`
+	Error        string `csv:"error"`
+	Attempts     int    `csv:"attempts"`
+	FirstFailure string `csv:"firstFailure"`
+	LastFailure  string `csv:"lastFailure"`
+}
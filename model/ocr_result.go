@@ -10,6 +10,12 @@ type OcrResult struct {
 	WordNum    int     `csv:"wordNum"`
 	Word       string  `csv:"word"`
 	Confidence float64 `csv:"confidence"`
+	BlockNum   int     `csv:"blockNum"`
+	ParNum     int     `csv:"parNum"`
+	Left       int     `csv:"left"`
+	Top        int     `csv:"top"`
+	Right      int     `csv:"right"`
+	Bottom     int     `csv:"bottom"`
 }
 
 func NewOcrResult(box gosseract.BoundingBox) *OcrResult {
@@ -18,5 +24,39 @@ func NewOcrResult(box gosseract.BoundingBox) *OcrResult {
 		WordNum:    box.WordNum,
 		Word:       strings.ReplaceAll(strings.ReplaceAll(box.Word, "\n", ""), " ", ""),
 		Confidence: box.Confidence,
+		BlockNum:   box.BlockNum,
+		ParNum:     box.ParNum,
+		Left:       box.Box.Min.X,
+		Top:        box.Box.Min.Y,
+		Right:      box.Box.Max.X,
+		Bottom:     box.Box.Max.Y,
 	}
 }
+
+// Width returns the width of the word bounding box in pixels
+func (o *OcrResult) Width() int {
+	return o.Right - o.Left
+}
+
+// Height returns the height of the word bounding box in pixels
+func (o *OcrResult) Height() int {
+	return o.Bottom - o.Top
+}
+
+// MeanConfidence returns the mean confidence of the recognized words.
+// Empty words and the negative confidence Tesseract reports for non-word boxes are ignored.
+func MeanConfidence(results []*OcrResult) float64 {
+	var total float64
+	count := 0
+	for _, result := range results {
+		if result.Word == "" || result.Confidence < 0 {
+			continue
+		}
+		total += result.Confidence
+		count++
+	}
+	if count == 0 {
+		return 0
+	}
+	return total / float64(count)
+}
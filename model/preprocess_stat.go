@@ -0,0 +1,13 @@
+package model
+
+// PreprocessStat compares OCR on the raw page image with OCR on the preprocessed image
+type PreprocessStat struct {
+	Image            string  `csv:"image"`
+	Steps            string  `csv:"steps"`
+	Threshold        int     `csv:"threshold"`
+	SkewAngle        float64 `csv:"skewAngle"`
+	WordsBefore      int     `csv:"wordsBefore"`
+	ConfidenceBefore float64 `csv:"confidenceBefore"`
+	WordsAfter       int     `csv:"wordsAfter"`
+	ConfidenceAfter  float64 `csv:"confidenceAfter"`
+}
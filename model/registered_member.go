@@ -0,0 +1,28 @@
+package model
+
+// RegisteredMember is a person in the member registry, with every name variant and district they filed under.
+// Name variants, districts and DocIds are separated by semicolons.
+type RegisteredMember struct {
+	MemberId  string `csv:"memberId" json:"memberId"`
+	Name      string `csv:"name" json:"name"`
+	Last      string `csv:"last" json:"last"`
+	First     string `csv:"first" json:"first"`
+	Suffix    string `csv:"suffix" json:"suffix"`
+	StateDsts string `csv:"stateDsts" json:"stateDsts"`
+	FirstYear int    `csv:"firstYear" json:"firstYear"`
+	LastYear  int    `csv:"lastYear" json:"lastYear"`
+	Filings   int    `csv:"filings" json:"filings"`
+	Variants  string `csv:"variants" json:"variants"`
+	DocIds    string `csv:"docIds" json:"docIds"`
+}
+
+// MemberOverride assigns filings to a member ID. An override matches a filing by DocId, or by last name,
+// first name and state district when DocId is 0. Empty names match any name and StateDst matches
+// as a prefix, so "CA" matches every California district.
+type MemberOverride struct {
+	MemberId string `csv:"memberId"`
+	DocId    int    `csv:"docId"`
+	Last     string `csv:"last"`
+	First    string `csv:"first"`
+	StateDst string `csv:"stateDst"`
+}
@@ -4,6 +4,13 @@ type S3Profile interface {
 	GetBucket() string
 	GetRegion() string
 	GetHostname() string
+	GetKeyPrefix() string
+	GetKeyTemplate() string
+	GetClass(name string) *S3ClassConfig
+	GetUpload() S3UploadConfig
+	GetAwsProfile() string
+	GetAssumeRole() *S3AssumeRoleConfig
+	GetUsePathStyle() bool
 	StaticAuthentication() bool
 }
 
@@ -13,6 +20,49 @@ type S3DefaultProfile struct {
 	S3Bucket   string
 	S3Region   string
 	S3Hostname string
+	// S3KeyPrefix is prepended to every object key
+	S3KeyPrefix string
+	// S3KeyTemplate builds the object keys of disclosure PDFs, e.g. {year}/{filingType}/{docId}.pdf
+	S3KeyTemplate string
+	// S3Classes overrides the settings of artifact classes by class name, e.g. images
+	S3Classes map[string]*S3ClassConfig
+	// S3Upload configures the transfer manager uploads
+	S3Upload S3UploadConfig
+	// AwsProfile is the profile of the ~/.aws credentials and config files, default if empty
+	AwsProfile string
+	// AssumeRole is the role assumed with the credentials of the profile
+	AssumeRole S3AssumeRoleConfig
+	// UsePathStyle addresses the bucket in the path instead of the host name, as most self-hosted stores require
+	UsePathStyle bool
+}
+
+// S3AssumeRoleConfig is the ARN of a role to assume, with the optional external ID and session name
+type S3AssumeRoleConfig struct {
+	RoleArn     string
+	ExternalId  string
+	SessionName string
+}
+
+// S3UploadConfig is the part size in MiB, number of parts uploaded at the same time, checksum algorithm
+// and maximum number of attempts of each request of an upload. Zero values keep the defaults.
+// Encryption is the server-side encryption of uploaded objects, sse-s3 or sse-kms with the optional KmsKeyId,
+// the default encryption of the bucket if empty. Tags are added to every uploaded object.
+type S3UploadConfig struct {
+	PartSize    int64
+	Concurrency int
+	Checksum    string
+	MaxAttempts int
+	Encryption  string
+	KmsKeyId    string
+	Tags        map[string]string
+}
+
+// S3ClassConfig is the object key prefix, content type and storage class of an artifact class.
+// Empty values keep the defaults of the class.
+type S3ClassConfig struct {
+	Prefix       string
+	ContentType  string
+	StorageClass string
 }
 
 func (s *S3DefaultProfile) GetBucket() string {
@@ -27,6 +77,39 @@ func (s *S3DefaultProfile) GetHostname() string {
 	return s.S3Hostname
 }
 
+func (s *S3DefaultProfile) GetKeyPrefix() string {
+	return s.S3KeyPrefix
+}
+
+func (s *S3DefaultProfile) GetKeyTemplate() string {
+	return s.S3KeyTemplate
+}
+
+// GetClass returns the settings of the artifact class, nil if the class isn't configured
+func (s *S3DefaultProfile) GetClass(name string) *S3ClassConfig {
+	return s.S3Classes[name]
+}
+
+func (s *S3DefaultProfile) GetUpload() S3UploadConfig {
+	return s.S3Upload
+}
+
+func (s *S3DefaultProfile) GetAwsProfile() string {
+	return s.AwsProfile
+}
+
+// GetAssumeRole returns nil if no role is configured
+func (s *S3DefaultProfile) GetAssumeRole() *S3AssumeRoleConfig {
+	if s.AssumeRole.RoleArn == "" {
+		return nil
+	}
+	return &s.AssumeRole
+}
+
+func (s *S3DefaultProfile) GetUsePathStyle() bool {
+	return s.UsePathStyle
+}
+
 func (s *S3DefaultProfile) StaticAuthentication() bool {
 	return false
 }
@@ -0,0 +1,8 @@
+package model
+
+// Security is an entry of the security master used to resolve tickers from asset names
+type Security struct {
+	Ticker    string `csv:"ticker"`
+	Name      string `csv:"name"`
+	AssetType string `csv:"assetType"`
+}
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Extraction methods recorded on each Transaction
+const (
+	ExtractionOcr  = "ocr"
+	ExtractionText = "text"
+)
+
+// Sources of the ticker recorded on each Transaction
+const (
+	TickerFromAsset  = "asset"
+	TickerFromMaster = "master"
+)
+
+// Filing status of a transaction, amended transactions correct an earlier PTR
+const (
+	FilingStatusNew     = "New"
+	FilingStatusAmended = "Amended"
+)
+
+// OwnerCode identifies who owns the traded asset, it is empty when the filer owns it
+type OwnerCode string
+
+const (
+	OwnerSelf      OwnerCode = ""
+	OwnerSpouse    OwnerCode = "SP"
+	OwnerJoint     OwnerCode = "JT"
+	OwnerDependent OwnerCode = "DC"
+)
+
+// TransactionType is the kind of trade as printed in the Transaction Type column
+type TransactionType string
+
+const (
+	TransactionPurchase    TransactionType = "P"
+	TransactionSale        TransactionType = "S"
+	TransactionPartialSale TransactionType = "S (partial)"
+	TransactionExchange    TransactionType = "E"
+)
+
+var partialSalePattern = regexp.MustCompile(`^S\s*\(\s*partial\s*\)$`)
+
+// ParseOwnerCode returns the owner code for the text of the Owner column
+func ParseOwnerCode(text string) (OwnerCode, bool) {
+	switch code := OwnerCode(strings.ToUpper(strings.TrimSpace(text))); code {
+	case OwnerSelf, OwnerSpouse, OwnerJoint, OwnerDependent:
+		return code, true
+	}
+	return OwnerCode(text), false
+}
+
+// ParseTransactionType returns the transaction type for the text of the Transaction Type column
+func ParseTransactionType(text string) (TransactionType, bool) {
+	text = strings.TrimSpace(text)
+	switch {
+	case text == "P":
+		return TransactionPurchase, true
+	case text == "S":
+		return TransactionSale, true
+	case partialSalePattern.MatchString(text):
+		return TransactionPartialSale, true
+	case text == "E":
+		return TransactionExchange, true
+	}
+	return TransactionType(text), false
+}
+
+// Transaction is a single trade reported on a periodic transaction report (PTR)
+type Transaction struct {
+	DocId            int             `csv:"docId" json:"docId"`
+	MemberId         string          `csv:"memberId" json:"memberId"`
+	SourcePdf        string          `csv:"sourcePdf" json:"sourcePdf"`
+	Page             int             `csv:"page" json:"page"`
+	Method           string          `csv:"method" json:"method"`
+	Owner            OwnerCode       `csv:"owner" json:"owner"`
+	Asset            string          `csv:"asset" json:"asset"`
+	Ticker           string          `csv:"ticker" json:"ticker"`
+	AssetType        string          `csv:"assetType" json:"assetType"`
+	AssetClass       string          `csv:"assetClass" json:"assetClass"`
+	TickerSource     string          `csv:"tickerSource" json:"tickerSource"`
+	TickerConfidence float64         `csv:"tickerConfidence" json:"tickerConfidence"`
+	TransactionType  TransactionType `csv:"transactionType" json:"transactionType"`
+	Date             string          `csv:"date" json:"date"`
+	NotificationDate string          `csv:"notificationDate" json:"notificationDate"`
+	Amount           string          `csv:"amount" json:"amount"`
+	AmountBracket    AmountBracket   `csv:"amountBracket" json:"amountBracket"`
+	AmountMin        int64           `csv:"amountMin" json:"amountMin"`
+	AmountMax        int64           `csv:"amountMax" json:"amountMax"`
+	FilingStatus     string          `csv:"filingStatus" json:"filingStatus"`
+	Confidence       float64         `csv:"confidence" json:"confidence"`
+	ParserVersion    string          `csv:"parserVersion" json:"parserVersion"`
+}
+
+// SetAmount sets the raw amount text and the bracket it normalizes to
+func (t *Transaction) SetAmount(text string) {
+	t.Amount = text
+	t.AmountBracket = ParseAmount(text)
+	t.AmountMin = t.AmountBracket.Min()
+	t.AmountMax = t.AmountBracket.Max()
+}
@@ -0,0 +1,77 @@
+package model
+
+// TransactionRecord is a parsed transaction as written by export-transactions.
+// Dates are ISO 8601 when they can be parsed and the amount bracket is its canonical label.
+// The provenance columns trace every record back to the page it was read from: the PDF, the page number
+// (starting at 0, as in page image names), the page image if the PDF was converted, the extraction method
+// (text layer or OCR), the extraction confidence and the version of the parser.
+type TransactionRecord struct {
+	DocId            int     `csv:"docId" json:"docId" parquet:"docId"`
+	MemberId         string  `csv:"memberId" json:"memberId" parquet:"memberId"`
+	Year             int     `csv:"year" json:"year" parquet:"year"`
+	FilingDate       string  `csv:"filingDate" json:"filingDate" parquet:"filingDate"`
+	FilingStatus     string  `csv:"filingStatus" json:"filingStatus" parquet:"filingStatus"`
+	Owner            string  `csv:"owner" json:"owner" parquet:"owner"`
+	Asset            string  `csv:"asset" json:"asset" parquet:"asset"`
+	Ticker           string  `csv:"ticker" json:"ticker" parquet:"ticker"`
+	AssetType        string  `csv:"assetType" json:"assetType" parquet:"assetType"`
+	AssetClass       string  `csv:"assetClass" json:"assetClass" parquet:"assetClass"`
+	TickerSource     string  `csv:"tickerSource" json:"tickerSource" parquet:"tickerSource"`
+	TickerConfidence float64 `csv:"tickerConfidence" json:"tickerConfidence" parquet:"tickerConfidence"`
+	TransactionType  string  `csv:"transactionType" json:"transactionType" parquet:"transactionType"`
+	Date             string  `csv:"date" json:"date" parquet:"date"`
+	NotificationDate string  `csv:"notificationDate" json:"notificationDate" parquet:"notificationDate"`
+	Amount           string  `csv:"amount" json:"amount" parquet:"amount"`
+	AmountBracket    string  `csv:"amountBracket" json:"amountBracket" parquet:"amountBracket"`
+	AmountMin        int64   `csv:"amountMin" json:"amountMin" parquet:"amountMin"`
+	AmountMax        int64   `csv:"amountMax" json:"amountMax" parquet:"amountMax"`
+	SourcePdf        string  `csv:"sourcePdf" json:"sourcePdf" parquet:"sourcePdf"`
+	Page             int     `csv:"page" json:"page" parquet:"page"`
+	PageImage        string  `csv:"pageImage" json:"pageImage" parquet:"pageImage"`
+	Method           string  `csv:"method" json:"method" parquet:"method"`
+	Confidence       float64 `csv:"confidence" json:"confidence" parquet:"confidence"`
+	ParserVersion    string  `csv:"parserVersion" json:"parserVersion" parquet:"parserVersion"`
+}
+
+// NewTransactionRecord returns the record of a transaction. The member is the disclosure index entry of the
+// transaction's filing, the filing year and date are left empty if it's nil.
+func NewTransactionRecord(t *Transaction, m *Member) *TransactionRecord {
+	record := &TransactionRecord{
+		DocId:            t.DocId,
+		MemberId:         t.MemberId,
+		FilingStatus:     t.FilingStatus,
+		Owner:            string(t.Owner),
+		Asset:            t.Asset,
+		Ticker:           t.Ticker,
+		AssetType:        t.AssetType,
+		AssetClass:       t.AssetClass,
+		TickerSource:     t.TickerSource,
+		TickerConfidence: t.TickerConfidence,
+		TransactionType:  string(t.TransactionType),
+		Date:             isoDate(t.Date),
+		NotificationDate: isoDate(t.NotificationDate),
+		Amount:           t.Amount,
+		AmountBracket:    t.AmountBracket.String(),
+		AmountMin:        t.AmountMin,
+		AmountMax:        t.AmountMax,
+		SourcePdf:        t.SourcePdf,
+		Page:             t.Page,
+		Method:           t.Method,
+		Confidence:       t.Confidence,
+		ParserVersion:    t.ParserVersion,
+	}
+	if m != nil {
+		record.Year = m.Year
+		record.FilingDate = isoDate(m.FilingDate)
+	}
+	return record
+}
+
+// isoDate returns the date as YYYY-MM-DD, or the text as is if it isn't a date
+func isoDate(text string) string {
+	date, err := ParseDate(text)
+	if err != nil {
+		return text
+	}
+	return date.Format("2006-01-02")
+}
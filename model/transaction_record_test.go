@@ -0,0 +1,44 @@
+package model
+
+import "testing"
+
+func TestNewTransactionRecord(t *testing.T) {
+	transaction := &Transaction{
+		DocId:            20012345,
+		SourcePdf:        "2023.ptr-pdfs.CA12.Doe.Jane.20012345.pdf",
+		Page:             1,
+		Method:           ExtractionOcr,
+		Owner:            OwnerSpouse,
+		TransactionType:  TransactionPartialSale,
+		Date:             "1/3/2023",
+		NotificationDate: "01/O3/2023",
+		AmountBracket:    Amount1001To15000,
+		Confidence:       87.5,
+		ParserVersion:    "1.0.0",
+	}
+	member := &Member{DocId: 20012345, Year: 2023, FilingDate: "2/1/2023"}
+
+	record := NewTransactionRecord(transaction, member)
+	expected := TransactionRecord{
+		DocId:            20012345,
+		Year:             2023,
+		FilingDate:       "2023-02-01",
+		Owner:            "SP",
+		TransactionType:  "S (partial)",
+		Date:             "2023-01-03",
+		NotificationDate: "01/O3/2023",
+		AmountBracket:    "$1,001 - $15,000",
+		SourcePdf:        "2023.ptr-pdfs.CA12.Doe.Jane.20012345.pdf",
+		Page:             1,
+		Method:           ExtractionOcr,
+		Confidence:       87.5,
+		ParserVersion:    "1.0.0",
+	}
+	if *record != expected {
+		t.Errorf("NewTransactionRecord() = %+v; want %+v", *record, expected)
+	}
+
+	if record = NewTransactionRecord(transaction, nil); record.Year != 0 || record.FilingDate != "" {
+		t.Errorf("NewTransactionRecord() without member = %+v; want no filing year and date", *record)
+	}
+}
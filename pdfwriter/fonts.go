@@ -0,0 +1,29 @@
+package pdfwriter
+
+// helveticaWidths are the Helvetica glyph widths in 1/1000 em for the printable ASCII range 0x20 - 0x7e.
+// Source: the Adobe Helvetica AFM metrics for the standard 14 fonts.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// helveticaDefaultWidth is used for characters outside of the printable ASCII range
+const helveticaDefaultWidth = 556
+
+// helveticaWidth returns the width of the WinAnsi encoded text in em at a font size of 1
+func helveticaWidth(text string) float64 {
+	total := 0
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c >= 0x20 && c <= 0x7e {
+			total += helveticaWidths[c-0x20]
+		} else {
+			total += helveticaDefaultWidth
+		}
+	}
+	return float64(total) / 1000
+}
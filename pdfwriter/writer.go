@@ -0,0 +1,212 @@
+package pdfwriter
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/paulschick/disclosureupdater/model"
+	"image"
+	"image/jpeg"
+	"io"
+	"strings"
+)
+
+// JpegQuality is the quality used when embedding page images
+const JpegQuality = 85
+
+// Document is a minimal PDF writer for image-only pages with an invisible text layer.
+// Each page is a full-page image with the OCR words drawn over it in text render mode 3
+// (neither fill nor stroke), so the text is selectable and searchable but not visible.
+type Document struct {
+	pages []*page
+}
+
+type page struct {
+	width  float64
+	height float64
+	image  image.Image
+	words  []*model.OcrResult
+}
+
+func NewDocument() *Document {
+	return &Document{
+		pages: make([]*page, 0),
+	}
+}
+
+// AddPage adds a page of width x height points.
+// The image is stretched to cover the page and the word boxes are given in image pixel coordinates.
+func (d *Document) AddPage(width, height float64, img image.Image, words []*model.OcrResult) {
+	d.pages = append(d.pages, &page{
+		width:  width,
+		height: height,
+		image:  img,
+		words:  words,
+	})
+}
+
+// NumPage returns the number of pages added to the document
+func (d *Document) NumPage() int {
+	return len(d.pages)
+}
+
+// WriteTo writes the PDF to w
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	pw := &pdfWriter{w: bufio.NewWriter(w)}
+	pw.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+
+	// object numbers: 1 catalog, 2 pages, 3 font, then 3 objects per page
+	const firstPageObj = 4
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPageObj+i*3)
+	}
+
+	pw.object(1, "<< /Type /Catalog /Pages 2 0 R >>")
+	pw.object(2, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	pw.object(3, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+
+	for i, p := range d.pages {
+		pageObj := firstPageObj + i*3
+		contentObj := pageObj + 1
+		imageObj := pageObj + 2
+
+		imgData, colorSpace, err := encodeJpeg(p.image)
+		if err != nil {
+			return pw.n, err
+		}
+		bounds := p.image.Bounds()
+
+		pw.object(pageObj, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] "+
+			"/Resources << /Font << /F1 3 0 R >> /XObject << /Im0 %d 0 R >> >> /Contents %d 0 R >>",
+			num(p.width), num(p.height), imageObj, contentObj))
+		pw.stream(contentObj, "", p.content())
+		pw.stream(imageObj, fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d "+
+			"/ColorSpace /%s /BitsPerComponent 8 /Filter /DCTDecode ",
+			bounds.Dx(), bounds.Dy(), colorSpace), imgData)
+	}
+
+	xrefOffset := pw.n
+	objCount := firstPageObj + len(d.pages)*3
+	pw.printf("xref\n0 %d\n0000000000 65535 f \n", objCount)
+	for i := 1; i < objCount; i++ {
+		pw.printf("%010d 00000 n \n", pw.offsets[i])
+	}
+	pw.printf("trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", objCount, xrefOffset)
+
+	if pw.err != nil {
+		return pw.n, pw.err
+	}
+	return pw.n, pw.w.Flush()
+}
+
+// content builds the page content stream, drawing the image and then the invisible text
+func (p *page) content() []byte {
+	var buf bytes.Buffer
+	bounds := p.image.Bounds()
+	scaleX := p.width / float64(bounds.Dx())
+	scaleY := p.height / float64(bounds.Dy())
+
+	fmt.Fprintf(&buf, "q %s 0 0 %s 0 0 cm /Im0 Do Q\n", num(p.width), num(p.height))
+	buf.WriteString("BT 3 Tr\n")
+	for _, word := range p.words {
+		text := winAnsi(word.Word)
+		if text == "" || word.Width() <= 0 || word.Height() <= 0 {
+			continue
+		}
+		size := float64(word.Height()) * scaleY
+		textWidth := helveticaWidth(text) * size
+		if textWidth <= 0 {
+			continue
+		}
+		hScale := 100 * float64(word.Width()) * scaleX / textWidth
+		x := float64(word.Left-bounds.Min.X) * scaleX
+		y := p.height - float64(word.Bottom-bounds.Min.Y)*scaleY
+		fmt.Fprintf(&buf, "/F1 %s Tf %s Tz 1 0 0 1 %s %s Tm (%s) Tj\n",
+			num(size), num(hScale), num(x), num(y), escape(text))
+	}
+	buf.WriteString("ET\n")
+	return buf.Bytes()
+}
+
+func encodeJpeg(img image.Image) ([]byte, string, error) {
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: JpegQuality})
+	if err != nil {
+		return nil, "", err
+	}
+	colorSpace := "DeviceRGB"
+	if _, ok := img.(*image.Gray); ok {
+		colorSpace = "DeviceGray"
+	}
+	return buf.Bytes(), colorSpace, nil
+}
+
+// winAnsi converts text to the single byte WinAnsi encoding used by the standard fonts.
+// Characters outside of Latin-1 are dropped.
+func winAnsi(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if r >= 0x20 && r <= 0xff && r != 0x7f {
+			b.WriteByte(byte(r))
+		}
+	}
+	return b.String()
+}
+
+func escape(text string) string {
+	r := strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`)
+	return r.Replace(text)
+}
+
+// num formats a float without trailing zeros for use in PDF operators
+func num(f float64) string {
+	s := fmt.Sprintf("%.3f", f)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+type pdfWriter struct {
+	w       *bufio.Writer
+	n       int64
+	err     error
+	offsets map[int]int64
+}
+
+func (pw *pdfWriter) printf(format string, args ...interface{}) {
+	if pw.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(pw.w, format, args...)
+	pw.n += int64(n)
+	pw.err = err
+}
+
+func (pw *pdfWriter) write(b []byte) {
+	if pw.err != nil {
+		return
+	}
+	n, err := pw.w.Write(b)
+	pw.n += int64(n)
+	pw.err = err
+}
+
+func (pw *pdfWriter) startObject(id int) {
+	if pw.offsets == nil {
+		pw.offsets = make(map[int]int64)
+	}
+	pw.offsets[id] = pw.n
+	pw.printf("%d 0 obj\n", id)
+}
+
+func (pw *pdfWriter) object(id int, body string) {
+	pw.startObject(id)
+	pw.printf("%s\nendobj\n", body)
+}
+
+func (pw *pdfWriter) stream(id int, dict string, data []byte) {
+	pw.startObject(id)
+	pw.printf("<< %s/Length %d >>\nstream\n", dict, len(data))
+	pw.write(data)
+	pw.printf("\nendstream\nendobj\n")
+}
@@ -0,0 +1,80 @@
+package pdfwriter
+
+import (
+	"bytes"
+	"github.com/gen2brain/go-fitz"
+	"github.com/paulschick/disclosureupdater/model"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestDocument_WriteTo(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 850, 1100))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	img.SetGray(10, 10, color.Gray{})
+	words := []*model.OcrResult{
+		{Word: "Apple", Left: 100, Top: 100, Right: 200, Bottom: 130},
+		{Word: "(AAPL)", Left: 210, Top: 100, Right: 320, Bottom: 130},
+		{Word: "", Left: 0, Top: 0, Right: 0, Bottom: 0},
+	}
+
+	doc := NewDocument()
+	doc.AddPage(612, 792, img, words)
+	doc.AddPage(612, 792, img, nil)
+
+	var buf bytes.Buffer
+	n, err := doc.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteTo() = %d; want %d", n, buf.Len())
+	}
+
+	pdf, err := fitz.NewFromMemory(buf.Bytes())
+	if err != nil {
+		t.Fatalf("fitz.NewFromMemory() error = %v", err)
+	}
+	defer func() {
+		_ = pdf.Close()
+	}()
+	if pdf.NumPage() != 2 {
+		t.Fatalf("NumPage() = %d; want 2", pdf.NumPage())
+	}
+	bounds, err := pdf.Bound(0)
+	if err != nil {
+		t.Fatalf("Bound() error = %v", err)
+	}
+	if bounds.Dx() != 612 || bounds.Dy() != 792 {
+		t.Errorf("Bound() = %v; want 612x792", bounds)
+	}
+	text, err := pdf.Text(0)
+	if err != nil {
+		t.Fatalf("Text() error = %v", err)
+	}
+	if !strings.Contains(text, "Apple") || !strings.Contains(text, "(AAPL)") {
+		t.Errorf("Text() = %q; want it to contain the OCR words", text)
+	}
+}
+
+func TestNum(t *testing.T) {
+	tests := []struct {
+		input    float64
+		expected string
+	}{
+		{612, "612"},
+		{12.5, "12.5"},
+		{0.1234, "0.123"},
+		{0, "0"},
+	}
+
+	for _, tt := range tests {
+		if got := num(tt.input); got != tt.expected {
+			t.Errorf("num(%v) = %q; want %q", tt.input, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package preprocess
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"strings"
+)
+
+// Step names used to toggle individual preprocessing steps
+const (
+	StepGrayscale = "grayscale"
+	StepBinarize  = "binarize"
+	StepDeskew    = "deskew"
+	StepBorder    = "border"
+	StepDespeckle = "despeckle"
+	StepAll       = "all"
+)
+
+// AllSteps lists the steps in the order they are applied
+var AllSteps = []string{StepGrayscale, StepBinarize, StepDeskew, StepBorder, StepDespeckle}
+
+// Options configures which steps run and their parameters
+type Options struct {
+	Grayscale    bool
+	Binarize     bool
+	Deskew       bool
+	RemoveBorder bool
+	Despeckle    bool
+
+	// MaxSkewAngle is the largest skew in degrees that deskew searches for in either direction
+	MaxSkewAngle float64
+	// SkewAngleStep is the angle increment in degrees between deskew candidates
+	SkewAngleStep float64
+	// BorderDarkRatio is the fraction of dark pixels that marks an edge row or column as border
+	BorderDarkRatio float64
+	// DespeckleSize is the largest connected dark component in pixels removed as noise
+	DespeckleSize int
+}
+
+// DefaultOptions returns options with every step disabled and default parameters
+func DefaultOptions() *Options {
+	return &Options{
+		MaxSkewAngle:    5,
+		SkewAngleStep:   0.25,
+		BorderDarkRatio: 0.5,
+		DespeckleSize:   4,
+	}
+}
+
+// OptionsFromSteps returns default options with the named steps enabled
+func OptionsFromSteps(steps []string) (*Options, error) {
+	opts := DefaultOptions()
+	for _, step := range steps {
+		switch strings.ToLower(strings.TrimSpace(step)) {
+		case StepGrayscale:
+			opts.Grayscale = true
+		case StepBinarize:
+			opts.Binarize = true
+		case StepDeskew:
+			opts.Deskew = true
+		case StepBorder:
+			opts.RemoveBorder = true
+		case StepDespeckle:
+			opts.Despeckle = true
+		case StepAll:
+			opts.Grayscale = true
+			opts.Binarize = true
+			opts.Deskew = true
+			opts.RemoveBorder = true
+			opts.Despeckle = true
+		default:
+			return nil, fmt.Errorf("unsupported preprocessing step %q", step)
+		}
+	}
+	return opts, nil
+}
+
+// Enabled returns true if any step is enabled
+func (o *Options) Enabled() bool {
+	return o.Grayscale || o.Binarize || o.Deskew || o.RemoveBorder || o.Despeckle
+}
+
+// Steps returns the names of the enabled steps in the order they are applied
+func (o *Options) Steps() []string {
+	steps := make([]string, 0, len(AllSteps))
+	enabled := map[string]bool{
+		StepGrayscale: o.Grayscale,
+		StepBinarize:  o.Binarize,
+		StepDeskew:    o.Deskew,
+		StepBorder:    o.RemoveBorder,
+		StepDespeckle: o.Despeckle,
+	}
+	for _, step := range AllSteps {
+		if enabled[step] {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// Report describes what the pipeline did to a single image
+type Report struct {
+	Steps     []string
+	Threshold uint8
+	SkewAngle float64
+}
+
+// Pipeline applies the enabled preprocessing steps to page images before OCR.
+// Binarize works on grayscale, so the image is converted to grayscale when the grayscale or binarize step is enabled.
+// Otherwise the colours are kept and deskew, border and despeckle detect their changes on a grayscale copy.
+type Pipeline struct {
+	Options *Options
+}
+
+func NewPipeline(opts *Options) *Pipeline {
+	return &Pipeline{
+		Options: opts,
+	}
+}
+
+// Apply runs the enabled steps on the image
+func (p *Pipeline) Apply(img image.Image) (image.Image, *Report) {
+	report := &Report{
+		Steps: p.Options.Steps(),
+	}
+	if !p.Options.Grayscale && !p.Options.Binarize {
+		return p.applyColor(img, report), report
+	}
+	gray := Grayscale(img)
+	if p.Options.Binarize {
+		report.Threshold = OtsuThreshold(gray)
+		gray = Binarize(gray, report.Threshold)
+	}
+	if p.Options.Deskew {
+		gray, report.SkewAngle = Deskew(gray, p.Options.MaxSkewAngle, p.Options.SkewAngleStep)
+	}
+	if p.Options.RemoveBorder {
+		gray = RemoveBorder(gray, p.Options.BorderDarkRatio)
+	}
+	if p.Options.Despeckle {
+		gray = Despeckle(gray, p.Options.DespeckleSize)
+	}
+	return gray, report
+}
+
+// applyColor runs deskew, border and despeckle on a copy of the image that keeps its colours
+func (p *Pipeline) applyColor(img image.Image, report *Report) image.Image {
+	out := RGBA(img)
+	if p.Options.Deskew {
+		report.SkewAngle = DetectSkew(Grayscale(out), p.Options.MaxSkewAngle, p.Options.SkewAngleStep)
+		if report.SkewAngle != 0 {
+			out = RotateRGBA(out, report.SkewAngle)
+		}
+	}
+	if p.Options.RemoveBorder {
+		gray := Grayscale(out)
+		whitenChanged(out, gray, RemoveBorder(gray, p.Options.BorderDarkRatio))
+	}
+	if p.Options.Despeckle {
+		gray := Grayscale(out)
+		whitenChanged(out, gray, Despeckle(gray, p.Options.DespeckleSize))
+	}
+	return out
+}
+
+// ApplyPng runs the pipeline and returns the result PNG encoded, ready to be passed to Tesseract
+func (p *Pipeline) ApplyPng(img image.Image) ([]byte, *Report, error) {
+	processed, report := p.Apply(img)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, processed); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), report, nil
+}
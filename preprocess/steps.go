@@ -0,0 +1,305 @@
+package preprocess
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+const (
+	white = 0xff
+	black = 0x00
+)
+
+// Grayscale converts any image to an 8-bit grayscale image with bounds starting at 0, 0
+func Grayscale(img image.Image) *image.Gray {
+	bounds := img.Bounds()
+	gray := image.NewGray(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(gray, gray.Bounds(), img, bounds.Min, draw.Src)
+	return gray
+}
+
+// RGBA copies any image to an RGBA image with bounds starting at 0, 0
+func RGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
+
+// OtsuThreshold returns the threshold that maximizes the between-class variance
+// of the grayscale histogram
+func OtsuThreshold(gray *image.Gray) uint8 {
+	var histogram [256]int
+	for _, p := range gray.Pix {
+		histogram[p]++
+	}
+	total := len(gray.Pix)
+	if total == 0 {
+		return 127
+	}
+
+	var sum float64
+	for i, count := range histogram {
+		sum += float64(i) * float64(count)
+	}
+
+	var sumBackground float64
+	var weightBackground int
+	var maxVariance float64
+	var threshold uint8
+	for i, count := range histogram {
+		weightBackground += count
+		if weightBackground == 0 {
+			continue
+		}
+		weightForeground := total - weightBackground
+		if weightForeground == 0 {
+			break
+		}
+		sumBackground += float64(i) * float64(count)
+		meanBackground := sumBackground / float64(weightBackground)
+		meanForeground := (sum - sumBackground) / float64(weightForeground)
+		diff := meanBackground - meanForeground
+		variance := float64(weightBackground) * float64(weightForeground) * diff * diff
+		if variance > maxVariance {
+			maxVariance = variance
+			threshold = uint8(i)
+		}
+	}
+	return threshold
+}
+
+// Binarize sets every pixel at or below the threshold to black and every other pixel to white
+func Binarize(gray *image.Gray, threshold uint8) *image.Gray {
+	out := image.NewGray(gray.Bounds())
+	for i, p := range gray.Pix {
+		if p <= threshold {
+			out.Pix[i] = black
+		} else {
+			out.Pix[i] = white
+		}
+	}
+	return out
+}
+
+// DetectSkew estimates the skew angle in degrees using the projection profile method.
+// Dark pixels are projected onto the vertical axis for each candidate angle between -maxAngle and maxAngle,
+// the angle with the sharpest profile (highest sum of squared row counts) is the one that lines text rows up.
+func DetectSkew(gray *image.Gray, maxAngle, step float64) float64 {
+	bounds := gray.Bounds()
+	// sample every few pixels, the profile shape is kept while the work is reduced considerably
+	sample := int(math.Max(1, float64(bounds.Dx())/800))
+	points := make([]image.Point, 0)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += sample {
+		for x := bounds.Min.X; x < bounds.Max.X; x += sample {
+			if gray.GrayAt(x, y).Y < 128 {
+				points = append(points, image.Pt(x, y))
+			}
+		}
+	}
+	if len(points) == 0 || step <= 0 {
+		return 0
+	}
+
+	bestAngle := 0.0
+	bestScore := -1.0
+	rows := make([]int, bounds.Dy()+bounds.Dx())
+	for angle := -maxAngle; angle <= maxAngle+step/2; angle += step {
+		rad := angle * math.Pi / 180
+		sin, cos := math.Sin(rad), math.Cos(rad)
+		for i := range rows {
+			rows[i] = 0
+		}
+		for _, p := range points {
+			row := int(float64(p.Y-bounds.Min.Y)*cos-float64(p.X-bounds.Min.X)*sin) + bounds.Dx()/2
+			if row >= 0 && row < len(rows) {
+				rows[row]++
+			}
+		}
+		var score float64
+		for _, count := range rows {
+			score += float64(count) * float64(count)
+		}
+		if score > bestScore {
+			bestScore = score
+			bestAngle = angle
+		}
+	}
+	return bestAngle
+}
+
+// Rotate rotates the image around its center by angle degrees using nearest neighbour sampling.
+// The bounds are kept the same so OCR coordinates stay comparable to the original, uncovered areas are white.
+func Rotate(gray *image.Gray, angle float64) *image.Gray {
+	bounds := gray.Bounds()
+	out := image.NewGray(bounds)
+	rad := angle * math.Pi / 180
+	sin, cos := math.Sin(rad), math.Cos(rad)
+	cx := float64(bounds.Min.X+bounds.Max.X) / 2
+	cy := float64(bounds.Min.Y+bounds.Max.Y) / 2
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			dx, dy := float64(x)-cx, float64(y)-cy
+			srcX := int(math.Round(dx*cos - dy*sin + cx))
+			srcY := int(math.Round(dx*sin + dy*cos + cy))
+			if image.Pt(srcX, srcY).In(bounds) {
+				out.SetGray(x, y, gray.GrayAt(srcX, srcY))
+			} else {
+				out.SetGray(x, y, color.Gray{Y: white})
+			}
+		}
+	}
+	return out
+}
+
+// RotateRGBA rotates a colour image like Rotate
+func RotateRGBA(rgba *image.RGBA, angle float64) *image.RGBA {
+	bounds := rgba.Bounds()
+	out := image.NewRGBA(bounds)
+	rad := angle * math.Pi / 180
+	sin, cos := math.Sin(rad), math.Cos(rad)
+	cx := float64(bounds.Min.X+bounds.Max.X) / 2
+	cy := float64(bounds.Min.Y+bounds.Max.Y) / 2
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			dx, dy := float64(x)-cx, float64(y)-cy
+			srcX := int(math.Round(dx*cos - dy*sin + cx))
+			srcY := int(math.Round(dx*sin + dy*cos + cy))
+			if image.Pt(srcX, srcY).In(bounds) {
+				out.SetRGBA(x, y, rgba.RGBAAt(srcX, srcY))
+			} else {
+				out.SetRGBA(x, y, color.RGBA{R: white, G: white, B: white, A: white})
+			}
+		}
+	}
+	return out
+}
+
+// Deskew detects the skew angle and rotates the image to correct it.
+// Returns the corrected image and the detected angle in degrees.
+func Deskew(gray *image.Gray, maxAngle, step float64) (*image.Gray, float64) {
+	angle := DetectSkew(gray, maxAngle, step)
+	if angle == 0 {
+		return gray, 0
+	}
+	return Rotate(gray, angle), angle
+}
+
+// RemoveBorder whitens the dark bands scanners and fax machines leave along the page edges.
+// Rows and columns are cleared from each edge inwards while more than darkRatio of their pixels are dark.
+// The image is not cropped so page coordinates are unchanged.
+func RemoveBorder(gray *image.Gray, darkRatio float64) *image.Gray {
+	bounds := gray.Bounds()
+	out := image.NewGray(bounds)
+	copy(out.Pix, gray.Pix)
+
+	rowIsDark := func(y int) bool {
+		dark := 0
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if out.GrayAt(x, y).Y < 128 {
+				dark++
+			}
+		}
+		return float64(dark) > darkRatio*float64(bounds.Dx())
+	}
+	colIsDark := func(x int) bool {
+		dark := 0
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			if out.GrayAt(x, y).Y < 128 {
+				dark++
+			}
+		}
+		return float64(dark) > darkRatio*float64(bounds.Dy())
+	}
+	clearRow := func(y int) {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			out.SetGray(x, y, color.Gray{Y: white})
+		}
+	}
+	clearCol := func(x int) {
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			out.SetGray(x, y, color.Gray{Y: white})
+		}
+	}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y && rowIsDark(y); y++ {
+		clearRow(y)
+	}
+	for y := bounds.Max.Y - 1; y >= bounds.Min.Y && rowIsDark(y); y-- {
+		clearRow(y)
+	}
+	for x := bounds.Min.X; x < bounds.Max.X && colIsDark(x); x++ {
+		clearCol(x)
+	}
+	for x := bounds.Max.X - 1; x >= bounds.Min.X && colIsDark(x); x-- {
+		clearCol(x)
+	}
+	return out
+}
+
+// Despeckle removes connected groups of dark pixels no larger than maxSize pixels.
+// Components are found with 8-connectivity, so it should run on a binarized image.
+func Despeckle(gray *image.Gray, maxSize int) *image.Gray {
+	bounds := gray.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	out := image.NewGray(bounds)
+	copy(out.Pix, gray.Pix)
+
+	visited := make([]bool, width*height)
+	stack := make([]int, 0)
+	component := make([]int, 0)
+	isDark := func(idx int) bool {
+		x, y := idx%width, idx/width
+		return out.Pix[y*out.Stride+x] < 128
+	}
+
+	for start := 0; start < width*height; start++ {
+		if visited[start] || !isDark(start) {
+			continue
+		}
+		stack = append(stack[:0], start)
+		component = component[:0]
+		visited[start] = true
+		for len(stack) > 0 {
+			idx := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			component = append(component, idx)
+			x, y := idx%width, idx/width
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := x+dx, y+dy
+					if nx < 0 || ny < 0 || nx >= width || ny >= height {
+						continue
+					}
+					n := ny*width + nx
+					if !visited[n] && isDark(n) {
+						visited[n] = true
+						stack = append(stack, n)
+					}
+				}
+			}
+		}
+		if len(component) <= maxSize {
+			for _, idx := range component {
+				x, y := idx%width, idx/width
+				out.Pix[y*out.Stride+x] = white
+			}
+		}
+	}
+	return out
+}
+
+// whitenChanged whitens the pixels of the colour image that a step changed in its grayscale copy,
+// so steps that clear pixels on grayscale can be applied to colour images
+func whitenChanged(rgba *image.RGBA, before, after *image.Gray) {
+	bounds := before.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if before.GrayAt(x, y) != after.GrayAt(x, y) {
+				rgba.SetRGBA(x, y, color.RGBA{R: white, G: white, B: white, A: white})
+			}
+		}
+	}
+}
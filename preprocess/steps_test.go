@@ -0,0 +1,143 @@
+package preprocess
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func blankPage(width, height int) *image.Gray {
+	gray := image.NewGray(image.Rect(0, 0, width, height))
+	for i := range gray.Pix {
+		gray.Pix[i] = white
+	}
+	return gray
+}
+
+func TestOtsuThreshold(t *testing.T) {
+	gray := blankPage(10, 10)
+	for i := 0; i < 30; i++ {
+		gray.Pix[i] = 40
+	}
+	threshold := OtsuThreshold(gray)
+	if threshold < 40 || threshold >= white {
+		t.Errorf("OtsuThreshold() = %d; want between 40 and 255", threshold)
+	}
+	binary := Binarize(gray, threshold)
+	if binary.Pix[0] != black || binary.Pix[99] != white {
+		t.Errorf("Binarize() = %d, %d; want %d, %d", binary.Pix[0], binary.Pix[99], black, white)
+	}
+}
+
+func TestDetectSkew(t *testing.T) {
+	tests := []float64{-2, 0, 1.5}
+
+	for _, angle := range tests {
+		gray := blankPage(600, 400)
+		slope := math.Tan(angle * math.Pi / 180)
+		for row := 50; row < 350; row += 40 {
+			for x := 50; x < 550; x++ {
+				y := row + int(math.Round(float64(x)*slope))
+				gray.SetGray(x, y, color.Gray{Y: black})
+				gray.SetGray(x, y+1, color.Gray{Y: black})
+			}
+		}
+		got := DetectSkew(gray, 5, 0.25)
+		if math.Abs(got-angle) > 0.25 {
+			t.Errorf("DetectSkew() = %v; want %v", got, angle)
+		}
+		deskewed, _ := Deskew(gray, 5, 0.25)
+		if residual := DetectSkew(deskewed, 5, 0.25); math.Abs(residual) > 0.25 {
+			t.Errorf("DetectSkew() after Deskew = %v; want 0", residual)
+		}
+	}
+}
+
+func TestDespeckle(t *testing.T) {
+	gray := blankPage(20, 20)
+	// single pixel speck
+	gray.SetGray(2, 2, color.Gray{Y: black})
+	// 3x3 block that should be kept
+	for y := 10; y < 13; y++ {
+		for x := 10; x < 13; x++ {
+			gray.SetGray(x, y, color.Gray{Y: black})
+		}
+	}
+	out := Despeckle(gray, 4)
+	if out.GrayAt(2, 2).Y != white {
+		t.Errorf("Despeckle() kept speck at 2,2")
+	}
+	if out.GrayAt(11, 11).Y != black {
+		t.Errorf("Despeckle() removed block at 11,11")
+	}
+}
+
+func TestRemoveBorder(t *testing.T) {
+	gray := blankPage(20, 20)
+	for y := 0; y < 20; y++ {
+		gray.SetGray(0, y, color.Gray{Y: black})
+		gray.SetGray(1, y, color.Gray{Y: black})
+	}
+	gray.SetGray(10, 10, color.Gray{Y: black})
+	out := RemoveBorder(gray, 0.5)
+	if out.GrayAt(0, 5).Y != white || out.GrayAt(1, 5).Y != white {
+		t.Errorf("RemoveBorder() kept the left border")
+	}
+	if out.GrayAt(10, 10).Y != black {
+		t.Errorf("RemoveBorder() removed page content")
+	}
+}
+
+func TestOptionsFromSteps(t *testing.T) {
+	opts, err := OptionsFromSteps([]string{"deskew", "Binarize"})
+	if err != nil {
+		t.Fatalf("OptionsFromSteps() error = %v", err)
+	}
+	steps := opts.Steps()
+	if len(steps) != 2 || steps[0] != StepBinarize || steps[1] != StepDeskew {
+		t.Errorf("Steps() = %v; want [binarize deskew]", steps)
+	}
+	if _, err = OptionsFromSteps([]string{"sharpen"}); err == nil {
+		t.Errorf("OptionsFromSteps() expected error for unknown step")
+	}
+}
+
+func TestPipeline_Apply(t *testing.T) {
+	page := image.NewRGBA(image.Rect(0, 0, 40, 40))
+	for i := range page.Pix {
+		page.Pix[i] = white
+	}
+	red := color.RGBA{R: 0xc0, A: white}
+	for x := 5; x < 35; x++ {
+		page.SetRGBA(x, 20, red)
+	}
+	page.SetRGBA(2, 2, color.RGBA{A: white})
+
+	tests := []struct {
+		steps    []string
+		wantGray bool
+	}{
+		{[]string{StepDespeckle}, false},
+		{[]string{StepGrayscale, StepDespeckle}, true},
+		{[]string{StepBinarize}, true},
+	}
+	for _, tt := range tests {
+		opts, err := OptionsFromSteps(tt.steps)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, _ := NewPipeline(opts).Apply(page)
+		if _, isGray := out.(*image.Gray); isGray != tt.wantGray {
+			t.Errorf("Apply() with %v returned %T; want grayscale %v", tt.steps, out, tt.wantGray)
+		}
+		if rgba, ok := out.(*image.RGBA); ok {
+			if got := rgba.RGBAAt(20, 20); got != red {
+				t.Errorf("Apply() with %v changed the line to %v; want %v", tt.steps, got, red)
+			}
+			if got := rgba.RGBAAt(2, 2); got.R != white {
+				t.Errorf("Apply() with %v kept the speck %v", tt.steps, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package ptr
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	assetTypePattern = regexp.MustCompile(`\[([A-Z]{2})\]`)
+	tickerPattern    = regexp.MustCompile(`\(([A-Z][A-Z0-9.\-]{0,9})\)`)
+	spacePattern     = regexp.MustCompile(`\s+`)
+)
+
+// ParseAsset splits the text of the Asset column into the asset name, ticker and asset type code.
+// "Apple Inc. - Common Stock (AAPL) [ST]" returns "Apple Inc. - Common Stock", "AAPL" and "ST".
+// The ticker and asset type are empty when the column doesn't contain them.
+func ParseAsset(text string) (name, ticker, assetType string) {
+	name = text
+	if m := assetTypePattern.FindStringSubmatchIndex(name); m != nil {
+		assetType = name[m[2]:m[3]]
+		name = name[:m[0]] + " " + name[m[1]:]
+	}
+	if all := tickerPattern.FindAllStringSubmatchIndex(name, -1); all != nil {
+		m := all[len(all)-1]
+		ticker = name[m[2]:m[3]]
+		name = name[:m[0]] + " " + name[m[1]:]
+	}
+	name = strings.TrimSpace(spacePattern.ReplaceAllString(name, " "))
+	return name, ticker, assetType
+}
@@ -0,0 +1,343 @@
+package ptr
+
+import (
+	"github.com/paulschick/disclosureupdater/model"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Column identifies a column of the PTR transaction table
+type Column int
+
+const (
+	ColumnId Column = iota
+	ColumnOwner
+	ColumnAsset
+	ColumnType
+	ColumnDate
+	ColumnNotificationDate
+	ColumnAmount
+	ColumnCapGains
+	// ColumnFilingStatus is taken from the "Filing Status" detail line printed under a transaction
+	ColumnFilingStatus
+)
+
+var (
+	datePattern         = regexp.MustCompile(`\d{1,2}/\d{1,2}/\d{2,4}`)
+	filingStatusPattern = regexp.MustCompile(`(?i)^(?:f\s?s|filing status)\s*:\s*([a-z]+)`)
+)
+
+// footerPrefixes mark the end of the transaction table
+var footerPrefixes = []string{
+	"* for the complete list",
+	"asset class details",
+	"initial public offerings",
+	"certification and signature",
+	"i certify",
+	"digitally signed",
+}
+
+// labelPrefixes mark detail lines printed under a transaction that are not part of any column
+var labelPrefixes = []string{
+	"filing status",
+	"subholding of",
+	"description",
+	"location",
+	"comments",
+}
+
+// Word is an OCR word with its vertical center, used to group words into rows
+type Word struct {
+	*model.OcrResult
+	centerY float64
+}
+
+// Row is a group of words on the same text line sorted left to right
+type Row struct {
+	Words []*Word
+}
+
+// Text returns the words of the row joined by spaces
+func (r *Row) Text() string {
+	words := make([]string, len(r.Words))
+	for i, w := range r.Words {
+		words[i] = w.Word
+	}
+	return strings.Join(words, " ")
+}
+
+// columnStart is the left edge of a column taken from its header word
+type columnStart struct {
+	column Column
+	left   int
+}
+
+// OcrTableParser reconstructs PTR transaction tables from OCR word boxes.
+// Words are grouped into rows by their vertical position, the header row gives the left edge of each
+// column, and every following row up to the footer is split into columns by word position.
+// A row with a transaction date starts a new transaction, rows without one continue the previous
+// transaction, as long asset names and amount ranges wrap onto several lines.
+// Column positions carry over to the next page when it doesn't repeat the header.
+type OcrTableParser struct {
+	DocId     int
+	SourcePdf string
+	columns   []columnStart
+}
+
+func NewOcrTableParser(docId int, sourcePdf string) *OcrTableParser {
+	return &OcrTableParser{
+		DocId:     docId,
+		SourcePdf: sourcePdf,
+	}
+}
+
+// ParsePage returns the transactions found on one page
+func (p *OcrTableParser) ParsePage(page int, results []*model.OcrResult) []*model.Transaction {
+	rows := GroupRows(results)
+	transactions := make([]*model.Transaction, 0)
+	start := 0
+	for i, row := range rows {
+		if columns := headerColumns(row); columns != nil {
+			p.columns = columns
+			start = i + 1
+			break
+		}
+	}
+	if p.columns == nil {
+		return transactions
+	}
+
+	var current map[Column]string
+	var currentPage int
+	var confidences []float64
+	finish := func() {
+		if current == nil {
+			return
+		}
+		transaction := newTransaction(p.DocId, p.SourcePdf, currentPage, model.ExtractionOcr, current)
+		transaction.Confidence = mean(confidences)
+		transactions = append(transactions, transaction)
+		current = nil
+		confidences = nil
+	}
+
+	for _, row := range rows[start:] {
+		text := strings.ToLower(row.Text())
+		if hasAnyPrefix(text, footerPrefixes) {
+			break
+		}
+		if hasAnyPrefix(text, labelPrefixes) {
+			if status, ok := parseFilingStatus(text); ok && current != nil {
+				current[ColumnFilingStatus] = status
+			}
+			continue
+		}
+		cells := p.splitRow(row)
+		if datePattern.MatchString(cells[ColumnDate]) {
+			finish()
+			current = make(map[Column]string)
+			currentPage = page
+		}
+		if current == nil {
+			continue
+		}
+		for column, cell := range cells {
+			current[column] = joinCell(current[column], cell)
+		}
+		for _, w := range row.Words {
+			if w.Confidence >= 0 {
+				confidences = append(confidences, w.Confidence)
+			}
+		}
+	}
+	finish()
+	return transactions
+}
+
+// newTransaction builds a typed transaction from the text of each table column
+func newTransaction(docId int, sourcePdf string, page int, method string, cells map[Column]string) *model.Transaction {
+	owner, _ := model.ParseOwnerCode(cells[ColumnOwner])
+	transactionType, _ := model.ParseTransactionType(cells[ColumnType])
+	asset, ticker, assetType := ParseAsset(cells[ColumnAsset])
+	transaction := &model.Transaction{
+		DocId:            docId,
+		SourcePdf:        sourcePdf,
+		Page:             page,
+		Method:           method,
+		Owner:            owner,
+		Asset:            asset,
+		Ticker:           ticker,
+		AssetType:        assetType,
+		TransactionType:  transactionType,
+		Date:             cells[ColumnDate],
+		NotificationDate: cells[ColumnNotificationDate],
+		FilingStatus:     cells[ColumnFilingStatus],
+		ParserVersion:    ParserVersion,
+	}
+	transaction.SetAmount(cells[ColumnAmount])
+	return transaction
+}
+
+// splitRow assigns each word of the row to the column whose left edge is closest on its left
+func (p *OcrTableParser) splitRow(row *Row) map[Column]string {
+	cells := make(map[Column]string)
+	for _, w := range row.Words {
+		column := p.columns[0].column
+		for _, c := range p.columns {
+			// allow words to start slightly left of their header
+			if w.Left+w.Height() >= c.left {
+				column = c.column
+			}
+		}
+		cells[column] = joinCell(cells[column], w.Word)
+	}
+	return cells
+}
+
+// GroupRows groups words into rows by their vertical center.
+// A word joins the current row when its center is within half a median word height of the row.
+func GroupRows(results []*model.OcrResult) []*Row {
+	words := make([]*Word, 0, len(results))
+	heights := make([]int, 0, len(results))
+	for _, r := range results {
+		if strings.TrimSpace(r.Word) == "" || r.Height() <= 0 {
+			continue
+		}
+		words = append(words, &Word{OcrResult: r, centerY: float64(r.Top+r.Bottom) / 2})
+		heights = append(heights, r.Height())
+	}
+	if len(words) == 0 {
+		return nil
+	}
+	sort.Ints(heights)
+	tolerance := float64(heights[len(heights)/2]) / 2
+	sort.SliceStable(words, func(i, j int) bool {
+		return words[i].centerY < words[j].centerY
+	})
+
+	rows := make([]*Row, 0)
+	var current *Row
+	var currentCenter float64
+	for _, w := range words {
+		if current == nil || w.centerY-currentCenter > tolerance {
+			current = &Row{}
+			rows = append(rows, current)
+		}
+		current.Words = append(current.Words, w)
+		var total float64
+		for _, cw := range current.Words {
+			total += cw.centerY
+		}
+		currentCenter = total / float64(len(current.Words))
+	}
+	for _, row := range rows {
+		sort.SliceStable(row.Words, func(i, j int) bool {
+			return row.Words[i].Left < row.Words[j].Left
+		})
+	}
+	return rows
+}
+
+// headerColumns returns the column left edges if the row is the PTR table header
+// "ID Owner Asset Transaction Type Date Notification Date Amount Cap. Gains > $200?"
+func headerColumns(row *Row) []columnStart {
+	found := make(map[Column]int)
+	for i, w := range row.Words {
+		word := normalizeHeaderWord(w.Word)
+		previous := ""
+		if i > 0 {
+			previous = normalizeHeaderWord(row.Words[i-1].Word)
+		}
+		switch {
+		case word == "id":
+			setOnce(found, ColumnId, w.Left)
+		case word == "owner":
+			setOnce(found, ColumnOwner, w.Left)
+		case word == "asset":
+			setOnce(found, ColumnAsset, w.Left)
+		case word == "transaction":
+			setOnce(found, ColumnType, w.Left)
+		case word == "type" && previous != "transaction":
+			setOnce(found, ColumnType, w.Left)
+		case word == "notification":
+			setOnce(found, ColumnNotificationDate, w.Left)
+		case word == "date" && previous != "notification":
+			setOnce(found, ColumnDate, w.Left)
+		case word == "amount":
+			setOnce(found, ColumnAmount, w.Left)
+		case word == "cap" || word == "gains":
+			setOnce(found, ColumnCapGains, w.Left)
+		}
+	}
+	_, hasAsset := found[ColumnAsset]
+	_, hasDate := found[ColumnDate]
+	_, hasAmount := found[ColumnAmount]
+	if !hasAsset || !hasDate || !hasAmount {
+		return nil
+	}
+	columns := make([]columnStart, 0, len(found))
+	for column, left := range found {
+		columns = append(columns, columnStart{column: column, left: left})
+	}
+	sort.Slice(columns, func(i, j int) bool {
+		return columns[i].left < columns[j].left
+	})
+	return columns
+}
+
+func normalizeHeaderWord(word string) string {
+	return strings.Trim(strings.ToLower(word), ".:?,;")
+}
+
+func setOnce(found map[Column]int, column Column, left int) {
+	if _, ok := found[column]; !ok {
+		found[column] = left
+	}
+}
+
+// parseFilingStatus returns the status of a "Filing Status: New" detail line, as model.FilingStatusNew or
+// model.FilingStatusAmended
+func parseFilingStatus(text string) (string, bool) {
+	m := filingStatusPattern.FindStringSubmatch(text)
+	if m == nil {
+		return "", false
+	}
+	switch strings.ToLower(m[1]) {
+	case "new":
+		return model.FilingStatusNew, true
+	case "amended", "amendment":
+		return model.FilingStatusAmended, true
+	}
+	return m[1], true
+}
+
+func hasAnyPrefix(text string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(text, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinCell(existing, addition string) string {
+	if addition == "" {
+		return existing
+	}
+	if existing == "" {
+		return addition
+	}
+	return existing + " " + addition
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var total float64
+	for _, v := range values {
+		total += v
+	}
+	return total / float64(len(values))
+}
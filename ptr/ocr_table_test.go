@@ -0,0 +1,99 @@
+package ptr
+
+import (
+	"github.com/paulschick/disclosureupdater/model"
+	"strings"
+	"testing"
+)
+
+// line lays out words starting at the given left edges on a line at top
+func line(top int, cells map[int]string) []*model.OcrResult {
+	results := make([]*model.OcrResult, 0)
+	for left, text := range cells {
+		x := left
+		for _, word := range strings.Fields(text) {
+			results = append(results, &model.OcrResult{
+				Word:       word,
+				Confidence: 90,
+				Left:       x,
+				Top:        top,
+				Right:      x + len(word)*10,
+				Bottom:     top + 20,
+			})
+			x += len(word)*10 + 10
+		}
+	}
+	return results
+}
+
+func TestOcrTableParser_ParsePage(t *testing.T) {
+	words := make([]*model.OcrResult, 0)
+	words = append(words, line(100, map[int]string{0: "Periodic Transaction Report"})...)
+	words = append(words, line(200, map[int]string{
+		0: "ID", 50: "Owner", 150: "Asset", 600: "Transaction Type", 800: "Date",
+		950: "Notification Date", 1150: "Amount", 1400: "Cap. Gains > $200?",
+	})...)
+	words = append(words, line(240, map[int]string{
+		50: "SP", 150: "Apple Inc. - Common", 600: "P", 800: "01/03/2023", 950: "01/20/2023", 1150: "$1,001 -",
+	})...)
+	words = append(words, line(262, map[int]string{150: "Stock (AAPL) [ST]", 1150: "$15,000"})...)
+	words = append(words, line(290, map[int]string{150: "FILING STATUS: New"})...)
+	words = append(words, line(330, map[int]string{
+		50: "JT", 150: "Microsoft Corporation (MSFT) [ST]", 600: "S (partial)", 800: "02/14/2023",
+		950: "02/15/2023", 1150: "$15,001 - $50,000",
+	})...)
+	words = append(words, line(400, map[int]string{0: "* For the complete list of asset type abbreviations"})...)
+	words = append(words, line(440, map[int]string{150: "Not a transaction 03/01/2023"})...)
+
+	parser := NewOcrTableParser(20012345, "2023.ptr-pdfs.CA12.Doe.Jane.20012345.pdf")
+	transactions := parser.ParsePage(0, words)
+	if len(transactions) != 2 {
+		t.Fatalf("ParsePage() returned %d transactions; want 2", len(transactions))
+	}
+
+	first := transactions[0]
+	expected := model.Transaction{
+		DocId:            20012345,
+		SourcePdf:        "2023.ptr-pdfs.CA12.Doe.Jane.20012345.pdf",
+		Page:             0,
+		Method:           model.ExtractionOcr,
+		Owner:            model.OwnerSpouse,
+		Asset:            "Apple Inc. - Common Stock",
+		Ticker:           "AAPL",
+		AssetType:        "ST",
+		TransactionType:  model.TransactionPurchase,
+		Date:             "01/03/2023",
+		NotificationDate: "01/20/2023",
+		Amount:           "$1,001 - $15,000",
+		AmountBracket:    model.Amount1001To15000,
+		AmountMin:        1001,
+		AmountMax:        15000,
+		FilingStatus:     model.FilingStatusNew,
+		Confidence:       90,
+		ParserVersion:    ParserVersion,
+	}
+	if *first != expected {
+		t.Errorf("ParsePage()[0] = %+v; want %+v", *first, expected)
+	}
+	second := transactions[1]
+	if second.TransactionType != model.TransactionPartialSale || second.Amount != "$15,001 - $50,000" || second.Owner != model.OwnerJoint {
+		t.Errorf("ParsePage()[1] = %+v", *second)
+	}
+
+	// the next page has no header and continues the table
+	next := line(50, map[int]string{
+		50: "DC", 150: "Tesla, Inc. (TSLA) [OP]", 600: "E", 800: "03/03/2023", 950: "03/04/2023", 1150: "$1,001 - $15,000",
+	})
+	transactions = parser.ParsePage(1, next)
+	if len(transactions) != 1 || transactions[0].Owner != model.OwnerDependent || transactions[0].Page != 1 {
+		t.Errorf("ParsePage() continuation = %+v", transactions)
+	}
+}
+
+func TestOcrTableParser_NoHeader(t *testing.T) {
+	parser := NewOcrTableParser(1, "x.pdf")
+	transactions := parser.ParsePage(0, line(10, map[int]string{0: "Financial Disclosure Report 01/01/2023"}))
+	if len(transactions) != 0 {
+		t.Errorf("ParsePage() = %+v; want no transactions", transactions)
+	}
+}
@@ -0,0 +1,210 @@
+package ptr
+
+import (
+	"bufio"
+	"encoding/csv"
+	"github.com/gocarina/gocsv"
+	"github.com/paulschick/disclosureupdater/common/methods"
+	"github.com/paulschick/disclosureupdater/model"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// DefaultMinMatchScore is the lowest name similarity accepted for a security master match
+const DefaultMinMatchScore = 0.85
+
+// nameNoiseWords are dropped from asset and security names before comparing them
+var nameNoiseWords = map[string]bool{
+	"the":          true,
+	"inc":          true,
+	"incorporated": true,
+	"corp":         true,
+	"corporation":  true,
+	"co":           true,
+	"company":      true,
+	"ltd":          true,
+	"limited":      true,
+	"plc":          true,
+	"llc":          true,
+	"lp":           true,
+	"sa":           true,
+	"nv":           true,
+	"ag":           true,
+	"common":       true,
+	"stock":        true,
+	"shares":       true,
+	"share":        true,
+	"ordinary":     true,
+	"class":        true,
+	"sponsored":    true,
+	"ads":          true,
+	"adr":          true,
+}
+
+// security is a security master entry with its normalized name
+type security struct {
+	*model.Security
+	bigrams map[string]int
+	size    int
+}
+
+// AssetResolver resolves the ticker and asset class of transactions.
+// Tickers in parentheses after the asset name are used as is, transactions without one are matched
+// by name against a security master. Name similarity is the Dice coefficient of the character bigrams
+// of the normalized names, which tolerates OCR errors and small differences in company suffixes.
+type AssetResolver struct {
+	MinScore   float64
+	securities []*security
+	byTicker   map[string]*security
+	byToken    map[string][]*security
+}
+
+func NewAssetResolver(securities []*model.Security, minScore float64) *AssetResolver {
+	r := &AssetResolver{
+		MinScore: minScore,
+		byTicker: make(map[string]*security),
+		byToken:  make(map[string][]*security),
+	}
+	for _, s := range securities {
+		normalized := normalizeName(s.Name)
+		entry := &security{Security: s}
+		entry.bigrams, entry.size = bigrams(normalized)
+		r.securities = append(r.securities, entry)
+		r.byTicker[strings.ToUpper(s.Ticker)] = entry
+		for _, token := range strings.Fields(normalized) {
+			r.byToken[token] = append(r.byToken[token], entry)
+		}
+	}
+	return r
+}
+
+// Resolve sets the asset class, ticker, ticker source and ticker confidence of the transaction.
+// A ticker from the asset name has confidence 1 if the security master lists it, otherwise the
+// extraction confidence of the row. A ticker from the security master has the name similarity.
+func (r *AssetResolver) Resolve(t *model.Transaction) {
+	t.AssetClass = model.AssetClass(t.AssetType)
+	if t.Ticker != "" {
+		if _, ok := r.byTicker[t.Ticker]; ok || t.Method == model.ExtractionText {
+			t.TickerSource, t.TickerConfidence = model.TickerFromAsset, 1
+			return
+		}
+	}
+	if model.IsListedAssetType(t.AssetType) {
+		if match, score := r.Match(t.Asset); match != nil && score >= r.MinScore {
+			t.Ticker, t.TickerSource, t.TickerConfidence = match.Ticker, model.TickerFromMaster, score
+			if t.AssetClass == "" {
+				t.AssetClass = model.AssetClass(match.AssetType)
+			}
+			return
+		}
+	}
+	if t.Ticker != "" {
+		t.TickerSource, t.TickerConfidence = model.TickerFromAsset, t.Confidence/100
+	}
+}
+
+// Match returns the security whose name is most similar to the asset name, and the similarity between 0 and 1.
+// Securities sharing a word with the name are compared first, every security if none of them is similar enough.
+func (r *AssetResolver) Match(assetName string) (*model.Security, float64) {
+	normalized := normalizeName(assetName)
+	assetBigrams, assetSize := bigrams(normalized)
+	if assetSize == 0 {
+		return nil, 0
+	}
+	var best *security
+	var bestScore float64
+	seen := make(map[*security]bool)
+	match := func(candidate *security) {
+		if seen[candidate] {
+			return
+		}
+		seen[candidate] = true
+		score := dice(assetBigrams, assetSize, candidate.bigrams, candidate.size)
+		if score > bestScore {
+			best, bestScore = candidate, score
+		}
+	}
+	for _, token := range strings.Fields(normalized) {
+		for _, candidate := range r.byToken[token] {
+			match(candidate)
+		}
+	}
+	// OCR errors can garble every token of the name, then no security shares a token with it
+	if bestScore < r.MinScore {
+		for _, candidate := range r.securities {
+			match(candidate)
+		}
+	}
+	if best == nil {
+		return nil, 0
+	}
+	return best.Security, bestScore
+}
+
+// LoadSecurityMaster reads a security master with the columns ticker, name and assetType.
+// The file may be comma, tab or pipe separated, the separator is taken from the header line.
+func LoadSecurityMaster(path string) ([]*model.Security, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	reader := bufio.NewReader(f)
+	comma, err := methods.SniffDelimiter(reader)
+	if err != nil {
+		return nil, err
+	}
+	gocsv.SetCSVReader(func(in io.Reader) gocsv.CSVReader {
+		r := csv.NewReader(in)
+		r.Comma = comma
+		r.LazyQuotes = true
+		return r
+	})
+	securities := make([]*model.Security, 0)
+	err = gocsv.Unmarshal(reader, &securities)
+	if err != nil {
+		return nil, err
+	}
+	return securities, nil
+}
+
+// normalizeName lower cases the name, drops punctuation and noise words
+func normalizeName(name string) string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	tokens := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if !nameNoiseWords[field] {
+			tokens = append(tokens, field)
+		}
+	}
+	return strings.Join(tokens, " ")
+}
+
+func bigrams(text string) (map[string]int, int) {
+	runes := []rune(text)
+	counts := make(map[string]int)
+	size := 0
+	for i := 0; i+1 < len(runes); i++ {
+		counts[string(runes[i:i+2])]++
+		size++
+	}
+	return counts, size
+}
+
+// dice returns the Dice coefficient of two bigram multisets
+func dice(a map[string]int, aSize int, b map[string]int, bSize int) float64 {
+	if aSize+bSize == 0 {
+		return 0
+	}
+	shared := 0
+	for bigram, count := range a {
+		shared += min(count, b[bigram])
+	}
+	return 2 * float64(shared) / float64(aSize+bSize)
+}
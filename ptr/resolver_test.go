@@ -0,0 +1,105 @@
+package ptr
+
+import (
+	"github.com/paulschick/disclosureupdater/model"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testSecurities = []*model.Security{
+	{Ticker: "AAPL", Name: "Apple Inc. - Common Stock", AssetType: "ST"},
+	{Ticker: "MSFT", Name: "Microsoft Corporation - Common Stock", AssetType: "ST"},
+	{Ticker: "GOOGL", Name: "Alphabet Inc. - Class A Common Stock", AssetType: "ST"},
+	{Ticker: "GOOG", Name: "Alphabet Inc. - Class C Capital Stock", AssetType: "ST"},
+	{Ticker: "QQQ", Name: "Invesco QQQ Trust, Series 1", AssetType: "EF"},
+}
+
+func TestAssetResolver_Resolve(t *testing.T) {
+	resolver := NewAssetResolver(testSecurities, DefaultMinMatchScore)
+	tests := []struct {
+		name       string
+		txn        model.Transaction
+		ticker     string
+		source     string
+		confidence float64
+		assetClass string
+	}{
+		{"ticker in asset name", model.Transaction{Asset: "Apple Inc. - Common Stock", Ticker: "AAPL", AssetType: "ST", Method: model.ExtractionOcr, Confidence: 80},
+			"AAPL", model.TickerFromAsset, 1, "Stocks (including ADRs)"},
+		{"unlisted ticker from text layer", model.Transaction{Asset: "Acme Co", Ticker: "ACME", AssetType: "ST", Method: model.ExtractionText, Confidence: 100},
+			"ACME", model.TickerFromAsset, 1, "Stocks (including ADRs)"},
+		{"unlisted ticker from OCR", model.Transaction{Asset: "Acme Co", Ticker: "ACME", AssetType: "ST", Method: model.ExtractionOcr, Confidence: 70},
+			"ACME", model.TickerFromAsset, 0.7, "Stocks (including ADRs)"},
+		{"fuzzy match", model.Transaction{Asset: "Microsoft Corp", AssetType: "ST", Method: model.ExtractionOcr},
+			"MSFT", model.TickerFromMaster, 1, "Stocks (including ADRs)"},
+		{"OCR typo in every word", model.Transaction{Asset: "Microsofl Corp", AssetType: "ST", Method: model.ExtractionOcr},
+			"MSFT", model.TickerFromMaster, 0, "Stocks (including ADRs)"},
+		{"misread ticker", model.Transaction{Asset: "Invesco QQQ Trust, Serles 1", Ticker: "OQQ", Method: model.ExtractionOcr},
+			"QQQ", model.TickerFromMaster, 0, "Exchange Traded Funds (ETF)"},
+		{"share class", model.Transaction{Asset: "Alphabet Inc. - Class C Capital Stock", AssetType: "ST"},
+			"GOOG", model.TickerFromMaster, 1, "Stocks (including ADRs)"},
+		{"no match", model.Transaction{Asset: "Acme Widgets Holdings", AssetType: "ST"},
+			"", "", 0, "Stocks (including ADRs)"},
+		{"not listed", model.Transaction{Asset: "Apple Inc", AssetType: "RP"},
+			"", "", 0, "Real Property"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			txn := test.txn
+			resolver.Resolve(&txn)
+			if txn.Ticker != test.ticker || txn.TickerSource != test.source || txn.AssetClass != test.assetClass {
+				t.Errorf("Resolve() = %q, %q, %q; want %q, %q, %q",
+					txn.Ticker, txn.TickerSource, txn.AssetClass, test.ticker, test.source, test.assetClass)
+			}
+			if test.confidence > 0 && txn.TickerConfidence != test.confidence {
+				t.Errorf("Resolve() confidence = %f; want %f", txn.TickerConfidence, test.confidence)
+			}
+			if test.source == model.TickerFromMaster && txn.TickerConfidence < DefaultMinMatchScore {
+				t.Errorf("Resolve() confidence = %f; want at least %f", txn.TickerConfidence, DefaultMinMatchScore)
+			}
+		})
+	}
+}
+
+func TestAssetResolver_Match(t *testing.T) {
+	resolver := NewAssetResolver(testSecurities, DefaultMinMatchScore)
+	tests := []struct {
+		assetName string
+		ticker    string
+		minScore  float64
+	}{
+		{"Microsoft Corp", "MSFT", 1},
+		// no word of the name is in the security master
+		{"Micros0ft Corp", "MSFT", 0.75},
+	}
+	for _, test := range tests {
+		match, score := resolver.Match(test.assetName)
+		if match == nil || match.Ticker != test.ticker || score < test.minScore {
+			t.Errorf("Match(%q) = %v, %f; want %s with at least %f", test.assetName, match, score, test.ticker,
+				test.minScore)
+		}
+	}
+}
+
+func TestLoadSecurityMaster(t *testing.T) {
+	files := map[string]string{
+		"comma.csv": "ticker,name,assetType\nAAPL,\"Apple Inc. - Common Stock\",ST\n",
+		"tab.csv":   "ticker\tname\tassetType\nAAPL\tApple Inc. - Common Stock\tST\n",
+		"pipe.txt":  "ticker|name\nAAPL|Apple Inc. - Common Stock\n",
+	}
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		securities, err := LoadSecurityMaster(path)
+		if err != nil {
+			t.Fatalf("LoadSecurityMaster(%s) error = %v", name, err)
+		}
+		if len(securities) != 1 || securities[0].Ticker != "AAPL" || securities[0].Name != "Apple Inc. - Common Stock" {
+			t.Errorf("LoadSecurityMaster(%s) = %+v", name, securities)
+		}
+	}
+}
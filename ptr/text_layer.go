@@ -0,0 +1,262 @@
+package ptr
+
+import (
+	"github.com/gen2brain/go-fitz"
+	"github.com/paulschick/disclosureupdater/model"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// textLayerConfidence is recorded on transactions parsed from the PDF text layer, which has no OCR errors
+const textLayerConfidence = 100
+
+// detailWindow is the number of lines looked ahead for a complete transaction after a detail label
+const detailWindow = 12
+
+var (
+	rowPattern = regexp.MustCompile(`^(?:(SP|JT|DC)\s+)?(.+?)\s+(P|S\s*\(partial\)|S|E)\s+` +
+		`(\d{1,2}/\d{1,2}/\d{4})\s+(\d{1,2}/\d{1,2}/\d{4})\s+` +
+		`(Spouse/DC Over \$[\d,]+|Over \$[\d,]+|\$[\d,]+\s*-\s*\$[\d,]+|\$[\d,]+)\s*(.*)$`)
+	ownerPrefixPattern = regexp.MustCompile(`^(SP|JT|DC)\s`)
+	// detail labels are printed in small caps, only the capital letters survive text extraction: "F S: New"
+	abbreviatedLabelPattern = regexp.MustCompile(`^[A-Z](?:\s?[A-Z])?\s*:`)
+	amountDashPattern       = regexp.MustCompile(`\s*-\s*`)
+	pageNoisePattern        = regexp.MustCompile(`(?i)^(filing id #?\d+|page \d+ of \d+)$`)
+)
+
+// headerWords are the words of the e-filed PTR table header
+// "ID Owner Asset Transaction Type Date Notification Date Amount Cap. Gains > $200?"
+var headerWords = map[string]bool{
+	"id":           true,
+	"owner":        true,
+	"asset":        true,
+	"transaction":  true,
+	"type":         true,
+	"date":         true,
+	"notification": true,
+	"amount":       true,
+	"cap":          true,
+	"gains":        true,
+	">":            true,
+	"$200":         true,
+}
+
+// textLine is a non-empty line of the text layer and the page it was found on
+type textLine struct {
+	page int
+	text string
+}
+
+// TextParser parses the text layer of e-filed PTRs, as extracted by go-fitz.
+// The table starts after the header and ends at the footer. Lines are collected until they form a
+// complete row: an optional owner code, the asset name with its type code, the transaction type,
+// the transaction and notification dates and the amount. The filing status is taken from the detail
+// lines printed under each row, other details (subholding, description, comments) are skipped.
+type TextParser struct {
+	DocId     int
+	SourcePdf string
+}
+
+func NewTextParser(docId int, sourcePdf string) *TextParser {
+	return &TextParser{
+		DocId:     docId,
+		SourcePdf: sourcePdf,
+	}
+}
+
+// Parse returns the transactions found in the text of every page of the PDF, in page order
+func (p *TextParser) Parse(pages []string) []*model.Transaction {
+	lines := splitLines(pages)
+	transactions := make([]*model.Transaction, 0)
+	var pending []textLine
+	flush := func() {
+		if transaction := p.buildTransaction(pending); transaction != nil {
+			transactions = append(transactions, transaction)
+		}
+		pending = nil
+	}
+
+	inTable, inDetail := false, false
+	for i, line := range lines {
+		lower := strings.ToLower(line.text)
+		if isHeaderLine(line.text) {
+			if strings.Contains(lower, "owner") || strings.Contains(lower, "asset") {
+				inTable = true
+			}
+			continue
+		}
+		if !inTable || pageNoisePattern.MatchString(line.text) {
+			continue
+		}
+		if hasAnyPrefix(lower, footerPrefixes) {
+			break
+		}
+		if isDetailLabel(line.text, lower) {
+			flush()
+			if status, ok := parseFilingStatus(line.text); ok && len(transactions) > 0 {
+				transactions[len(transactions)-1].FilingStatus = status
+			}
+			inDetail = true
+			continue
+		}
+		if inDetail {
+			if !startsTransaction(lines[i:]) {
+				// wrapped description or comment
+				continue
+			}
+			inDetail = false
+		}
+		if ownerPrefixPattern.MatchString(line.text) && isRow(joinLines(pending)) {
+			flush()
+		}
+		pending = append(pending, line)
+		next := ""
+		if i+1 < len(lines) {
+			next = lines[i+1].text
+		}
+		if isCompleteRow(joinLines(pending), next) {
+			flush()
+		}
+	}
+	flush()
+	return transactions
+}
+
+// buildTransaction returns the transaction of the collected row lines, or nil if they don't form a row
+func (p *TextParser) buildTransaction(lines []textLine) *model.Transaction {
+	if len(lines) == 0 {
+		return nil
+	}
+	m := rowPattern.FindStringSubmatch(joinLines(lines))
+	if m == nil {
+		return nil
+	}
+	transaction := newTransaction(p.DocId, p.SourcePdf, lines[0].page, model.ExtractionText, map[Column]string{
+		ColumnOwner:            m[1],
+		ColumnAsset:            joinCell(m[2], m[7]),
+		ColumnType:             m[3],
+		ColumnDate:             m[4],
+		ColumnNotificationDate: m[5],
+		ColumnAmount:           amountDashPattern.ReplaceAllString(m[6], " - "),
+	})
+	transaction.Confidence = textLayerConfidence
+	return transaction
+}
+
+// ExtractTextPages returns the text layer of every page of the PDF
+func ExtractTextPages(pdfPath string) ([]string, error) {
+	doc, err := fitz.New(pdfPath)
+	if err != nil {
+		return nil, err
+	}
+	return textPages(doc)
+}
+
+// ReadTextPages returns the text layer of every page of the PDF read from r
+func ReadTextPages(r io.Reader) ([]string, error) {
+	doc, err := fitz.NewFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return textPages(doc)
+}
+
+func textPages(doc *fitz.Document) ([]string, error) {
+	var err error
+	defer func() {
+		_ = doc.Close()
+	}()
+	pages := make([]string, doc.NumPage())
+	for n := range pages {
+		pages[n], err = doc.Text(n)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return pages, nil
+}
+
+// ContainsTableHeader returns true if the text has the PTR table header, i.e. the PDF has a text layer to parse
+func ContainsTableHeader(pages []string) bool {
+	for _, line := range splitLines(pages) {
+		lower := strings.ToLower(line.text)
+		if isHeaderLine(line.text) && (strings.Contains(lower, "owner") || strings.Contains(lower, "asset")) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitLines(pages []string) []textLine {
+	lines := make([]textLine, 0)
+	for page, text := range pages {
+		for _, line := range strings.Split(text, "\n") {
+			line = strings.TrimSpace(spacePattern.ReplaceAllString(line, " "))
+			if line != "" {
+				lines = append(lines, textLine{page: page, text: line})
+			}
+		}
+	}
+	return lines
+}
+
+func joinLines(lines []textLine) string {
+	text := ""
+	for _, line := range lines {
+		text = joinCell(text, line.text)
+	}
+	return text
+}
+
+// isHeaderLine returns true if every word of the line is a table header word
+func isHeaderLine(text string) bool {
+	for _, word := range strings.Fields(text) {
+		if !headerWords[normalizeHeaderWord(word)] {
+			return false
+		}
+	}
+	return true
+}
+
+func isDetailLabel(text, lower string) bool {
+	return abbreviatedLabelPattern.MatchString(text) || hasAnyPrefix(lower, labelPrefixes)
+}
+
+func isRow(text string) bool {
+	return rowPattern.MatchString(text)
+}
+
+// isCompleteRow returns true once the row has its asset type code and the amount doesn't wrap onto the next line
+func isCompleteRow(text, next string) bool {
+	return isRow(text) && assetTypePattern.MatchString(text) &&
+		!strings.HasSuffix(text, "-") && !strings.HasPrefix(next, "-")
+}
+
+// startsTransaction returns true if a new row starts at the first line.
+// Rows start with an owner code or with a capitalized asset name that is followed by a complete row
+// before the next detail label, other lines are the continuation of a wrapped description.
+func startsTransaction(lines []textLine) bool {
+	first := lines[0].text
+	if ownerPrefixPattern.MatchString(first) {
+		return true
+	}
+	if first[0] < 'A' || first[0] > 'Z' {
+		return false
+	}
+	var window []textLine
+	for i := 0; i < len(lines) && i < detailWindow; i++ {
+		if i > 0 && isDetailLabel(lines[i].text, strings.ToLower(lines[i].text)) {
+			break
+		}
+		window = append(window, lines[i])
+		next := ""
+		if i+1 < len(lines) {
+			next = lines[i+1].text
+		}
+		if isCompleteRow(joinLines(window), next) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package ptr
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"github.com/paulschick/disclosureupdater/model"
+	"github.com/paulschick/disclosureupdater/pdfwriter"
+	"image"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// The fixtures are e-filed PTRs from the House clerk, saved as testdata/{DocId}.pdf, and PTR text layers in the
+// line layout of e-filed PDFs with pages separated by form feeds. The text layers are written to a PDF first, so
+// every fixture is read with ExtractTextPages. To add a filing, download it with download-pdfs, copy the PDF to
+// testdata named by its DocId and run `go test ./ptr -update` to generate its golden file.
+func TestTextParser_Golden(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdfs, err := filepath.Glob(filepath.Join("testdata", "*.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixtures = append(fixtures, pdfs...)
+	if len(fixtures) == 0 {
+		t.Fatal("no fixtures in testdata")
+	}
+	for _, fixture := range fixtures {
+		name := strings.TrimSuffix(filepath.Base(fixture), filepath.Ext(fixture))
+		t.Run(name, func(t *testing.T) {
+			pdfPath := fixture
+			if filepath.Ext(fixture) == ".txt" {
+				text, err := os.ReadFile(fixture)
+				if err != nil {
+					t.Fatal(err)
+				}
+				pdfPath = writeFixturePdf(t, strings.Split(string(text), "\f"))
+			}
+			pages, err := ExtractTextPages(pdfPath)
+			if err != nil {
+				t.Fatalf("ExtractTextPages() error = %v", err)
+			}
+			if !ContainsTableHeader(pages) {
+				t.Errorf("ContainsTableHeader() = false; want true")
+			}
+			docId, err := strconv.Atoi(name)
+			if err != nil {
+				docId = 20012345
+			}
+			transactions := NewTextParser(docId, name+".pdf").Parse(pages)
+			actual, err := json.MarshalIndent(transactions, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			goldenPath := filepath.Join("testdata", name+".golden.json")
+			if *update {
+				if err = os.WriteFile(goldenPath, append(actual, '\n'), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			expected, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(bytes.TrimSpace(actual), bytes.TrimSpace(expected)) {
+				t.Errorf("Parse() = %s; want %s", actual, expected)
+			}
+		})
+	}
+}
+
+// writeFixturePdf writes the pages to a letter size PDF, one text line of the fixture per line of the page
+func writeFixturePdf(t *testing.T, pages []string) string {
+	t.Helper()
+	const charWidth, wordHeight, lineHeight, margin = 8, 16, 24, 60
+	img := image.NewGray(image.Rect(0, 0, 1275, 1650))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	doc := pdfwriter.NewDocument()
+	for _, text := range pages {
+		words := make([]*model.OcrResult, 0)
+		for n, line := range strings.Split(strings.Trim(text, "\n"), "\n") {
+			top := margin + n*lineHeight
+			left := margin
+			for _, word := range strings.Fields(line) {
+				right := left + len([]rune(word))*charWidth
+				words = append(words, &model.OcrResult{Word: word, Left: left, Top: top, Right: right,
+					Bottom: top + wordHeight})
+				left = right + charWidth
+			}
+		}
+		doc.AddPage(612, 792, img, words)
+	}
+	pdfPath := filepath.Join(t.TempDir(), "fixture.pdf")
+	file, err := os.Create(pdfPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	if _, err = doc.WriteTo(file); err != nil {
+		t.Fatal(err)
+	}
+	return pdfPath
+}
+
+func TestContainsTableHeader(t *testing.T) {
+	pages := []string{"Financial Disclosure Report\nSchedule A: Assets and \"Unearned\" Income", ""}
+	if ContainsTableHeader(pages) {
+		t.Errorf("ContainsTableHeader() = true; want false")
+	}
+}
+
+func TestParseAsset(t *testing.T) {
+	tests := []struct {
+		text      string
+		name      string
+		ticker    string
+		assetType string
+	}{
+		{"Apple Inc. - Common Stock (AAPL) [ST]", "Apple Inc. - Common Stock", "AAPL", "ST"},
+		{"Alphabet Inc. - Class A Common Stock (GOOGL) [OP]", "Alphabet Inc. - Class A Common Stock", "GOOGL", "OP"},
+		{"Berkshire Hathaway Inc. (BRK.B) [ST]", "Berkshire Hathaway Inc.", "BRK.B", "ST"},
+		{"United States Treasury Bill [GS]", "United States Treasury Bill", "", "GS"},
+		{"Fidelity Contrafund (The)", "Fidelity Contrafund (The)", "", ""},
+	}
+	for _, test := range tests {
+		name, ticker, assetType := ParseAsset(test.text)
+		if name != test.name || ticker != test.ticker || assetType != test.assetType {
+			t.Errorf("ParseAsset(%q) = %q, %q, %q; want %q, %q, %q",
+				test.text, name, ticker, assetType, test.name, test.ticker, test.assetType)
+		}
+	}
+}
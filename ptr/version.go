@@ -0,0 +1,5 @@
+package ptr
+
+// ParserVersion is recorded on every parsed transaction. Increase it when a change to the parsers
+// changes their output, so exported transactions can be traced to the parser that produced them.
+const ParserVersion = "1.0.0"
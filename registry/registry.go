@@ -0,0 +1,498 @@
+package registry
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"github.com/gocarina/gocsv"
+	"github.com/paulschick/disclosureupdater/common/methods"
+	"github.com/paulschick/disclosureupdater/model"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// titles are dropped from first names, the index sometimes has them in First instead of Prefix
+var titles = map[string]bool{
+	"hon": true,
+	"mr":  true,
+	"mrs": true,
+	"ms":  true,
+	"dr":  true,
+}
+
+// suffixes are kept apart from last names, so a father and son with the same name are different people
+var suffixes = map[string]bool{
+	"jr":  true,
+	"sr":  true,
+	"ii":  true,
+	"iii": true,
+	"iv":  true,
+}
+
+// nicknames map common short first names to the full name
+var nicknames = map[string]string{
+	"andy":   "andrew",
+	"bill":   "william",
+	"bob":    "robert",
+	"bobby":  "robert",
+	"buddy":  "earl",
+	"chris":  "christopher",
+	"chuck":  "charles",
+	"dan":    "daniel",
+	"danny":  "daniel",
+	"dave":   "david",
+	"debbie": "deborah",
+	"dick":   "richard",
+	"don":    "donald",
+	"ed":     "edward",
+	"greg":   "gregory",
+	"jim":    "james",
+	"jimmy":  "james",
+	"joe":    "joseph",
+	"ken":    "kenneth",
+	"kathy":  "katherine",
+	"liz":    "elizabeth",
+	"matt":   "matthew",
+	"mike":   "michael",
+	"nick":   "nicholas",
+	"pat":    "patrick",
+	"rick":   "richard",
+	"rob":    "robert",
+	"ron":    "ronald",
+	"sam":    "samuel",
+	"steve":  "steven",
+	"tom":    "thomas",
+	"tony":   "anthony",
+	"will":   "william",
+}
+
+// person is a registered member with the values used to match filings to them
+type person struct {
+	*model.RegisteredMember
+	stateDsts map[string]bool
+	variants  map[string]bool
+	docIds    map[int]bool
+	// givenNames are the given names of every first name variant, by the joined names
+	givenNames map[string][]string
+}
+
+// Registry assigns a stable member ID to every filing of the disclosure index.
+// Filings are matched to a person by normalized last name, suffix, first name and state: titles, middle
+// initials and punctuation are ignored and common nicknames map to the full first name. A filing whose first
+// name doesn't match is still matched to a person with the same last name and suffix who filed from the same
+// district if their given names agree, where an initial agrees with a name it starts, e.g. "J. Robert" and
+// "James R.". The ID is derived from the earliest filing, e.g. "doe-jane-ca". A registry seeded with the members
+// written before keeps their IDs, and assigns new IDs only to people it hasn't seen before, so IDs don't change
+// when earlier years are added. Overrides take precedence over matching.
+type Registry struct {
+	overrides []*model.MemberOverride
+	people    map[string]*person
+	byKey     map[string]*person
+	byLast    map[string][]*person
+	byDocId   map[int]*person
+	// known are the people of seeded filings by DocId
+	known map[int]*person
+}
+
+func NewRegistry(overrides []*model.MemberOverride) *Registry {
+	return &Registry{
+		overrides: overrides,
+		people:    make(map[string]*person),
+		byKey:     make(map[string]*person),
+		byLast:    make(map[string][]*person),
+		byDocId:   make(map[int]*person),
+		known:     make(map[int]*person),
+	}
+}
+
+// Seed registers the members written by an earlier run, see LoadMembers. Their filings keep their IDs, and
+// filings of the same name and state are matched to them. Seed before Add.
+func (r *Registry) Seed(members []*model.RegisteredMember) {
+	for _, m := range members {
+		if m.MemberId == "" {
+			continue
+		}
+		p := r.people[m.MemberId]
+		if p == nil {
+			p = r.newPerson(m.MemberId)
+		}
+		seeded := *m
+		p.RegisteredMember = &seeded
+		for _, stateDst := range splitList(m.StateDsts) {
+			p.stateDsts[stateDst] = true
+		}
+		for _, variant := range splitList(m.Variants) {
+			p.variants[variant] = true
+		}
+		for _, docId := range splitList(m.DocIds) {
+			if id, err := strconv.Atoi(docId); err == nil {
+				p.docIds[id] = true
+				r.known[id] = p
+			}
+		}
+		p.addGivenNames(m.First)
+		last, first, suffix := NormalizeLast(m.Last), CanonicalFirst(m.First), suffixOf(m.Last, m.Suffix)
+		for stateDst := range p.stateDsts {
+			r.register(p, last, first, suffix, stateOf(stateDst))
+		}
+	}
+}
+
+// Add registers the filings, in order of year and filing date so IDs come from the earliest filings
+func (r *Registry) Add(members []*model.Member) {
+	sorted := make([]*model.Member, len(members))
+	copy(sorted, members)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Year != sorted[j].Year {
+			return sorted[i].Year < sorted[j].Year
+		}
+		di, _ := model.ParseDate(sorted[i].FilingDate)
+		dj, _ := model.ParseDate(sorted[j].FilingDate)
+		if !di.Equal(dj) {
+			return di.Before(dj)
+		}
+		return sorted[i].DocId < sorted[j].DocId
+	})
+	for _, m := range sorted {
+		r.add(m)
+	}
+}
+
+func (r *Registry) add(m *model.Member) {
+	last, first, state := NormalizeLast(m.Last), CanonicalFirst(m.First), stateOf(m.StateDst)
+	suffix := suffixOf(m.Last, m.Suffix)
+	var p *person
+	if id := r.override(m); id != "" {
+		p = r.people[id]
+		if p == nil {
+			p = r.newPerson(id)
+		}
+	} else if p = r.known[m.DocId]; p == nil {
+		if p = r.byKey[personKey(last, first, suffix, state)]; p == nil {
+			p = r.matchDistrict(m, last, suffix, state)
+		}
+	}
+	if p == nil {
+		p = r.newPerson(r.uniqueId(slug(last, first, suffix, state)))
+	}
+	r.register(p, last, first, suffix, state)
+	r.byDocId[m.DocId] = p
+
+	p.Name = strings.Join(strings.Fields(m.First+" "+m.Last+" "+m.Suffix), " ")
+	p.Last, p.First, p.Suffix = strings.TrimSpace(m.Last), strings.TrimSpace(m.First), strings.TrimSpace(m.Suffix)
+	if p.FirstYear == 0 || m.Year < p.FirstYear {
+		p.FirstYear = m.Year
+	}
+	if m.Year > p.LastYear {
+		p.LastYear = m.Year
+	}
+	p.docIds[m.DocId] = true
+	p.Filings = len(p.docIds)
+	p.stateDsts[m.StateDst] = true
+	p.variants[m.FullName()] = true
+	p.addGivenNames(m.First)
+}
+
+// register matches later filings with the names and state to the person
+func (r *Registry) register(p *person, last, first, suffix, state string) {
+	key := personKey(last, first, suffix, state)
+	if _, ok := r.byKey[key]; !ok {
+		r.byKey[key] = p
+	}
+	lastKey := personKey(last, "", suffix, state)
+	if !containsPerson(r.byLast[lastKey], p) {
+		r.byLast[lastKey] = append(r.byLast[lastKey], p)
+	}
+}
+
+// matchDistrict returns a person with the same last name and suffix who filed from the same district, and whose
+// given names agree with the filing's, e.g. "J. Robert" and "James R." but not "John" and "Jane"
+func (r *Registry) matchDistrict(m *model.Member, last, suffix, state string) *person {
+	names := givenNames(m.First)
+	if len(names) == 0 {
+		return nil
+	}
+	for _, p := range r.byLast[personKey(last, "", suffix, state)] {
+		if !p.stateDsts[m.StateDst] {
+			continue
+		}
+		for _, variant := range p.givenNames {
+			if namesAgree(names, variant) {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Registry) override(m *model.Member) string {
+	for _, o := range r.overrides {
+		if o.DocId != 0 {
+			if o.DocId == m.DocId {
+				return o.MemberId
+			}
+			continue
+		}
+		if o.Last != "" && NormalizeLast(o.Last) != NormalizeLast(m.Last) {
+			continue
+		}
+		if o.First != "" && CanonicalFirst(o.First) != CanonicalFirst(m.First) {
+			continue
+		}
+		if !strings.HasPrefix(strings.ToUpper(m.StateDst), strings.ToUpper(o.StateDst)) {
+			continue
+		}
+		return o.MemberId
+	}
+	return ""
+}
+
+func (r *Registry) newPerson(id string) *person {
+	p := &person{
+		RegisteredMember: &model.RegisteredMember{MemberId: id},
+		stateDsts:        make(map[string]bool),
+		variants:         make(map[string]bool),
+		docIds:           make(map[int]bool),
+		givenNames:       make(map[string][]string),
+	}
+	r.people[id] = p
+	return p
+}
+
+func (p *person) addGivenNames(first string) {
+	if names := givenNames(first); len(names) > 0 {
+		p.givenNames[strings.Join(names, " ")] = names
+	}
+}
+
+func (r *Registry) uniqueId(id string) string {
+	unique := id
+	for n := 2; r.people[unique] != nil; n++ {
+		unique = fmt.Sprintf("%s-%d", id, n)
+	}
+	return unique
+}
+
+// MemberId returns the member ID of the filing, or an empty string if the filing isn't registered
+func (r *Registry) MemberId(docId int) string {
+	if p, ok := r.byDocId[docId]; ok {
+		return p.MemberId
+	}
+	return ""
+}
+
+// Member returns the registered member of the filing, or nil if the filing isn't registered
+func (r *Registry) Member(docId int) *model.RegisteredMember {
+	if p, ok := r.byDocId[docId]; ok {
+		return p.registeredMember()
+	}
+	return nil
+}
+
+// Members returns every registered member sorted by ID
+func (r *Registry) Members() []*model.RegisteredMember {
+	members := make([]*model.RegisteredMember, 0, len(r.people))
+	for _, p := range r.people {
+		members = append(members, p.registeredMember())
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].MemberId < members[j].MemberId
+	})
+	return members
+}
+
+func (p *person) registeredMember() *model.RegisteredMember {
+	p.StateDsts = joinSorted(p.stateDsts)
+	p.Variants = joinSorted(p.variants)
+	docIds := make([]int, 0, len(p.docIds))
+	for docId := range p.docIds {
+		docIds = append(docIds, docId)
+	}
+	sort.Ints(docIds)
+	values := make([]string, len(docIds))
+	for i, docId := range docIds {
+		values[i] = strconv.Itoa(docId)
+	}
+	p.DocIds = strings.Join(values, ";")
+	return p.RegisteredMember
+}
+
+// LoadOverrides reads the member override file, which may be comma, tab or pipe separated
+func LoadOverrides(path string) ([]*model.MemberOverride, error) {
+	overrides := make([]*model.MemberOverride, 0)
+	if err := loadCsv(path, &overrides); err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// LoadMembers reads the members written by an earlier run to seed the registry with
+func LoadMembers(path string) ([]*model.RegisteredMember, error) {
+	members := make([]*model.RegisteredMember, 0)
+	if err := loadCsv(path, &members); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// loadCsv reads a comma, tab or pipe separated file into out
+func loadCsv(path string, out interface{}) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	reader := bufio.NewReader(f)
+	comma, err := methods.SniffDelimiter(reader)
+	if err != nil {
+		return err
+	}
+	gocsv.SetCSVReader(func(in io.Reader) gocsv.CSVReader {
+		r := csv.NewReader(in)
+		r.Comma = comma
+		return r
+	})
+	return gocsv.Unmarshal(reader, out)
+}
+
+// NormalizeLast lower cases the last name and drops suffixes and punctuation, see suffixOf
+func NormalizeLast(last string) string {
+	parts := make([]string, 0)
+	for _, token := range tokens(last) {
+		if !suffixes[token] {
+			parts = append(parts, token)
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// CanonicalFirst returns the lower cased first given name without titles and initials, nicknames are
+// replaced by the full name
+func CanonicalFirst(first string) string {
+	candidates := make([]string, 0)
+	for _, token := range tokens(first) {
+		if !titles[token] {
+			candidates = append(candidates, token)
+		}
+	}
+	for _, token := range candidates {
+		if len(token) > 1 {
+			if full, ok := nicknames[token]; ok {
+				return full
+			}
+			return token
+		}
+	}
+	// only initials
+	if len(candidates) > 0 {
+		return candidates[0]
+	}
+	return ""
+}
+
+func tokens(name string) []string {
+	return strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+}
+
+// suffixOf returns the lower cased suffix of the suffix field, or of the last name if the index has it there
+func suffixOf(last, suffix string) string {
+	parts := make([]string, 0)
+	for _, token := range append(tokens(last), tokens(suffix)...) {
+		if suffixes[token] && !slices.Contains(parts, token) {
+			parts = append(parts, token)
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// givenNames returns the given names of a first name, titles excluded and nicknames replaced by the full name
+func givenNames(first string) []string {
+	names := make([]string, 0)
+	for _, token := range tokens(first) {
+		if titles[token] {
+			continue
+		}
+		if full, ok := nicknames[token]; ok {
+			token = full
+		}
+		names = append(names, token)
+	}
+	return names
+}
+
+// namesAgree returns true if the given names agree in order, where an initial agrees with every name it starts.
+// Names without a counterpart, e.g. a middle name missing from one of them, don't disagree.
+func namesAgree(a, b []string) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] == b[i] {
+			continue
+		}
+		if len(a[i]) == 1 && strings.HasPrefix(b[i], a[i]) || len(b[i]) == 1 && strings.HasPrefix(a[i], b[i]) {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+func stateOf(stateDst string) string {
+	if len(stateDst) > 2 {
+		return strings.ToUpper(stateDst[:2])
+	}
+	return strings.ToUpper(stateDst)
+}
+
+// personKey identifies a person by names and state, the first name is empty for the key of a last name
+func personKey(last, first, suffix, state string) string {
+	return last + "|" + suffix + "|" + first + "|" + state
+}
+
+func slug(last, first, suffix, state string) string {
+	parts := make([]string, 0, 4)
+	for _, part := range []string{last, first, suffix, strings.ToLower(state)} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return "unknown"
+	}
+	return strings.Join(parts, "-")
+}
+
+func splitList(value string) []string {
+	values := make([]string, 0)
+	for _, v := range strings.Split(value, ";") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func joinSorted(values map[string]bool) string {
+	sorted := make([]string, 0, len(values))
+	for value := range values {
+		sorted = append(sorted, value)
+	}
+	sort.Strings(sorted)
+	return strings.Join(sorted, ";")
+}
+
+func containsPerson(people []*person, p *person) bool {
+	for _, candidate := range people {
+		if candidate == p {
+			return true
+		}
+	}
+	return false
+}
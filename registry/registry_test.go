@@ -0,0 +1,171 @@
+package registry
+
+import (
+	"github.com/paulschick/disclosureupdater/model"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRegistry_MemberId(t *testing.T) {
+	members := []*model.Member{
+		{DocId: 1, Prefix: "Hon.", First: "James", Last: "Smith", StateDst: "OH03", Year: 2019, FilingDate: "5/1/2019"},
+		{DocId: 2, Prefix: "Hon.", First: "Jim", Last: "Smith", StateDst: "OH03", Year: 2020, FilingDate: "5/1/2020"},
+		{DocId: 3, First: "James R.", Last: "Smith Jr.", StateDst: "OH05", Year: 2022, FilingDate: "5/1/2022"},
+		{DocId: 4, First: "J. Robert", Last: "Smith", StateDst: "OH03", Year: 2021, FilingDate: "5/1/2021"},
+		{DocId: 5, First: "John", Last: "Smith", StateDst: "OH09", Year: 2021, FilingDate: "5/1/2021"},
+		{DocId: 6, First: "Debbie", Last: "Wasserman Schultz", StateDst: "FL23", Year: 2020, FilingDate: "5/1/2020"},
+		{DocId: 7, First: "Deborah", Last: "Wasserman-Schultz", StateDst: "FL25", Year: 2023, FilingDate: "5/1/2023"},
+		{DocId: 8, First: "Buddy", Last: "Carter", StateDst: "GA01", Year: 2020, FilingDate: "5/1/2020"},
+		{DocId: 9, First: "Earl L.", Last: "Carter", StateDst: "GA01", Year: 2021, FilingDate: "5/1/2021"},
+		{DocId: 10, First: "French", Last: "Hill", StateDst: "AR02", Year: 2020, FilingDate: "5/1/2020"},
+		{DocId: 11, First: "J. French", Last: "Hill", StateDst: "AR02", Year: 2021, FilingDate: "5/1/2021"},
+	}
+	overrides := []*model.MemberOverride{
+		{MemberId: "hill-french-ar", Last: "Hill", StateDst: "AR"},
+	}
+	registry := NewRegistry(overrides)
+	registry.Add(members)
+
+	tests := []struct {
+		docId    int
+		memberId string
+	}{
+		{1, "smith-james-oh"},
+		{2, "smith-james-oh"},
+		// the son, the suffix is kept apart from the last name
+		{3, "smith-james-jr-oh"},
+		// same district and initial
+		{4, "smith-james-oh"},
+		{5, "smith-john-oh"},
+		{6, "wassermanschultz-deborah-fl"},
+		{7, "wassermanschultz-deborah-fl"},
+		{8, "carter-earl-ga"},
+		{9, "carter-earl-ga"},
+		{10, "hill-french-ar"},
+		{11, "hill-french-ar"},
+	}
+	for _, test := range tests {
+		if actual := registry.MemberId(test.docId); actual != test.memberId {
+			t.Errorf("MemberId(%d) = %q; want %q", test.docId, actual, test.memberId)
+		}
+	}
+
+	smith := registry.Member(1)
+	if smith.FirstYear != 2019 || smith.LastYear != 2021 || smith.Filings != 3 || smith.StateDsts != "OH03" ||
+		smith.DocIds != "1;2;4" {
+		t.Errorf("Member(1) = %+v", *smith)
+	}
+	if len(registry.Members()) != 6 {
+		t.Errorf("Members() returned %d members; want 6", len(registry.Members()))
+	}
+}
+
+func TestRegistry_StableIds(t *testing.T) {
+	first := NewRegistry(nil)
+	first.Add([]*model.Member{
+		{DocId: 1, First: "Jane", Last: "Doe", StateDst: "CA12", Year: 2020},
+	})
+	second := NewRegistry(nil)
+	second.Add([]*model.Member{
+		{DocId: 2, First: "Jane", Last: "Doe", StateDst: "CA11", Year: 2023},
+		{DocId: 1, First: "Jane", Last: "Doe", StateDst: "CA12", Year: 2020},
+	})
+	if first.MemberId(1) != second.MemberId(1) || second.MemberId(1) != second.MemberId(2) {
+		t.Errorf("MemberId() = %q, %q, %q; want the same ID", first.MemberId(1), second.MemberId(1), second.MemberId(2))
+	}
+}
+
+func TestRegistry_DistinctPeople(t *testing.T) {
+	members := []*model.Member{
+		{DocId: 1, First: "Robert", Last: "Jones", Suffix: "Sr.", StateDst: "TX04", Year: 2019},
+		{DocId: 2, First: "Robert", Last: "Jones Jr.", StateDst: "TX04", Year: 2023},
+		// successor spouse in the same district
+		{DocId: 3, First: "John", Last: "Miller", StateDst: "PA09", Year: 2019},
+		{DocId: 4, First: "Jane", Last: "Miller", StateDst: "PA09", Year: 2021},
+		// an initial only agrees with the name it starts
+		{DocId: 5, First: "J. Robert", Last: "Miller", StateDst: "PA09", Year: 2022},
+		{DocId: 6, First: "R. J.", Last: "Miller", StateDst: "PA09", Year: 2023},
+	}
+	registry := NewRegistry(nil)
+	registry.Add(members)
+	tests := []struct {
+		docId    int
+		memberId string
+	}{
+		{1, "jones-robert-sr-tx"},
+		{2, "jones-robert-jr-tx"},
+		{3, "miller-john-pa"},
+		{4, "miller-jane-pa"},
+		{5, "miller-john-pa"},
+		{6, "miller-r-pa"},
+	}
+	for _, test := range tests {
+		if actual := registry.MemberId(test.docId); actual != test.memberId {
+			t.Errorf("MemberId(%d) = %q; want %q", test.docId, actual, test.memberId)
+		}
+	}
+}
+
+func TestRegistry_Seed(t *testing.T) {
+	later := []*model.Member{
+		{DocId: 2, First: "James R.", Last: "Smith", StateDst: "OH03", Year: 2023},
+		{DocId: 3, First: "Jane", Last: "Doe", StateDst: "NY10", Year: 2023},
+	}
+	first := NewRegistry(nil)
+	first.Add(later)
+
+	// an earlier year is downloaded, its filing would give Smith the ID smith-robert-oh without the seed
+	earlier := []*model.Member{
+		{DocId: 1, First: "J. Robert", Last: "Smith", StateDst: "OH03", Year: 2020},
+	}
+	unseeded := NewRegistry(nil)
+	unseeded.Add(append(earlier, later...))
+	if unseeded.MemberId(2) != "smith-robert-oh" {
+		t.Fatalf("MemberId(2) without seed = %q", unseeded.MemberId(2))
+	}
+	second := NewRegistry(nil)
+	second.Seed(first.Members())
+	second.Add(append(earlier, later...))
+	for _, docId := range []int{1, 2} {
+		if second.MemberId(docId) != "smith-james-oh" {
+			t.Errorf("MemberId(%d) = %q; want %q", docId, second.MemberId(docId), "smith-james-oh")
+		}
+	}
+	smith := second.Member(2)
+	if smith.Filings != 2 || smith.FirstYear != 2020 || smith.LastYear != 2023 || smith.DocIds != "1;2" {
+		t.Errorf("Member(2) = %+v", *smith)
+	}
+	if len(second.Members()) != 2 {
+		t.Errorf("Members() returned %d members; want 2", len(second.Members()))
+	}
+
+	path := filepath.Join(t.TempDir(), "members.csv")
+	content := "memberId\tname\tlast\tfirst\tsuffix\tstateDsts\tfirstYear\tlastYear\tfilings\tvariants\tdocIds\n" +
+		"doe-jane-ca\tJane Doe\tDoe\tJane\t\tCA11;CA12\t2020\t2023\t2\tJane Doe\t1;2\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	members, err := LoadMembers(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 1 || members[0].MemberId != "doe-jane-ca" || members[0].DocIds != "1;2" {
+		t.Errorf("LoadMembers() = %+v", members)
+	}
+}
+
+func TestLoadOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "member_overrides.csv")
+	content := "memberId,docId,last,first,stateDst\nhill-french-ar,,Hill,,AR\ndoe-jane-ca,20012345,,,\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	overrides, err := LoadOverrides(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(overrides) != 2 || overrides[0].Last != "Hill" || overrides[1].DocId != 20012345 {
+		t.Errorf("LoadOverrides() = %+v, %+v", *overrides[0], *overrides[1])
+	}
+}
@@ -0,0 +1,135 @@
+package s3client
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/paulschick/disclosureupdater/model"
+	"maps"
+	"mime"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// User metadata of disclosure PDFs, S3 returns the names in lower case
+const (
+	MetadataDocId      = "docid"
+	MetadataMember     = "member"
+	MetadataFilingType = "filingtype"
+	MetadataYear       = "year"
+	MetadataFilingDate = "filingdate"
+	MetadataStateDst   = "statedst"
+)
+
+// Tags of disclosure PDFs, for bucket policies and lifecycle rules
+const (
+	TagDocId      = "docId"
+	TagFilingType = "filingType"
+	TagYear       = "year"
+	TagStateDst   = "stateDst"
+)
+
+// maxObjectTags is the maximum number of tags of an object
+const maxObjectTags = 10
+
+// memberTagCount is the number of tags memberTags returns, the configured tags have to fit next to them
+const memberTagCount = 4
+
+// memberOf returns the member of a file named like a disclosure PDF, nil for other files. The entry of the
+// disclosure index is returned if the service has it, it has the filing date and the full filing type.
+func (s *S3ServiceV2) memberOf(fileName string) *model.Member {
+	if !strings.HasSuffix(fileName, ".pdf") {
+		return nil
+	}
+	member, err := model.ParsePdfFileName(filepath.Base(fileName))
+	if err != nil {
+		return nil
+	}
+	if indexed, ok := s.Members[member.DocId]; ok {
+		return indexed
+	}
+	return member
+}
+
+// setObjectAttributes sets the server-side encryption, tags and user metadata of an upload.
+// Files named like disclosure PDFs get the metadata and tags of their member.
+func (s *S3ServiceV2) setObjectAttributes(input *s3.PutObjectInput, fileName string) {
+	switch s.Upload.Encryption {
+	case EncryptionSseS3:
+		input.ServerSideEncryption = types.ServerSideEncryptionAes256
+	case EncryptionSseKms:
+		input.ServerSideEncryption = types.ServerSideEncryptionAwsKms
+		if s.Upload.KmsKeyId != "" {
+			input.SSEKMSKeyId = aws.String(s.Upload.KmsKeyId)
+		}
+	}
+	tags := maps.Clone(s.Upload.Tags)
+	if member := s.memberOf(fileName); member != nil {
+		if input.Metadata == nil {
+			input.Metadata = make(map[string]string)
+		}
+		maps.Copy(input.Metadata, memberMetadata(member))
+		if tags == nil {
+			tags = make(map[string]string)
+		}
+		maps.Copy(tags, memberTags(member))
+	}
+	if len(tags) > 0 {
+		input.Tagging = aws.String(encodeTagging(tags))
+	}
+}
+
+// memberMetadata returns the user metadata of a member's PDF. Metadata is sent in headers, so the member's name
+// is encoded as an RFC 2047 word if it isn't ASCII.
+func memberMetadata(member *model.Member) map[string]string {
+	metadata := map[string]string{
+		MetadataDocId:    strconv.Itoa(member.DocId),
+		MetadataMember:   mime.QEncoding.Encode("utf-8", member.FullName()),
+		MetadataYear:     strconv.Itoa(member.Year),
+		MetadataStateDst: member.StateDst,
+	}
+	if member.FilingType != "" {
+		metadata[MetadataFilingType] = member.FilingType
+	}
+	if member.FilingDate != "" {
+		metadata[MetadataFilingDate] = member.FilingDate
+	}
+	return metadata
+}
+
+// memberTags returns the tags of a member's PDF, the filing type is ptr or financial like in object keys
+func memberTags(member *model.Member) map[string]string {
+	return map[string]string{
+		TagDocId:      strconv.Itoa(member.DocId),
+		TagFilingType: filingKind(member),
+		TagYear:       strconv.Itoa(member.Year),
+		TagStateDst:   member.StateDst,
+	}
+}
+
+// encodeTagging returns the tags as the URL query of the x-amz-tagging header, sorted by key
+func encodeTagging(tags map[string]string) string {
+	values := make(url.Values, len(tags))
+	for key, value := range tags {
+		values.Set(key, value)
+	}
+	return values.Encode()
+}
+
+// validateTags checks that the configured tags fit next to the tags of disclosure PDFs
+func validateTags(tags map[string]string) error {
+	if len(tags) > maxObjectTags-memberTagCount {
+		return fmt.Errorf("%d tags configured, at most %d fit next to the tags of disclosure PDFs", len(tags),
+			maxObjectTags-memberTagCount)
+	}
+	for key := range tags {
+		if key == "" {
+			return errors.New("tag without a key")
+		}
+	}
+	return nil
+}
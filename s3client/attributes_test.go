@@ -0,0 +1,117 @@
+package s3client
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/paulschick/disclosureupdater/internal/s3test"
+	"github.com/paulschick/disclosureupdater/model"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSetObjectAttributes(t *testing.T) {
+	indexed := &model.Member{Prefix: "Hon.", First: "Nydia", Last: "Velázquez", FilingType: "P", StateDst: "NY07",
+		Year: 2024, FilingDate: "3/1/2024", DocId: 20024000}
+	s := &S3ServiceV2{
+		Upload:  model.S3UploadConfig{Encryption: EncryptionSseKms, KmsKeyId: "alias/disclosures"},
+		Members: map[int]*model.Member{indexed.DocId: indexed},
+	}
+	tests := []struct {
+		name         string
+		fileName     string
+		tags         map[string]string
+		wantMetadata map[string]string
+		wantTagging  string
+	}{
+		{"other file", "/data/csv/transactions.csv", map[string]string{"project": "disclosures"},
+			map[string]string{Sha256MetadataKey: "abc"}, "project=disclosures"},
+		{"indexed pdf", "2024.ptr-pdfs.NY07.Velazquez.Nydia.20024000.pdf", nil,
+			map[string]string{Sha256MetadataKey: "abc", MetadataDocId: "20024000",
+				MetadataMember: "=?utf-8?q?Hon._Nydia_Vel=C3=A1zquez?=", MetadataFilingType: "P", MetadataYear: "2024",
+				MetadataFilingDate: "3/1/2024", MetadataStateDst: "NY07"},
+			"docId=20024000&filingType=ptr&stateDst=NY07&year=2024"},
+		{"pdf without index entry", "/data/disclosures/2023.pdfs.CA12.Doe.Jane_Q.10055555.pdf",
+			map[string]string{"project": "disclosures", "year": "overridden"},
+			map[string]string{Sha256MetadataKey: "abc", MetadataDocId: "10055555", MetadataMember: "Jane Q Doe",
+				MetadataYear: "2023", MetadataStateDst: "CA12"},
+			"docId=10055555&filingType=financial&project=disclosures&stateDst=CA12&year=2023"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.Upload.Tags = tt.tags
+			input := &s3.PutObjectInput{Metadata: map[string]string{Sha256MetadataKey: "abc"}}
+			s.setObjectAttributes(input, tt.fileName)
+			if input.ServerSideEncryption != types.ServerSideEncryptionAwsKms ||
+				aws.ToString(input.SSEKMSKeyId) != "alias/disclosures" {
+				t.Errorf("encryption = %q, %q", input.ServerSideEncryption, aws.ToString(input.SSEKMSKeyId))
+			}
+			if !reflect.DeepEqual(input.Metadata, tt.wantMetadata) {
+				t.Errorf("Metadata = %v, want %v", input.Metadata, tt.wantMetadata)
+			}
+			if got := aws.ToString(input.Tagging); got != tt.wantTagging {
+				t.Errorf("Tagging = %q, want %q", got, tt.wantTagging)
+			}
+		})
+	}
+}
+
+func TestUploadFileAttributes(t *testing.T) {
+	fake, server := s3test.NewServer(t)
+	s3Profile := &model.S3StaticProfile{
+		S3DefaultProfile: model.S3DefaultProfile{
+			S3Bucket:     "disclosures",
+			S3Hostname:   server.URL,
+			UsePathStyle: true,
+			S3Upload: model.S3UploadConfig{
+				Encryption: EncryptionSseS3,
+				Tags:       map[string]string{"project": "disclosures"},
+			},
+		},
+		S3ApiKey:    "apiKey",
+		S3SecretKey: "secretKey",
+	}
+	service, err := NewS3ServiceV2(s3Profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp := filepath.Join(t.TempDir(), "2024.ptr-pdfs.CA12.Doe.Jane.20012345.pdf")
+	if err = os.WriteFile(fp, []byte("pdf"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	if _, err = service.UploadFile(file, "2024/ptr/20012345.pdf", defaultArtifactClass(ClassDisclosures)); err != nil {
+		t.Fatal(err)
+	}
+
+	var put *http.Request
+	for _, r := range fake.Requests() {
+		if r.Method == http.MethodPut {
+			put = r
+		}
+	}
+	if put == nil {
+		t.Fatal("no PutObject request")
+	}
+	want := map[string]string{
+		"X-Amz-Server-Side-Encryption": "AES256",
+		"X-Amz-Tagging":                "docId=20012345&filingType=ptr&project=disclosures&stateDst=CA12&year=2024",
+		"X-Amz-Meta-Docid":             "20012345",
+		"X-Amz-Meta-Member":            "Jane Doe",
+		"X-Amz-Meta-Filingtype":        "P",
+	}
+	for name, value := range want {
+		if got := put.Header.Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package s3client
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	conf "github.com/paulschick/disclosureupdater/config"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// BucketIndexFileName is the bucket index in the s3 folder, one JSON object per line
+	BucketIndexFileName = "s3_objects.jsonl"
+	// legacyBucketIndexFileName is the index of earlier versions, one key per line
+	legacyBucketIndexFileName = "s3_objects.txt"
+)
+
+// BucketObject is an object of the bucket index
+type BucketObject struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	ETag         string    `json:"etag"`
+	StorageClass string    `json:"storageClass"`
+	LastModified time.Time `json:"lastModified"`
+}
+
+func NewBucketObject(obj types.Object) *BucketObject {
+	return &BucketObject{
+		Key:          aws.ToString(obj.Key),
+		Size:         aws.ToInt64(obj.Size),
+		ETag:         strings.Trim(aws.ToString(obj.ETag), `"`),
+		StorageClass: string(obj.StorageClass),
+		LastModified: aws.ToTime(obj.LastModified),
+	}
+}
+
+// BucketIndex is the bucket index by object key
+type BucketIndex map[string]*BucketObject
+
+// Changed returns a reason if the local file has to be uploaded: missing if the key isn't in the index,
+// size or checksum if the object differs. Objects of the legacy index only have a key, they are never changed.
+func (ix BucketIndex) Changed(f *SyncFile, checksum func(*SyncFile) (string, error)) (string, error) {
+	obj, ok := ix[f.Key]
+	if !ok {
+		return "missing", nil
+	}
+	if obj.Size == 0 && obj.ETag == "" {
+		return "", nil
+	}
+	changed, err := differs(f, &SyncFile{Key: obj.Key, Size: obj.Size, ETag: obj.ETag}, checksum)
+	if err != nil || !changed {
+		return "", err
+	}
+	if f.Size != obj.Size {
+		return "size", nil
+	}
+	return "checksum", nil
+}
+
+// bucketIndexPath returns the path of the bucket index in the s3 folder
+func bucketIndexPath(commonDirs *conf.CommonDirs) string {
+	return filepath.Join(commonDirs.S3Folder, BucketIndexFileName)
+}
+
+// LoadBucketIndex reads the bucket index written by WriteBucketObjects. The key only index of earlier
+// versions is read if there is no JSON Lines index, os.ErrNotExist is returned if neither exists.
+func LoadBucketIndex(commonDirs *conf.CommonDirs) (BucketIndex, error) {
+	file, err := os.Open(bucketIndexPath(commonDirs))
+	if errors.Is(err, os.ErrNotExist) {
+		return loadLegacyBucketIndex(filepath.Join(commonDirs.S3Folder, legacyBucketIndexFileName))
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	return readBucketIndex(file)
+}
+
+func readBucketIndex(r io.Reader) (BucketIndex, error) {
+	index := make(BucketIndex)
+	decoder := json.NewDecoder(r)
+	for {
+		obj := &BucketObject{}
+		err := decoder.Decode(obj)
+		if errors.Is(err, io.EOF) {
+			return index, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading bucket index: %w", err)
+		}
+		index[obj.Key] = obj
+	}
+}
+
+func loadLegacyBucketIndex(path string) (BucketIndex, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	index := make(BucketIndex)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// Bucket lines contain the object key
+		index[scanner.Text()] = &BucketObject{Key: scanner.Text()}
+	}
+	return index, scanner.Err()
+}
+
+// SaveBucketIndex writes the index sorted by key, e.g. after adding the uploaded objects
+func SaveBucketIndex(commonDirs *conf.CommonDirs, index BucketIndex) error {
+	keys := make([]string, 0, len(index))
+	for key := range index {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	objects := make([]*BucketObject, len(keys))
+	for i, key := range keys {
+		objects[i] = index[key]
+	}
+	fp := bucketIndexPath(commonDirs)
+	tmpFp := fp + ".tmp"
+	file, err := os.Create(tmpFp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	err = writeBucketObjects(w, objects)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpFp, fp)
+	}
+	if err != nil {
+		_ = os.Remove(tmpFp)
+	}
+	return err
+}
+
+// writeBucketObjects writes the objects as JSON Lines
+func writeBucketObjects(w io.Writer, objects []*BucketObject) error {
+	encoder := json.NewEncoder(w)
+	for _, obj := range objects {
+		if err := encoder.Encode(obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package s3client
+
+import (
+	"errors"
+	conf "github.com/paulschick/disclosureupdater/config"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBucketIndex_Changed(t *testing.T) {
+	index := BucketIndex{
+		"same":      {Key: "same", Size: 3, ETag: "abc"},
+		"size":      {Key: "size", Size: 4, ETag: "abc"},
+		"checksum":  {Key: "checksum", Size: 3, ETag: "abc"},
+		"multipart": {Key: "multipart", Size: 3, ETag: "abc-2"},
+		"legacy":    {Key: "legacy"},
+	}
+	// the test checksum is the local path
+	checksum := func(f *SyncFile) (string, error) {
+		return f.path, nil
+	}
+	tests := []struct {
+		file *SyncFile
+		want string
+	}{
+		{&SyncFile{Key: "same", Size: 3, path: "ABC"}, ""},
+		{&SyncFile{Key: "missing", Size: 3}, "missing"},
+		{&SyncFile{Key: "size", Size: 3, path: "abc"}, "size"},
+		{&SyncFile{Key: "checksum", Size: 3, path: "xyz"}, "checksum"},
+		{&SyncFile{Key: "multipart", Size: 3, path: "xyz"}, ""},
+		{&SyncFile{Key: "legacy", Size: 3, path: "xyz"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file.Key, func(t *testing.T) {
+			got, err := index.Changed(tt.file, checksum)
+			if err != nil || got != tt.want {
+				t.Errorf("Changed() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadBucketIndex(t *testing.T) {
+	commonDirs := &conf.CommonDirs{S3Folder: t.TempDir()}
+	if _, err := LoadBucketIndex(commonDirs); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("LoadBucketIndex() without index error = %v, want os.ErrNotExist", err)
+	}
+
+	legacy := "house/2023/ptr/20012345.pdf\nhouse/images/a/a-0.png\n"
+	if err := os.WriteFile(filepath.Join(commonDirs.S3Folder, legacyBucketIndexFileName), []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	index, err := LoadBucketIndex(commonDirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := BucketIndex{
+		"house/2023/ptr/20012345.pdf": {Key: "house/2023/ptr/20012345.pdf"},
+		"house/images/a/a-0.png":      {Key: "house/images/a/a-0.png"},
+	}
+	if !reflect.DeepEqual(index, want) {
+		t.Errorf("LoadBucketIndex() of the legacy index = %v, want %v", index, want)
+	}
+
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	index["house/2023/ptr/20012345.pdf"] = &BucketObject{Key: "house/2023/ptr/20012345.pdf", Size: 1024,
+		ETag: "abc", StorageClass: "STANDARD", LastModified: modified}
+	if err = SaveBucketIndex(commonDirs, index); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadBucketIndex(commonDirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, index) {
+		t.Errorf("LoadBucketIndex() = %v, want %v", got, index)
+	}
+}
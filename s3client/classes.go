@@ -0,0 +1,168 @@
+package s3client
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/paulschick/disclosureupdater/common/constants"
+	conf "github.com/paulschick/disclosureupdater/config"
+	"github.com/paulschick/disclosureupdater/model"
+	"github.com/paulschick/disclosureupdater/storage"
+	"mime"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Artifact classes, named after the data folder they are uploaded from
+const (
+	ClassDisclosures = constants.DefaultDisclosuresFolder
+	ClassSearchable  = constants.DefaultSearchableFolder
+	ClassImages      = constants.DefaultImageFolder
+	ClassOcr         = constants.DefaultOcrFolder
+	ClassCsv         = constants.DefaultCsvFolder
+	ClassExports     = constants.DefaultExportFolder
+)
+
+// ArtifactClassNames are the artifact classes in pipeline order
+var ArtifactClassNames = []string{ClassDisclosures, ClassSearchable, ClassImages, ClassOcr, ClassCsv, ClassExports}
+
+// contentTypes are the content types of extensions that mime doesn't know on every system
+var contentTypes = map[string]string{
+	".pdf":     "application/pdf",
+	".png":     "image/png",
+	".jpg":     "image/jpeg",
+	".jpeg":    "image/jpeg",
+	".csv":     "text/csv",
+	".tsv":     "text/tab-separated-values",
+	".hocr":    "text/vnd.hocr+html",
+	".xml":     "application/xml",
+	".json":    "application/json",
+	".jsonl":   "application/x-ndjson",
+	".parquet": "application/vnd.apache.parquet",
+	".gob":     "application/octet-stream",
+}
+
+// ArtifactClass is a kind of file uploaded to the bucket. Objects of a class are stored under its prefix,
+// which keeps artifacts with the same file names apart, e.g. the original and the searchable PDFs.
+type ArtifactClass struct {
+	Name   string
+	Prefix string
+	// ContentType of every object of the class, by file extension if empty
+	ContentType string
+	// StorageClass of the objects, the bucket's default if empty
+	StorageClass string
+}
+
+// defaultArtifactClass returns the class without configuration. The csv folder holds the OCR TSVs and
+// manifests such as transactions.csv and filings.csv, which are tab separated despite their extension.
+func defaultArtifactClass(name string) *ArtifactClass {
+	class := &ArtifactClass{Name: name, Prefix: name}
+	switch name {
+	case ClassDisclosures:
+		// PDFs were uploaded to the root of the key scheme before classes existed
+		class.Prefix = ""
+		class.ContentType = contentTypes[".pdf"]
+	case ClassSearchable:
+		class.Prefix = SearchableKeyPrefix
+		class.ContentType = contentTypes[".pdf"]
+	case ClassCsv:
+		class.ContentType = contentTypes[".tsv"]
+	}
+	return class
+}
+
+// ArtifactClassFromProfile returns the artifact class with the prefix, content type and storage class
+// configured in the profile
+func ArtifactClassFromProfile(s3Profile model.S3Profile, name string) (*ArtifactClass, error) {
+	if !slices.Contains(ArtifactClassNames, name) {
+		return nil, fmt.Errorf("unknown artifact class %q, expected one of %s", name, strings.Join(ArtifactClassNames, ", "))
+	}
+	class := defaultArtifactClass(name)
+	classConfig := s3Profile.GetClass(name)
+	if classConfig == nil {
+		return class, nil
+	}
+	if classConfig.Prefix != "" {
+		class.Prefix = strings.Trim(classConfig.Prefix, "/")
+	}
+	if classConfig.ContentType != "" {
+		class.ContentType = classConfig.ContentType
+	}
+	if classConfig.StorageClass != "" {
+		storageClass := strings.ToUpper(classConfig.StorageClass)
+		if !slices.Contains(types.StorageClass("").Values(), types.StorageClass(storageClass)) {
+			return nil, fmt.Errorf("invalid storage class %q of artifact class %s", classConfig.StorageClass, name)
+		}
+		class.StorageClass = storageClass
+	}
+	return class, nil
+}
+
+// Dir returns the data folder the class is uploaded from
+func (c *ArtifactClass) Dir(commonDirs *conf.CommonDirs) string {
+	switch c.Name {
+	case ClassDisclosures:
+		return commonDirs.DisclosuresFolder
+	case ClassSearchable:
+		return commonDirs.SearchableFolder
+	case ClassImages:
+		return commonDirs.ImageFolder
+	case ClassOcr:
+		return commonDirs.OcrFolder
+	case ClassCsv:
+		return commonDirs.CsvFolder
+	}
+	return commonDirs.ExportFolder
+}
+
+// IsPdf returns true for the classes whose keys are built by the key template
+func (c *ArtifactClass) IsPdf() bool {
+	return c.Name == ClassDisclosures || c.Name == ClassSearchable
+}
+
+// ContentTypeOf returns the content type of a file of the class
+func (c *ArtifactClass) ContentTypeOf(fileName string) string {
+	if c.ContentType != "" {
+		return c.ContentType
+	}
+	ext := strings.ToLower(filepath.Ext(fileName))
+	if contentType, ok := contentTypes[ext]; ok {
+		return contentType
+	}
+	if contentType := mime.TypeByExtension(ext); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// Backend returns the objects of the class as a storage backend, keyed by their path under the class prefix.
+// The keys of disclosure PDFs are built by the key scheme, see KeyScheme.Key. Objects are uploaded with the
+// encryption and tags of the profile like UploadFile.
+func (s *S3ServiceV2) Backend(class *ArtifactClass) *storage.S3 {
+	scheme := KeySchemeFromProfile(s.S3Profile)
+	b := storage.NewS3(s.Client, s.Uploader, s.S3Profile.GetBucket(), path.Join(scheme.Prefix, class.Prefix))
+	b.PrepareUpload = func(input *s3.PutObjectInput) {
+		s.setObjectAttributes(input, path.Base(aws.ToString(input.Key)))
+	}
+	return b
+}
+
+// PdfBackend returns the PDFs of a PDF class as a storage backend keyed by their file names, which are mapped to
+// object keys by the key scheme. Only the PDFs of fileNames, or stored through the backend, are listed, since
+// the key template can't be turned back into a file name.
+func (s *S3ServiceV2) PdfBackend(class *ArtifactClass, fileNames []string) *storage.Named {
+	b := s.Backend(class)
+	named := storage.NewNamed(b, KeySchemeFromProfile(s.S3Profile).Name, fileNames)
+	b.PrepareUpload = func(input *s3.PutObjectInput) {
+		key := strings.TrimPrefix(strings.TrimPrefix(aws.ToString(input.Key), b.Prefix), "/")
+		fileName, ok := named.Name(key)
+		if !ok {
+			fileName = path.Base(key)
+		}
+		s.setObjectAttributes(input, fileName)
+	}
+	return named
+}
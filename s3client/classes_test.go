@@ -0,0 +1,114 @@
+package s3client
+
+import (
+	"context"
+	"github.com/paulschick/disclosureupdater/internal/s3test"
+	"github.com/paulschick/disclosureupdater/model"
+	"github.com/paulschick/disclosureupdater/storage"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestArtifactClassFromProfile(t *testing.T) {
+	profile := &model.S3DefaultProfile{S3Classes: map[string]*model.S3ClassConfig{
+		ClassImages:  {Prefix: "/pages/", StorageClass: "standard_ia"},
+		ClassExports: {ContentType: "application/octet-stream"},
+		ClassOcr:     {StorageClass: "COLD"},
+	}}
+	tests := []struct {
+		name    string
+		class   string
+		want    *ArtifactClass
+		wantErr bool
+	}{
+		{"pdf default", ClassDisclosures, &ArtifactClass{Name: ClassDisclosures, ContentType: "application/pdf"}, false},
+		{"searchable default", ClassSearchable, &ArtifactClass{Name: ClassSearchable, Prefix: SearchableKeyPrefix,
+			ContentType: "application/pdf"}, false},
+		{"csv default", ClassCsv, &ArtifactClass{Name: ClassCsv, Prefix: "csv",
+			ContentType: "text/tab-separated-values"}, false},
+		{"configured prefix and storage class", ClassImages, &ArtifactClass{Name: ClassImages, Prefix: "pages",
+			StorageClass: "STANDARD_IA"}, false},
+		{"configured content type", ClassExports, &ArtifactClass{Name: ClassExports, Prefix: "exports",
+			ContentType: "application/octet-stream"}, false},
+		{"invalid storage class", ClassOcr, nil, true},
+		{"unknown class", "logs", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ArtifactClassFromProfile(profile, tt.class)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ArtifactClassFromProfile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ArtifactClassFromProfile() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArtifactClass_ContentTypeOf(t *testing.T) {
+	tests := []struct {
+		class    string
+		fileName string
+		want     string
+	}{
+		{ClassImages, "a/a-0.png", "image/png"},
+		{ClassImages, "a/a-0.JPG", "image/jpeg"},
+		{ClassOcr, "a-0.hocr", "text/vnd.hocr+html"},
+		{ClassOcr, "a-0.alto.xml", "application/xml"},
+		{ClassCsv, "transactions.csv", "text/tab-separated-values"},
+		{ClassExports, "index.csv", "text/csv"},
+		{ClassExports, "index.jsonl", "application/x-ndjson"},
+		{ClassExports, "index.parquet", "application/vnd.apache.parquet"},
+		{ClassExports, "index", "application/octet-stream"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			if got := defaultArtifactClass(tt.class).ContentTypeOf(tt.fileName); got != tt.want {
+				t.Errorf("ContentTypeOf() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestS3ServiceV2_PdfBackend(t *testing.T) {
+	fake, server := s3test.NewServer(t)
+	service, err := NewS3ServiceV2(&model.S3StaticProfile{
+		S3DefaultProfile: model.S3DefaultProfile{
+			S3Bucket:     "disclosures",
+			S3Hostname:   server.URL,
+			UsePathStyle: true,
+			S3KeyPrefix:  "house",
+		},
+		S3ApiKey:    "apiKey",
+		S3SecretKey: "secretKey",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	fileName := "2024.ptr-pdfs.CA12.Doe.Jane.20012345.pdf"
+	b := service.PdfBackend(defaultArtifactClass(ClassSearchable), []string{fileName})
+	if err = b.Put(ctx, fileName, strings.NewReader("pdf"), nil); err != nil {
+		t.Fatal(err)
+	}
+	object := fake.Object("disclosures", "house/searchable/2024/ptr/20012345.pdf")
+	if object == nil {
+		t.Fatalf("objects = %v, want house/searchable/2024/ptr/20012345.pdf", fake.Keys())
+	}
+	if got := object.Header.Get("X-Amz-Meta-Docid"); got != "20012345" {
+		t.Errorf("docId metadata = %q, want the DocId of the file name", got)
+	}
+
+	// a new backend finds the PDF by the file names of the index
+	b = service.PdfBackend(defaultArtifactClass(ClassSearchable), []string{fileName})
+	var names []string
+	err = b.List(ctx, "", func(info *storage.ObjectInfo) error {
+		names = append(names, info.Key)
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(names, []string{fileName}) {
+		t.Errorf("List() = %v, %v, want [%s]", names, err, fileName)
+	}
+}
@@ -0,0 +1,97 @@
+package s3client
+
+import (
+	"github.com/paulschick/disclosureupdater/model"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultKeyTemplate is the object key of a disclosure PDF when the profile doesn't set a template
+const DefaultKeyTemplate = "{year}/{filingType}/{docId}.pdf"
+
+// SearchableKeyPrefix separates the searchable PDFs from the original PDFs, which have the same file names
+const SearchableKeyPrefix = "searchable"
+
+// KeyScheme builds the object keys of local files, so the keys are the same on every machine.
+// Disclosure PDFs are keyed by the template, where {year}, {filingType} (ptr or financial), {docId},
+// {stateDst}, {last}, {first} and {fileName} are replaced with the values from the PDF file name.
+// Other files are keyed by their path in the folder. Every key starts with the prefix.
+type KeyScheme struct {
+	Prefix   string
+	Template string
+}
+
+func NewKeyScheme(prefix, template string) *KeyScheme {
+	if template == "" {
+		template = DefaultKeyTemplate
+	}
+	return &KeyScheme{
+		Prefix:   prefix,
+		Template: template,
+	}
+}
+
+// KeySchemeFromProfile returns the key scheme set in the S3 profile
+func KeySchemeFromProfile(s3Profile model.S3Profile) *KeyScheme {
+	return NewKeyScheme(s3Profile.GetKeyPrefix(), s3Profile.GetKeyTemplate())
+}
+
+// Key returns the object key of a file by its path relative to the folder it's uploaded from. The class prefix
+// is added after the scheme prefix to keep artifacts with the same file names apart, e.g. SearchableKeyPrefix.
+func (k *KeyScheme) Key(classPrefix, relPath string) string {
+	return path.Join(k.Prefix, classPrefix, k.Name(relPath))
+}
+
+// Name returns the key of a file without the scheme and class prefixes, the template applied to disclosure PDFs
+func (k *KeyScheme) Name(relPath string) string {
+	fileName := filepath.Base(relPath)
+	member, err := model.ParsePdfFileName(fileName)
+	if err != nil {
+		return filepath.ToSlash(relPath)
+	}
+	return strings.NewReplacer(
+		"{year}", strconv.Itoa(member.Year),
+		"{filingType}", filingKind(member),
+		"{docId}", strconv.Itoa(member.DocId),
+		"{stateDst}", member.StateDst,
+		"{last}", keySegment(member.Last),
+		"{first}", keySegment(member.First),
+		"{fileName}", fileName,
+	).Replace(k.Template)
+}
+
+// IsLegacyKey returns true for keys of the earlier scheme, which used the absolute local path of the
+// file as the key, e.g. /home/me/.disclosurecli/data/disclosures/2023.ptr-pdfs.CA12.Doe.Jane.20012345.pdf.
+// It returns the local folder name and file name of legacy keys.
+func IsLegacyKey(key string) (folder string, fileName string, ok bool) {
+	if !strings.HasPrefix(key, "/") && !filepath.IsAbs(key) {
+		return "", "", false
+	}
+	dir, fileName := path.Split(filepath.ToSlash(key))
+	return path.Base(dir), fileName, true
+}
+
+// copySource returns the URL encoded bucket and key of a CopyObject source
+func copySource(bucket, key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return bucket + "/" + strings.Join(segments, "/")
+}
+
+// filingKind returns ptr for periodic transaction reports and financial for other filings,
+// the filing type is only known for PTRs from the PDF file name
+func filingKind(member *model.Member) string {
+	if member.FilingType == "P" {
+		return "ptr"
+	}
+	return "financial"
+}
+
+func keySegment(name string) string {
+	return strings.ReplaceAll(name, " ", "_")
+}
@@ -0,0 +1,97 @@
+package s3client
+
+import (
+	conf "github.com/paulschick/disclosureupdater/config"
+	"github.com/paulschick/disclosureupdater/model"
+	"reflect"
+	"testing"
+)
+
+func TestKeyScheme_Key(t *testing.T) {
+	const ptrPdf = "2023.ptr-pdfs.CA12.Van_Doe.Jane.20012345.pdf"
+	tests := []struct {
+		name        string
+		scheme      *KeyScheme
+		classPrefix string
+		relPath     string
+		want        string
+	}{
+		{"default template", NewKeyScheme("", ""), "", ptrPdf, "2023/ptr/20012345.pdf"},
+		{"prefix", NewKeyScheme("disclosures/", ""), "", ptrPdf, "disclosures/2023/ptr/20012345.pdf"},
+		{"class prefix", NewKeyScheme("archive", ""), SearchableKeyPrefix, ptrPdf,
+			"archive/searchable/2023/ptr/20012345.pdf"},
+		{"annual report", NewKeyScheme("", ""), "",
+			"2022.financial-pdfs.OH01.Smith.James.10055555.pdf", "2022/financial/10055555.pdf"},
+		{"template placeholders", NewKeyScheme("", "{stateDst}/{last}_{first}/{docId}-{year}.pdf"), "", ptrPdf,
+			"CA12/Van_Doe_Jane/20012345-2023.pdf"},
+		{"file name placeholder", NewKeyScheme("", "{filingType}/{fileName}"), "", ptrPdf,
+			"ptr/2023.ptr-pdfs.CA12.Van_Doe.Jane.20012345.pdf"},
+		{"other files", NewKeyScheme("p", ""), "images", "2023.ptr-pdfs.CA12.Doe.Jane.20012345/page-0.png",
+			"p/images/2023.ptr-pdfs.CA12.Doe.Jane.20012345/page-0.png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.scheme.Key(tt.classPrefix, tt.relPath); got != tt.want {
+				t.Errorf("Key() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsLegacyKey(t *testing.T) {
+	tests := []struct {
+		key          string
+		wantFolder   string
+		wantFileName string
+		wantOk       bool
+	}{
+		{"/home/me/.disclosurecli/data/disclosures/2023.ptr-pdfs.CA12.Doe.Jane.20012345.pdf",
+			"disclosures", "2023.ptr-pdfs.CA12.Doe.Jane.20012345.pdf", true},
+		{"/root/data/searchable/a.pdf", "searchable", "a.pdf", true},
+		{"2023/ptr/20012345.pdf", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			folder, fileName, ok := IsLegacyKey(tt.key)
+			if folder != tt.wantFolder || fileName != tt.wantFileName || ok != tt.wantOk {
+				t.Errorf("IsLegacyKey() = %q, %q, %v, want %q, %q, %v",
+					folder, fileName, ok, tt.wantFolder, tt.wantFileName, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestS3ServiceV2_PlanKeyMigration(t *testing.T) {
+	service := &S3ServiceV2{S3Profile: &model.S3DefaultProfile{S3Bucket: "bucket", S3KeyPrefix: "house"}}
+	commonDirs := &conf.CommonDirs{SearchableFolder: "/data/searchable"}
+	keys := []string{
+		"/home/me/data/disclosures/2023.ptr-pdfs.CA12.Doe.Jane.20012345.pdf",
+		"/home/me/data/searchable/2023.ptr-pdfs.CA12.Doe.Jane.20012345.pdf",
+		"/home/me/data/disclosures/2022.financial-pdfs.OH01.Smith.James.10055555.pdf",
+		"house/2022/financial/10055555.pdf",
+		"house/2021/ptr/20011111.pdf",
+	}
+	want := []*KeyMigration{
+		{OldKey: keys[0], NewKey: "house/2023/ptr/20012345.pdf"},
+		{OldKey: keys[1], NewKey: "house/searchable/2023/ptr/20012345.pdf"},
+		{OldKey: keys[2], NewKey: "house/2022/financial/10055555.pdf", Exists: true},
+	}
+	got, err := service.PlanKeyMigration(commonDirs, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		for _, m := range got {
+			t.Logf("%+v", *m)
+		}
+		t.Errorf("PlanKeyMigration() returned unexpected migrations")
+	}
+}
+
+func TestCopySource(t *testing.T) {
+	got := copySource("bucket", "/home/me/data/disclosures/2023.ptr-pdfs.CA12.De La Cruz.Ana #2.pdf")
+	want := "bucket//home/me/data/disclosures/2023.ptr-pdfs.CA12.De%20La%20Cruz.Ana%20%232.pdf"
+	if got != want {
+		t.Errorf("copySource() = %q, want %q", got, want)
+	}
+}
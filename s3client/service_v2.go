@@ -8,61 +8,128 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/aws/smithy-go"
 	conf "github.com/paulschick/disclosureupdater/config"
 	"github.com/paulschick/disclosureupdater/model"
+	"net/url"
 	"os"
 	"path/filepath"
-	"slices"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 )
 
 type S3ServiceV2 struct {
 	Client    *s3.Client
 	S3Profile model.S3Profile
+	Uploader  *manager.Uploader
+	// Upload is the upload configuration of the profile with defaults for unset values
+	Upload model.S3UploadConfig
+	// Members are the disclosure index entries by DocId, for the metadata of uploaded PDFs.
+	// Without an entry the metadata is taken from the file name, which has no filing date.
+	Members map[int]*model.Member
 }
 
+// DefaultRegion is used with a custom endpoint if the profile has no region, S3-compatible stores like MinIO
+// accept it unless they're configured with another region
+const DefaultRegion = "us-east-1"
+
 func NewS3ServiceV2(s3Profile model.S3Profile) (*S3ServiceV2, error) {
-	endpoint := aws.Endpoint{
-		URL: s3Profile.GetHostname(),
+	baseEndpoint, err := BaseEndpoint(s3Profile.GetHostname())
+	if err != nil {
+		return nil, err
+	}
+	region := s3Profile.GetRegion()
+	if region == "" && baseEndpoint != "" {
+		region = DefaultRegion
+	}
+	upload, err := uploadOptions(s3Profile.GetUpload())
+	if err != nil {
+		return nil, err
 	}
-	endpointResolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
-		if service == s3.ServiceID && region == s3Profile.GetRegion() {
-			return endpoint, nil
-		}
-		return aws.Endpoint{}, &aws.EndpointNotFoundError{}
-	})
 	var cfg aws.Config
-	var err error
 	if s3Profile.StaticAuthentication() {
 		apiKey := s3Profile.(*model.S3StaticProfile).S3ApiKey
 		apiSecret := s3Profile.(*model.S3StaticProfile).S3SecretKey
 		cfg, err = config.LoadDefaultConfig(
 			context.TODO(),
-			config.WithRegion(s3Profile.GetRegion()),
-			config.WithEndpointResolverWithOptions(endpointResolver),
+			config.WithRegion(region),
+			config.WithRetryMaxAttempts(upload.MaxAttempts),
 			config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(apiKey, apiSecret, "")))
 		if err != nil {
 			return nil, err
 		}
 	} else {
+		awsProfile := s3Profile.GetAwsProfile()
+		if awsProfile == "" {
+			awsProfile = "default"
+		}
 		cfg, err = config.LoadDefaultConfig(context.TODO(),
-			config.WithSharedConfigProfile("default"),
-			config.WithRegion(s3Profile.GetRegion()),
-			config.WithEndpointResolverWithOptions(endpointResolver))
+			config.WithSharedConfigProfile(awsProfile),
+			config.WithRegion(region),
+			config.WithRetryMaxAttempts(upload.MaxAttempts))
 		if err != nil {
 			return nil, err
 		}
 	}
-	client := s3.NewFromConfig(cfg)
+	if assumeRole := s3Profile.GetAssumeRole(); assumeRole != nil {
+		cfg.Credentials = assumeRoleCredentials(cfg, assumeRole)
+	}
+	// only the S3 client uses the endpoint, STS requests of an assumed role go to AWS
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if baseEndpoint != "" {
+			o.BaseEndpoint = aws.String(baseEndpoint)
+		}
+		o.UsePathStyle = s3Profile.GetUsePathStyle()
+	})
 	return &S3ServiceV2{
 		Client:    client,
 		S3Profile: s3Profile,
+		Uploader:  newUploader(client, upload),
+		Upload:    upload,
 	}, err
 }
 
+// BaseEndpoint returns the endpoint URL of the profile's hostname, https is used if it has no scheme.
+// Without a hostname the endpoint of the region is resolved by the SDK and an empty string is returned.
+func BaseEndpoint(hostname string) (string, error) {
+	hostname = strings.TrimSpace(hostname)
+	if hostname == "" {
+		return "", nil
+	}
+	if !strings.Contains(hostname, "://") {
+		hostname = "https://" + hostname
+	}
+	u, err := url.Parse(hostname)
+	if err != nil {
+		return "", fmt.Errorf("invalid S3 hostname %q: %w", hostname, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return "", fmt.Errorf("invalid S3 hostname %q, want a host or an http(s) URL", hostname)
+	}
+	return strings.TrimSuffix(u.String(), "/"), nil
+}
+
+// assumeRoleCredentials returns the credentials of the role, assumed with the credentials of cfg.
+// The credentials are cached and refreshed before they expire.
+func assumeRoleCredentials(cfg aws.Config, assumeRole *model.S3AssumeRoleConfig) aws.CredentialsProvider {
+	provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), assumeRole.RoleArn, func(o *stscreds.AssumeRoleOptions) {
+		if assumeRole.ExternalId != "" {
+			o.ExternalID = aws.String(assumeRole.ExternalId)
+		}
+		if assumeRole.SessionName != "" {
+			o.RoleSessionName = assumeRole.SessionName
+		}
+	})
+	return aws.NewCredentialsCache(provider)
+}
+
 func (s *S3ServiceV2) CreateNewBucket() error {
 	exists, err := s.BucketExists()
 	if err != nil {
@@ -104,6 +171,8 @@ func (s *S3ServiceV2) BucketExists() (bool, error) {
 	return exists, err
 }
 
+// WriteBucketObjects writes the key, size, ETag, storage class and last modified time of every object
+// to the bucket index. The index is written to a temporary file first, so a failed listing keeps the last index.
 func (s *S3ServiceV2) WriteBucketObjects(commonDirs *conf.CommonDirs) error {
 	var maxKeys int32 = 1000
 	params := &s3.ListObjectsV2Input{
@@ -112,96 +181,96 @@ func (s *S3ServiceV2) WriteBucketObjects(commonDirs *conf.CommonDirs) error {
 	p := s3.NewListObjectsV2Paginator(s.Client, params, func(o *s3.ListObjectsV2PaginatorOptions) {
 		o.Limit = maxKeys
 	})
-	pageNo := 0
+	fp := bucketIndexPath(commonDirs)
+	tmpFp := fp + ".tmp"
+	file, err := os.Create(tmpFp)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+		_ = os.Remove(tmpFp)
+	}()
+	dataWriter := bufio.NewWriter(file)
 	for p.HasMorePages() {
 		page, err := p.NextPage(context.TODO())
-		items := make([]string, 0)
 		if err != nil {
 			return err
 		}
-		for _, obj := range page.Contents {
-			items = append(items, *obj.Key)
-		}
-		fp := filepath.Join(commonDirs.S3Folder, "s3_objects.txt")
-		var file *os.File
-		if pageNo == 0 {
-			if _, b := os.Stat(fp); !errors.Is(b, os.ErrNotExist) {
-				// delete file
-				err = os.Remove(fp)
-				if err != nil {
-					return err
-				}
-			}
+		objects := make([]*BucketObject, len(page.Contents))
+		for i, obj := range page.Contents {
+			objects[i] = NewBucketObject(obj)
 		}
-		file, err = os.OpenFile(fp, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-		if err != nil {
+		if err = writeBucketObjects(dataWriter, objects); err != nil {
 			return err
 		}
-		dataWriter := bufio.NewWriter(file)
-		for _, item := range items {
-			_, _ = dataWriter.WriteString(item + "\n")
-		}
-		_ = dataWriter.Flush()
-		_ = file.Close()
-
-		pageNo++
 	}
-	return nil
+	if err = dataWriter.Flush(); err != nil {
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpFp, fp); err != nil {
+		return err
+	}
+	// the key only index of earlier versions is replaced by the new index
+	err = os.Remove(filepath.Join(commonDirs.S3Folder, legacyBucketIndexFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
 }
 
 func (s *S3ServiceV2) UploadPdfsS3(commonDirs *conf.CommonDirs) error {
+	class, err := ArtifactClassFromProfile(s.S3Profile, ClassDisclosures)
+	if err != nil {
+		return err
+	}
+	return s.UploadClassS3(commonDirs, class)
+}
+
+// UploadClassS3 uploads the files of the artifact class, including files in subfolders, whose object keys
+// are not present in the bucket index, or whose size or md5 differ from the object in the index.
+// Keys are built by the key scheme of the profile, with the class prefix added to keep artifacts
+// with the same file names apart.
+func (s *S3ServiceV2) UploadClassS3(commonDirs *conf.CommonDirs, class *ArtifactClass) error {
 	var err error
-	indexFp := filepath.Join(commonDirs.S3Folder, "s3_objects.txt")
-	if _, b := os.Stat(indexFp); errors.Is(b, os.ErrNotExist) {
-		fmt.Printf("No index file found at %s\n", indexFp)
-		// there should be an empty file at least
+	index, err := LoadBucketIndex(commonDirs)
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Printf("No index file found at %s, run update-bucket-items\n", bucketIndexPath(commonDirs))
 		return nil
 	}
-	var file *os.File
-	file, err = os.Open(indexFp)
 	if err != nil {
-		fmt.Printf("Error opening file %s: %s\n", indexFp, err)
+		fmt.Printf("Error reading bucket index: %s\n", err)
 		return err
 	}
-	defer func() {
-		// not handling close error for now
-		_ = file.Close()
-	}()
-	scanner := bufio.NewScanner(file)
-	inBucket := make([]string, 0)
-	for scanner.Scan() {
-		// Bucket lines contain full file path
-		line := scanner.Text()
-		fmt.Printf("S3 Item:\t%s\n", line)
-		if _, b := os.Stat(line); errors.Is(b, os.ErrNotExist) {
-			fmt.Printf("File %s does not exist\n", line)
-		} else {
-			inBucket = append(inBucket, line)
-		}
-	}
-	pdfDir := commonDirs.DisclosuresFolder
-	var files []os.DirEntry
-	files, err = os.ReadDir(pdfDir)
+	var files map[string]*SyncFile
+	files, err = listLocal(&SyncFolder{Dir: class.Dir(commonDirs), Class: class}, KeySchemeFromProfile(s.S3Profile))
 	if err != nil {
 		fmt.Printf("Error reading directory: %s\n", err)
 		return err
 	}
 	toUploadSlice := make([]string, 0)
-	for _, dirEntry := range files {
-		file, err := os.Open(fmt.Sprintf("%s/%s", pdfDir, dirEntry.Name()))
+	toUploadKeys := make(map[string]string)
+	for key, f := range files {
+		fName := f.path
+		reason, err := index.Changed(f, s.etagChecksum())
 		if err != nil {
-			fmt.Printf("Error opening file: %s\n", err)
+			fmt.Printf("Error comparing file %s: %s\n", fName, err)
 			return err
 		}
-
-		fName := file.Name()
-		isInBucket := slices.Contains(inBucket, fName)
-		if isInBucket {
-			fmt.Printf("File %s is in bucket, skipping\n", fName)
+		if reason == "" {
+			fmt.Printf("File %s is in bucket as %s, skipping\n", fName, key)
 		} else {
+			if reason != "missing" {
+				fmt.Printf("File %s changed (%s), uploading again\n", fName, reason)
+			}
 			toUploadSlice = append(toUploadSlice, fName)
+			toUploadKeys[fName] = key
 		}
 	}
+	sort.Strings(toUploadSlice)
 	fmt.Printf("Uploading %d files\n", len(toUploadSlice))
 
 	done := make(chan bool, len(toUploadSlice))
@@ -211,6 +280,8 @@ func (s *S3ServiceV2) UploadPdfsS3(commonDirs *conf.CommonDirs) error {
 	throttle := time.Tick(time.Second / reqPer)
 	fmt.Printf("Uploading at %d requests per second\n", reqPer)
 	uploadCount := 0
+	// guards the index and upload count
+	var mu sync.Mutex
 	for _, fName := range toUploadSlice {
 		go func(fName string) {
 			<-throttle
@@ -221,7 +292,7 @@ func (s *S3ServiceV2) UploadPdfsS3(commonDirs *conf.CommonDirs) error {
 				done <- false
 				return
 			}
-			err = s.UploadFile(file)
+			obj, err := s.UploadFile(file, toUploadKeys[fName], class)
 			if err != nil {
 				fmt.Printf("Error uploading file: %s\n", err)
 				errs <- err
@@ -235,10 +306,13 @@ func (s *S3ServiceV2) UploadPdfsS3(commonDirs *conf.CommonDirs) error {
 				done <- false
 				return
 			}
-			done <- true
-			errs <- nil
+			mu.Lock()
+			index[obj.Key] = obj
 			uploadCount++
 			fmt.Printf("Uploaded file %s\tUpload Count %d\n", fName, uploadCount)
+			mu.Unlock()
+			done <- true
+			errs <- nil
 		}(fName)
 	}
 	var errStr string
@@ -251,21 +325,96 @@ func (s *S3ServiceV2) UploadPdfsS3(commonDirs *conf.CommonDirs) error {
 		err = errors.New(errStr)
 	}
 	fmt.Printf("Uploaded %d files\n", uploadCount)
+	if uploadCount > 0 {
+		// the uploaded files aren't uploaded again before the next update-bucket-items
+		if saveErr := SaveBucketIndex(commonDirs, index); saveErr != nil {
+			fmt.Printf("Error saving bucket index: %s\n", saveErr)
+			err = errors.Join(err, saveErr)
+		}
+	}
 
 	return err
 }
 
-func (s *S3ServiceV2) UploadFile(file *os.File) error {
-	var err error
-	fileName := file.Name()
-	_, err = s.Client.PutObject(context.TODO(), &s3.PutObjectInput{
+// ListKeys returns the keys of every object in the bucket
+func (s *S3ServiceV2) ListKeys() ([]string, error) {
+	p := s3.NewListObjectsV2Paginator(s.Client, &s3.ListObjectsV2Input{
 		Bucket: aws.String(s.S3Profile.GetBucket()),
-		Key:    aws.String(fileName),
-		Body:   file,
 	})
+	keys := make([]string, 0)
+	for p.HasMorePages() {
+		page, err := p.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range page.Contents {
+			keys = append(keys, *obj.Key)
+		}
+	}
+	return keys, nil
+}
+
+// KeyMigration is an object copied from a legacy key, the absolute local path, to its key in the key scheme
+type KeyMigration struct {
+	OldKey string
+	NewKey string
+	// Exists is true if the new key was already in the bucket, the object isn't copied again
+	Exists bool
+}
+
+// PlanKeyMigration returns the migrations of every object with a legacy key. Objects uploaded from the
+// searchable folder get the prefix of the searchable class, objects from other folders the prefix of the
+// disclosures class.
+func (s *S3ServiceV2) PlanKeyMigration(commonDirs *conf.CommonDirs, keys []string) ([]*KeyMigration, error) {
+	scheme := KeySchemeFromProfile(s.S3Profile)
+	pdfClass, err := ArtifactClassFromProfile(s.S3Profile, ClassDisclosures)
 	if err != nil {
-		fmt.Printf("Failed to upload file %s: %s\n", fileName, err)
-		return err
+		return nil, err
+	}
+	searchableClass, err := ArtifactClassFromProfile(s.S3Profile, ClassSearchable)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		existing[key] = true
 	}
+	searchableFolder := filepath.Base(commonDirs.SearchableFolder)
+	migrations := make([]*KeyMigration, 0)
+	for _, key := range keys {
+		folder, fileName, ok := IsLegacyKey(key)
+		if !ok {
+			continue
+		}
+		classPrefix := pdfClass.Prefix
+		if folder == searchableFolder {
+			classPrefix = searchableClass.Prefix
+		}
+		newKey := scheme.Key(classPrefix, fileName)
+		migrations = append(migrations, &KeyMigration{OldKey: key, NewKey: newKey, Exists: existing[newKey]})
+	}
+	return migrations, nil
+}
+
+// MigrateKey copies the object to its new key, and deletes the old key if deleteOld is true
+func (s *S3ServiceV2) MigrateKey(migration *KeyMigration, deleteOld bool) error {
+	bucket := s.S3Profile.GetBucket()
+	if !migration.Exists {
+		_, err := s.Client.CopyObject(context.TODO(), &s3.CopyObjectInput{
+			Bucket:     aws.String(bucket),
+			CopySource: aws.String(copySource(bucket, migration.OldKey)),
+			Key:        aws.String(migration.NewKey),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if !deleteOld {
+		return nil
+	}
+	_, err := s.Client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(migration.OldKey),
+	})
 	return err
 }
@@ -0,0 +1,119 @@
+package s3client
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/paulschick/disclosureupdater/internal/s3test"
+	"github.com/paulschick/disclosureupdater/model"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewS3ServiceV2PathStyle(t *testing.T) {
+	for _, usePathStyle := range []bool{false, true} {
+		s3Profile := &model.S3StaticProfile{
+			S3DefaultProfile: model.S3DefaultProfile{
+				S3Bucket:     "bucket",
+				S3Region:     "us-east-1",
+				S3Hostname:   "http://localhost:9000",
+				UsePathStyle: usePathStyle,
+			},
+			S3ApiKey:    "apiKey",
+			S3SecretKey: "secretKey",
+		}
+		service, err := NewS3ServiceV2(s3Profile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := service.Client.Options().UsePathStyle; got != usePathStyle {
+			t.Errorf("UsePathStyle = %v, want %v", got, usePathStyle)
+		}
+		if got := *service.Client.Options().BaseEndpoint; got != "http://localhost:9000" {
+			t.Errorf("BaseEndpoint = %q", got)
+		}
+	}
+}
+
+// TestNewS3ServiceV2Endpoint uploads to an S3-compatible store without a region, like an on-prem MinIO
+func TestNewS3ServiceV2Endpoint(t *testing.T) {
+	fake, server := s3test.NewServer(t)
+	s3Profile := &model.S3StaticProfile{
+		S3DefaultProfile: model.S3DefaultProfile{
+			S3Bucket:     "disclosures",
+			S3Hostname:   server.URL,
+			UsePathStyle: true,
+		},
+		S3ApiKey:    "apiKey",
+		S3SecretKey: "secretKey",
+	}
+	service, err := NewS3ServiceV2(s3Profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fp := filepath.Join(t.TempDir(), "10000001.pdf")
+	if err = os.WriteFile(fp, []byte("pdf"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	obj, err := service.UploadFile(file, "2024/10000001.pdf", defaultArtifactClass(ClassDisclosures))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj.ETag != s3test.ETag([]byte("pdf")) {
+		t.Errorf("UploadFile() ETag = %q", obj.ETag)
+	}
+	if stored := fake.Object("disclosures", "2024/10000001.pdf"); stored == nil || string(stored.Data) != "pdf" {
+		t.Fatalf("object wasn't stored under the path of the bucket, objects: %v", fake.Keys())
+	}
+
+	sha256Hex, err := service.objectSha256("2024/10000001.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("%x", sha256.Sum256([]byte("pdf"))); sha256Hex != want {
+		t.Errorf("objectSha256() = %q, want %q", sha256Hex, want)
+	}
+	keys, err := service.ListKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != "2024/10000001.pdf" {
+		t.Errorf("ListKeys() = %v", keys)
+	}
+
+	for _, r := range fake.Requests() {
+		if !strings.Contains(r.Header.Get("Authorization"), "/"+DefaultRegion+"/s3/") {
+			t.Errorf("%s %s signed for another region: %s", r.Method, r.URL, r.Header.Get("Authorization"))
+		}
+	}
+}
+
+func TestBaseEndpoint(t *testing.T) {
+	tests := []struct {
+		hostname string
+		want     string
+		wantErr  bool
+	}{
+		{"", "", false},
+		{"s3.eu-central-1.amazonaws.com", "https://s3.eu-central-1.amazonaws.com", false},
+		{"http://minio.internal:9000/", "http://minio.internal:9000", false},
+		{"https://minio.internal/s3", "https://minio.internal/s3", false},
+		{"ftp://minio.internal", "", true},
+		{"http://", "", true},
+	}
+	for _, tt := range tests {
+		got, err := BaseEndpoint(tt.hostname)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("BaseEndpoint(%q) = %q, %v, want %q", tt.hostname, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,393 @@
+package s3client
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/paulschick/disclosureupdater/common/workerpool"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Sync modes
+const (
+	// SyncPush uploads new and changed local files
+	SyncPush = "push"
+	// SyncPull downloads new and changed objects
+	SyncPull = "pull"
+	// SyncBoth copies new files both ways, changed files are copied from the side that was modified last
+	SyncBoth = "both"
+)
+
+// Sync actions
+const (
+	ActionUpload       = "upload"
+	ActionDownload     = "download"
+	ActionDeleteRemote = "delete-remote"
+	ActionDeleteLocal  = "delete-local"
+)
+
+// syncConcurrency is the number of files copied at the same time
+const syncConcurrency = 16
+
+// SyncFolder is a local folder synced with the objects under the prefix of its artifact class.
+// Disclosure PDFs are keyed by the key template, which can't be reversed, so the keys of those folders are
+// mapped back to file names with Names. Objects of other folders are mapped back by their path under the prefix.
+type SyncFolder struct {
+	Dir   string
+	Class *ArtifactClass
+	Names map[string]string
+}
+
+// NewPdfSyncFolder returns a folder of disclosure PDFs, fileNames are the names of every PDF of the disclosure index
+func NewPdfSyncFolder(dir string, class *ArtifactClass, scheme *KeyScheme, fileNames []string) *SyncFolder {
+	names := make(map[string]string, len(fileNames))
+	for _, fileName := range fileNames {
+		names[scheme.Key(class.Prefix, fileName)] = fileName
+	}
+	return &SyncFolder{Dir: dir, Class: class, Names: names}
+}
+
+// relPath returns the path in the folder of the object key, false if the key isn't an object of the folder
+func (f *SyncFolder) relPath(scheme *KeyScheme, key string) (string, bool) {
+	if f.Names != nil {
+		name, ok := f.Names[key]
+		return name, ok
+	}
+	prefix := path.Join(scheme.Prefix, f.Class.Prefix)
+	if prefix == "." || prefix == "" {
+		return key, true
+	}
+	if rel, ok := strings.CutPrefix(key, prefix+"/"); ok {
+		return rel, true
+	}
+	return "", false
+}
+
+// listPrefix returns the prefix to list the objects of the folder with
+func (f *SyncFolder) listPrefix(scheme *KeyScheme) string {
+	prefix := path.Join(scheme.Prefix, f.Class.Prefix)
+	if prefix == "." || prefix == "" {
+		return ""
+	}
+	return prefix + "/"
+}
+
+// SyncFile is a file or object compared by a sync
+type SyncFile struct {
+	Key      string
+	RelPath  string
+	Size     int64
+	Modified time.Time
+	// ETag of the object, without quotes
+	ETag string
+	// path of the local file, empty for objects
+	path string
+}
+
+// SyncAction is a copy or delete that brings the folder and the bucket in sync
+type SyncAction struct {
+	Action  string
+	Key     string
+	RelPath string
+	Reason  string
+}
+
+// SyncOptions configure a sync
+type SyncOptions struct {
+	Mode   string
+	DryRun bool
+	// Delete removes files that only exist on the source side, it can't be used with SyncBoth
+	Delete bool
+}
+
+func (o SyncOptions) validate() error {
+	switch o.Mode {
+	case SyncPush, SyncPull:
+		return nil
+	case SyncBoth:
+		if o.Delete {
+			return errors.New("--delete can't be used with the both sync mode, a missing file can't be told apart from a new one")
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid sync mode %q, expected %s, %s or %s", o.Mode, SyncPush, SyncPull, SyncBoth)
+	}
+}
+
+// PlanSync compares the local files with the objects by key, size and checksum and returns the actions
+// of the sync mode, sorted by key. Checksums are only compared when the sizes match, local files are
+// hashed with md5 lazily by the checksum function, without it objects are compared by size only.
+func PlanSync(local, remote map[string]*SyncFile, opts SyncOptions, checksum func(*SyncFile) (string, error)) ([]*SyncAction, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	actions := make([]*SyncAction, 0)
+	for key, l := range local {
+		r, ok := remote[key]
+		if !ok {
+			if opts.Mode == SyncPull {
+				if opts.Delete {
+					actions = append(actions, &SyncAction{ActionDeleteLocal, key, l.RelPath, "not in bucket"})
+				}
+				continue
+			}
+			actions = append(actions, &SyncAction{ActionUpload, key, l.RelPath, "missing"})
+			continue
+		}
+		changed, err := differs(l, r, checksum)
+		if err != nil {
+			return nil, err
+		}
+		if !changed {
+			continue
+		}
+		reason := "size"
+		if l.Size == r.Size {
+			reason = "checksum"
+		}
+		switch {
+		case opts.Mode == SyncPush, opts.Mode == SyncBoth && l.Modified.After(r.Modified):
+			actions = append(actions, &SyncAction{ActionUpload, key, l.RelPath, reason})
+		default:
+			actions = append(actions, &SyncAction{ActionDownload, key, r.RelPath, reason})
+		}
+	}
+	for key, r := range remote {
+		if _, ok := local[key]; ok {
+			continue
+		}
+		if opts.Mode == SyncPush {
+			if opts.Delete {
+				actions = append(actions, &SyncAction{ActionDeleteRemote, key, r.RelPath, "not in folder"})
+			}
+			continue
+		}
+		actions = append(actions, &SyncAction{ActionDownload, key, r.RelPath, "missing"})
+	}
+	sort.Slice(actions, func(i, j int) bool {
+		return actions[i].Key < actions[j].Key
+	})
+	return actions, nil
+}
+
+// differs returns true if the sizes differ, or the md5 of the local file isn't the ETag of the object.
+// ETags of multipart uploads aren't an md5 of the content, those objects are compared by size only,
+// as are all objects if checksum is nil.
+func differs(local, remote *SyncFile, checksum func(*SyncFile) (string, error)) (bool, error) {
+	if local.Size != remote.Size {
+		return true, nil
+	}
+	if checksum == nil || remote.ETag == "" || strings.Contains(remote.ETag, "-") {
+		return false, nil
+	}
+	sum, err := checksum(local)
+	if err != nil {
+		return false, err
+	}
+	return !strings.EqualFold(sum, remote.ETag), nil
+}
+
+// Sync brings the folder and its objects in the bucket in sync and returns the actions taken,
+// or the actions that would be taken for a dry run
+func (s *S3ServiceV2) Sync(folder *SyncFolder, opts SyncOptions) ([]*SyncAction, error) {
+	scheme := KeySchemeFromProfile(s.S3Profile)
+	local, err := listLocal(folder, scheme)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := s.listRemote(folder, scheme)
+	if err != nil {
+		return nil, err
+	}
+	actions, err := PlanSync(local, remote, opts, s.etagChecksum())
+	if err != nil || opts.DryRun {
+		return actions, err
+	}
+
+	tasks := make([]*workerpool.Task, len(actions))
+	for i, action := range actions {
+		tasks[i] = workerpool.NewTask(func(data interface{}) error {
+			a := data.(*SyncAction)
+			return s.applySyncAction(folder, a, remote[a.Key])
+		}, action, i)
+	}
+	pool := workerpool.NewPool(tasks, syncConcurrency, len(tasks))
+	pool.Run()
+
+	var errStr string
+	for i, task := range tasks {
+		if task.Err != nil {
+			fmt.Printf("Error syncing %s: %s\n", actions[i].Key, task.Err)
+			errStr = errStr + " " + task.Err.Error()
+		}
+	}
+	if errStr != "" {
+		return actions, errors.New(errStr)
+	}
+	return actions, nil
+}
+
+func (s *S3ServiceV2) applySyncAction(folder *SyncFolder, a *SyncAction, remote *SyncFile) error {
+	localPath := filepath.Join(folder.Dir, filepath.FromSlash(a.RelPath))
+	switch a.Action {
+	case ActionUpload:
+		file, err := os.Open(localPath)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+		_, err = s.UploadFile(file, a.Key, folder.Class)
+		return err
+	case ActionDownload:
+		return s.DownloadFile(a.Key, localPath, remote.Modified)
+	case ActionDeleteRemote:
+		_, err := s.Client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
+			Bucket: aws.String(s.S3Profile.GetBucket()),
+			Key:    aws.String(a.Key),
+		})
+		return err
+	case ActionDeleteLocal:
+		return os.Remove(localPath)
+	}
+	return fmt.Errorf("unknown sync action %q", a.Action)
+}
+
+// DownloadFile writes the object to localPath, and sets the modification time of the file to the object's.
+// The object is written to a temporary file first, so an interrupted download doesn't leave a partial file.
+func (s *S3ServiceV2) DownloadFile(key, localPath string, modified time.Time) error {
+	out, err := s.Client.GetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(s.S3Profile.GetBucket()),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = out.Body.Close()
+	}()
+	if err = os.MkdirAll(filepath.Dir(localPath), os.ModePerm); err != nil {
+		return err
+	}
+	tmpPath := localPath + ".download"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, out.Body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, localPath)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if !modified.IsZero() {
+		return os.Chtimes(localPath, modified, modified)
+	}
+	return nil
+}
+
+// listLocal returns the files of the folder and its subfolders by object key
+func listLocal(folder *SyncFolder, scheme *KeyScheme) (map[string]*SyncFile, error) {
+	files := make(map[string]*SyncFile)
+	err := filepath.WalkDir(folder.Dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) && p == folder.Dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(folder.Dir, p)
+		if err != nil {
+			return err
+		}
+		key := scheme.Key(folder.Class.Prefix, rel)
+		files[key] = &SyncFile{
+			Key:      key,
+			RelPath:  filepath.ToSlash(rel),
+			Size:     info.Size(),
+			Modified: info.ModTime(),
+			path:     p,
+		}
+		return nil
+	})
+	return files, err
+}
+
+// listRemote returns the objects of the folder by key
+func (s *S3ServiceV2) listRemote(folder *SyncFolder, scheme *KeyScheme) (map[string]*SyncFile, error) {
+	p := s3.NewListObjectsV2Paginator(s.Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.S3Profile.GetBucket()),
+		Prefix: aws.String(folder.listPrefix(scheme)),
+	})
+	objects := make(map[string]*SyncFile)
+	for p.HasMorePages() {
+		page, err := p.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range page.Contents {
+			key := aws.ToString(obj.Key)
+			rel, ok := folder.relPath(scheme, key)
+			if !ok {
+				continue
+			}
+			objects[key] = &SyncFile{
+				Key:      key,
+				RelPath:  rel,
+				Size:     aws.ToInt64(obj.Size),
+				Modified: aws.ToTime(obj.LastModified),
+				ETag:     strings.Trim(aws.ToString(obj.ETag), `"`),
+			}
+		}
+	}
+	return objects, nil
+}
+
+// etagChecksum returns the checksum the ETags of the profile's objects are compared with. The ETag of objects
+// encrypted with SSE-KMS isn't an md5 of the content, so nil is returned for sse-kms and they're compared by size.
+func (s *S3ServiceV2) etagChecksum() func(*SyncFile) (string, error) {
+	if s.Upload.Encryption == EncryptionSseKms {
+		return nil
+	}
+	return fileMd5
+}
+
+// fileMd5 returns the hex encoded md5 of a local file
+func fileMd5(f *SyncFile) (string, error) {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	h := md5.New()
+	if _, err = io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
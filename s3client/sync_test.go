@@ -0,0 +1,126 @@
+package s3client
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPlanSync(t *testing.T) {
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+	local := map[string]*SyncFile{
+		"same":           {Key: "same", RelPath: "same", Size: 3, Modified: older, path: "abc"},
+		"local-only":     {Key: "local-only", RelPath: "local-only", Size: 1, Modified: older},
+		"size":           {Key: "size", RelPath: "size", Size: 4, Modified: older},
+		"checksum-local": {Key: "checksum-local", RelPath: "checksum-local", Size: 3, Modified: newer, path: "xyz"},
+		"checksum-older": {Key: "checksum-older", RelPath: "checksum-older", Size: 3, Modified: older, path: "xyz"},
+		"multipart":      {Key: "multipart", RelPath: "multipart", Size: 3, Modified: older, path: "xyz"},
+	}
+	remote := map[string]*SyncFile{
+		"same":           {Key: "same", RelPath: "same", Size: 3, Modified: older, ETag: "ABC"},
+		"remote-only":    {Key: "remote-only", RelPath: "remote-only", Size: 1, Modified: older},
+		"size":           {Key: "size", RelPath: "size", Size: 5, Modified: newer},
+		"checksum-local": {Key: "checksum-local", RelPath: "checksum-local", Size: 3, Modified: older, ETag: "abc"},
+		"checksum-older": {Key: "checksum-older", RelPath: "checksum-older", Size: 3, Modified: newer, ETag: "abc"},
+		"multipart":      {Key: "multipart", RelPath: "multipart", Size: 3, Modified: newer, ETag: "abc-2"},
+	}
+	// the test checksum is the local path
+	checksum := func(f *SyncFile) (string, error) {
+		return f.path, nil
+	}
+	tests := []struct {
+		name    string
+		opts    SyncOptions
+		want    []string
+		wantErr bool
+	}{
+		{"push", SyncOptions{Mode: SyncPush}, []string{
+			"upload checksum-local", "upload checksum-older", "upload local-only", "upload size"}, false},
+		{"push delete", SyncOptions{Mode: SyncPush, Delete: true}, []string{
+			"upload checksum-local", "upload checksum-older", "upload local-only", "delete-remote remote-only",
+			"upload size"}, false},
+		{"pull", SyncOptions{Mode: SyncPull}, []string{
+			"download checksum-local", "download checksum-older", "download remote-only", "download size"}, false},
+		{"pull delete", SyncOptions{Mode: SyncPull, Delete: true}, []string{
+			"download checksum-local", "download checksum-older", "delete-local local-only", "download remote-only",
+			"download size"}, false},
+		{"both", SyncOptions{Mode: SyncBoth}, []string{
+			"upload checksum-local", "download checksum-older", "upload local-only", "download remote-only",
+			"download size"}, false},
+		{"both delete", SyncOptions{Mode: SyncBoth, Delete: true}, nil, true},
+		{"invalid mode", SyncOptions{Mode: "mirror"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actions, err := PlanSync(local, remote, tt.opts, checksum)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PlanSync() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got []string
+			for _, a := range actions {
+				got = append(got, a.Action+" "+a.Key)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PlanSync() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyncFolder_relPath(t *testing.T) {
+	scheme := NewKeyScheme("house", "")
+	pdfs := NewPdfSyncFolder("/data/disclosures", defaultArtifactClass(ClassDisclosures), scheme,
+		[]string{"2023.ptr-pdfs.CA12.Doe.Jane.20012345.pdf"})
+	images := &SyncFolder{Dir: "/data/images", Class: defaultArtifactClass(ClassImages)}
+	tests := []struct {
+		name   string
+		folder *SyncFolder
+		key    string
+		want   string
+		wantOk bool
+	}{
+		{"known pdf", pdfs, "house/2023/ptr/20012345.pdf", "2023.ptr-pdfs.CA12.Doe.Jane.20012345.pdf", true},
+		{"unknown pdf", pdfs, "house/2023/ptr/20099999.pdf", "", false},
+		{"image", images, "house/images/a/a-0.png", "a/a-0.png", true},
+		{"other class", images, "house/csv/a-0.csv", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.folder.relPath(scheme, tt.key)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("relPath() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestListLocal(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "a"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a", "a-0.png"), []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := listLocal(&SyncFolder{Dir: dir, Class: defaultArtifactClass(ClassImages)}, NewKeyScheme("", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, ok := files["images/a/a-0.png"]
+	if len(files) != 1 || !ok || f.RelPath != "a/a-0.png" || f.Size != 3 {
+		t.Errorf("listLocal() = %v, want images/a/a-0.png", files)
+	}
+	sum, err := fileMd5(f)
+	if err != nil || sum != "bff139fa05ac583f685a523ab3d110a0" {
+		t.Errorf("fileMd5() = %q, %v", sum, err)
+	}
+
+	files, err = listLocal(&SyncFolder{Dir: filepath.Join(dir, "missing"), Class: defaultArtifactClass(ClassImages)},
+		NewKeyScheme("", ""))
+	if err != nil || len(files) != 0 {
+		t.Errorf("listLocal() of a missing folder = %v, %v, want no files", files, err)
+	}
+}
@@ -0,0 +1,159 @@
+package s3client
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/paulschick/disclosureupdater/model"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Upload checksums
+const (
+	// ChecksumCrc32c has S3 verify a CRC32C of every part, and a Content-MD5 of files uploaded at once
+	ChecksumCrc32c = "crc32c"
+	// ChecksumMd5 only sends a Content-MD5 of files uploaded at once, for hosts without additional checksums
+	ChecksumMd5 = "md5"
+)
+
+// Server-side encryption of uploads
+const (
+	// EncryptionSseS3 encrypts objects with keys managed by S3
+	EncryptionSseS3 = "sse-s3"
+	// EncryptionSseKms encrypts objects with a KMS key, the AWS managed key of S3 unless a key ID is set
+	EncryptionSseKms = "sse-kms"
+)
+
+const (
+	// DefaultPartSize is the part size of multipart uploads in MiB
+	DefaultPartSize int64 = 16
+	// DefaultUploadConcurrency is the number of parts of a file uploaded at the same time
+	DefaultUploadConcurrency = manager.DefaultUploadConcurrency
+	// DefaultMaxAttempts is the maximum number of attempts of each request
+	DefaultMaxAttempts = 5
+)
+
+const mib = 1024 * 1024
+
+// uploadOptions returns the upload configuration with defaults for the zero values
+func uploadOptions(upload model.S3UploadConfig) (model.S3UploadConfig, error) {
+	if upload.PartSize == 0 {
+		upload.PartSize = DefaultPartSize
+	}
+	if upload.PartSize*mib < manager.MinUploadPartSize {
+		return upload, fmt.Errorf("part size must be at least %d MiB", manager.MinUploadPartSize/mib)
+	}
+	if upload.Concurrency <= 0 {
+		upload.Concurrency = DefaultUploadConcurrency
+	}
+	if upload.MaxAttempts <= 0 {
+		upload.MaxAttempts = DefaultMaxAttempts
+	}
+	upload.Checksum = strings.ToLower(upload.Checksum)
+	switch upload.Checksum {
+	case "":
+		upload.Checksum = ChecksumCrc32c
+	case ChecksumCrc32c, ChecksumMd5:
+	default:
+		return upload, fmt.Errorf("invalid checksum %q, expected %s or %s", upload.Checksum, ChecksumCrc32c, ChecksumMd5)
+	}
+	upload.Encryption = strings.ToLower(upload.Encryption)
+	switch upload.Encryption {
+	case "", EncryptionSseS3, EncryptionSseKms:
+	default:
+		return upload, fmt.Errorf("invalid encryption %q, expected %s or %s", upload.Encryption, EncryptionSseS3,
+			EncryptionSseKms)
+	}
+	if upload.KmsKeyId != "" && upload.Encryption != EncryptionSseKms {
+		return upload, fmt.Errorf("a KMS key ID requires %s encryption", EncryptionSseKms)
+	}
+	if err := validateTags(upload.Tags); err != nil {
+		return upload, err
+	}
+	return upload, nil
+}
+
+// newUploader returns a transfer manager uploader, which uploads files larger than the part size in parts
+func newUploader(client *s3.Client, upload model.S3UploadConfig) *manager.Uploader {
+	return manager.NewUploader(client, func(u *manager.Uploader) {
+		u.PartSize = upload.PartSize * mib
+		u.Concurrency = upload.Concurrency
+	})
+}
+
+// UploadFile uploads the file to the object key with the content type and storage class of the artifact class,
+// and the encryption and tags of the profile. Disclosure PDFs also get the metadata and tags of their member.
+// Files larger than the part size are uploaded in parts. Content-MD5 is only sent for files uploaded at once,
+// the transfer manager ignores it for multipart uploads, so those are verified by CRC32C only.
+// It returns the uploaded object for the bucket index.
+func (s *S3ServiceV2) UploadFile(file *os.File, key string, class *ArtifactClass) (*BucketObject, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	input := &s3.PutObjectInput{
+		Bucket:       aws.String(s.S3Profile.GetBucket()),
+		Key:          aws.String(key),
+		Body:         file,
+		ContentType:  aws.String(class.ContentTypeOf(file.Name())),
+		StorageClass: types.StorageClass(class.StorageClass),
+	}
+	if s.Upload.Checksum == ChecksumCrc32c {
+		input.ChecksumAlgorithm = types.ChecksumAlgorithmCrc32c
+	}
+	contentMd5, sha256Hex, err := fileDigests(file)
+	if err != nil {
+		return nil, err
+	}
+	// verify-s3 compares the sha256 with the local file, the ETag of multipart uploads isn't a checksum of the file
+	input.Metadata = map[string]string{Sha256MetadataKey: sha256Hex}
+	s.setObjectAttributes(input, file.Name())
+	if info.Size() < s.Uploader.PartSize {
+		input.ContentMD5 = aws.String(contentMd5)
+		input.ContentLength = aws.Int64(info.Size())
+	}
+	out, err := s.Uploader.Upload(context.TODO(), input)
+	if err != nil {
+		fmt.Printf("Failed to upload file %s: %s\n", file.Name(), err)
+		return nil, err
+	}
+	storageClass := class.StorageClass
+	if storageClass == "" {
+		storageClass = string(types.StorageClassStandard)
+	}
+	return &BucketObject{
+		Key:          key,
+		Size:         info.Size(),
+		ETag:         strings.Trim(aws.ToString(out.ETag), `"`),
+		StorageClass: storageClass,
+		LastModified: time.Now().UTC(),
+	}, nil
+}
+
+// fileDigests returns the base64 encoded md5 of the rest of the file, the Content-MD5 header, and the hex
+// encoded sha256, and seeks back to where the file was
+func fileDigests(file io.ReadSeeker) (string, string, error) {
+	offset, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", "", err
+	}
+	md5Hash := md5.New()
+	sha256Hash := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(md5Hash, sha256Hash), file); err != nil {
+		return "", "", err
+	}
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		return "", "", err
+	}
+	return base64.StdEncoding.EncodeToString(md5Hash.Sum(nil)), hex.EncodeToString(sha256Hash.Sum(nil)), nil
+}
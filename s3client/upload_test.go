@@ -0,0 +1,64 @@
+package s3client
+
+import (
+	"github.com/paulschick/disclosureupdater/model"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUploadOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		upload  model.S3UploadConfig
+		want    model.S3UploadConfig
+		wantErr bool
+	}{
+		{"defaults", model.S3UploadConfig{}, model.S3UploadConfig{PartSize: DefaultPartSize,
+			Concurrency: DefaultUploadConcurrency, Checksum: ChecksumCrc32c, MaxAttempts: DefaultMaxAttempts}, false},
+		{"configured", model.S3UploadConfig{PartSize: 64, Concurrency: 10, Checksum: "MD5", MaxAttempts: 3},
+			model.S3UploadConfig{PartSize: 64, Concurrency: 10, Checksum: ChecksumMd5, MaxAttempts: 3}, false},
+		{"part size too small", model.S3UploadConfig{PartSize: 4}, model.S3UploadConfig{}, true},
+		{"invalid checksum", model.S3UploadConfig{Checksum: "sha1"}, model.S3UploadConfig{}, true},
+		{"sse-kms", model.S3UploadConfig{Encryption: "SSE-KMS", KmsKeyId: "alias/disclosures",
+			Tags: map[string]string{"project": "disclosures"}}, model.S3UploadConfig{PartSize: DefaultPartSize,
+			Concurrency: DefaultUploadConcurrency, Checksum: ChecksumCrc32c, MaxAttempts: DefaultMaxAttempts,
+			Encryption: EncryptionSseKms, KmsKeyId: "alias/disclosures",
+			Tags: map[string]string{"project": "disclosures"}}, false},
+		{"invalid encryption", model.S3UploadConfig{Encryption: "sse-c"}, model.S3UploadConfig{}, true},
+		{"kms key without sse-kms", model.S3UploadConfig{Encryption: EncryptionSseS3, KmsKeyId: "alias/disclosures"},
+			model.S3UploadConfig{}, true},
+		{"too many tags", model.S3UploadConfig{Tags: map[string]string{"a": "", "b": "", "c": "", "d": "", "e": "",
+			"f": "", "g": ""}}, model.S3UploadConfig{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := uploadOptions(tt.upload)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("uploadOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("uploadOptions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileDigests(t *testing.T) {
+	r := strings.NewReader("png")
+	contentMd5, sha256Hex, err := fileDigests(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "v/E5+gWsWD9oWlI6s9EQoA=="; contentMd5 != want {
+		t.Errorf("fileDigests() md5 = %q, want %q", contentMd5, want)
+	}
+	if want := "8f8cbb7dcf46e0bc7d53265749a6c17d116093a6ba95e442764060c76fd4a86c"; sha256Hex != want {
+		t.Errorf("fileDigests() sha256 = %q, want %q", sha256Hex, want)
+	}
+	// the body is read again by the upload
+	if rest, _ := io.ReadAll(r); string(rest) != "png" {
+		t.Errorf("fileDigests() didn't seek back, %q left", rest)
+	}
+}
@@ -0,0 +1,185 @@
+package s3client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/paulschick/disclosureupdater/common/workerpool"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Sha256MetadataKey is the user metadata of uploaded objects with the hex encoded sha256 of the file
+const Sha256MetadataKey = "sha256"
+
+// Verify statuses
+const (
+	// VerifyOk objects match the local file by sha256, or md5 for objects uploaded without sha256
+	VerifyOk = "ok"
+	// VerifyMissing files aren't in the bucket
+	VerifyMissing = "missing"
+	// VerifyCorrupt objects differ from the local file by size or checksum
+	VerifyCorrupt = "corrupt"
+	// VerifyOrphaned objects have no local file
+	VerifyOrphaned = "orphaned"
+	// VerifyUnverified objects have the size of the local file, but were uploaded in parts without sha256
+	VerifyUnverified = "unverified"
+	// VerifyError objects couldn't be compared, the reason is the error
+	VerifyError = "error"
+)
+
+// VerifyResult is the status of a local file or object
+type VerifyResult struct {
+	Key     string
+	RelPath string
+	Status  string
+	Reason  string
+}
+
+// Verify compares every file of the folder with its object by size, and by the sha256 metadata of the object,
+// or the md5 ETag of objects uploaded at once without it. It returns a result per key sorted by key.
+func (s *S3ServiceV2) Verify(folder *SyncFolder) ([]*VerifyResult, error) {
+	scheme := KeySchemeFromProfile(s.S3Profile)
+	local, err := listLocal(folder, scheme)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := s.listRemote(folder, scheme)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*VerifyResult, 0, len(local))
+	tasks := make([]*workerpool.Task, 0, len(local))
+	for key, l := range local {
+		result := &VerifyResult{Key: key, RelPath: l.RelPath}
+		results = append(results, result)
+		r, ok := remote[key]
+		if !ok {
+			result.Status = VerifyMissing
+			continue
+		}
+		if l.Size != r.Size {
+			result.Status = VerifyCorrupt
+			result.Reason = fmt.Sprintf("size %d, object %d", l.Size, r.Size)
+			continue
+		}
+		tasks = append(tasks, workerpool.NewTask(func(data interface{}) error {
+			res := data.(*VerifyResult)
+			return s.verifyObject(res, local[res.Key], remote[res.Key])
+		}, result, len(tasks)))
+	}
+	for key, r := range remote {
+		if _, ok := local[key]; !ok {
+			results = append(results, &VerifyResult{Key: key, RelPath: r.RelPath, Status: VerifyOrphaned})
+		}
+	}
+	if len(tasks) > 0 {
+		pool := workerpool.NewPool(tasks, syncConcurrency, len(tasks))
+		pool.Run()
+	}
+	var errStr string
+	for _, task := range tasks {
+		if task.Err != nil {
+			errStr = errStr + " " + task.Err.Error()
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Key < results[j].Key
+	})
+	if errStr != "" {
+		return results, errors.New(errStr)
+	}
+	return results, nil
+}
+
+// Reupload uploads the local file of a missing or corrupt result again
+func (s *S3ServiceV2) Reupload(folder *SyncFolder, result *VerifyResult) (*BucketObject, error) {
+	file, err := os.Open(filepath.Join(folder.Dir, filepath.FromSlash(result.RelPath)))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	return s.UploadFile(file, result.Key, folder.Class)
+}
+
+// verifyObject sets the status of the result by the checksums of the local file and object,
+// the status is VerifyError if they couldn't be compared
+func (s *S3ServiceV2) verifyObject(result *VerifyResult, local, remote *SyncFile) error {
+	sha256Hex, err := s.objectSha256(result.Key)
+	if err == nil {
+		result.Status, result.Reason, err = verifyChecksum(local, remote, sha256Hex, s.etagChecksum())
+	}
+	if err != nil {
+		result.Status = VerifyError
+		result.Reason = err.Error()
+	}
+	return err
+}
+
+// verifyChecksum compares a local file with an object of the same size. sha256Hex is the sha256 metadata
+// of the object, empty for objects uploaded without it. etagChecksum returns the checksum of a local file
+// the ETag is compared with, it is nil if the ETags of the bucket aren't the md5 of the content, as with SSE-KMS.
+func verifyChecksum(local, remote *SyncFile, sha256Hex string,
+	etagChecksum func(*SyncFile) (string, error)) (string, string, error) {
+	if sha256Hex != "" {
+		sum, err := fileSha256(local)
+		if err != nil {
+			return "", "", err
+		}
+		if !strings.EqualFold(sum, sha256Hex) {
+			return VerifyCorrupt, "sha256", nil
+		}
+		return VerifyOk, "sha256", nil
+	}
+	if remote.ETag == "" || strings.Contains(remote.ETag, "-") {
+		return VerifyUnverified, "multipart upload without sha256", nil
+	}
+	if etagChecksum == nil {
+		return VerifyUnverified, "sse-kms upload without sha256", nil
+	}
+	sum, err := etagChecksum(local)
+	if err != nil {
+		return "", "", err
+	}
+	if !strings.EqualFold(sum, remote.ETag) {
+		return VerifyCorrupt, "md5", nil
+	}
+	return VerifyOk, "md5", nil
+}
+
+// objectSha256 returns the sha256 metadata of the object
+func (s *S3ServiceV2) objectSha256(key string) (string, error) {
+	out, err := s.Client.HeadObject(context.TODO(), &s3.HeadObjectInput{
+		Bucket: aws.String(s.S3Profile.GetBucket()),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return "", err
+	}
+	return out.Metadata[Sha256MetadataKey], nil
+}
+
+// fileSha256 returns the hex encoded sha256 of a local file
+func fileSha256(f *SyncFile) (string, error) {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	h := sha256.New()
+	if _, err = io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,97 @@
+package s3client
+
+import (
+	"github.com/paulschick/disclosureupdater/internal/s3test"
+	"github.com/paulschick/disclosureupdater/model"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyChecksum(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "a-0.png")
+	if err := os.WriteFile(fp, []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	local := &SyncFile{Key: "images/a/a-0.png", Size: 3, path: fp}
+	pngMd5 := "bff139fa05ac583f685a523ab3d110a0"
+	pngSha256 := "8f8cbb7dcf46e0bc7d53265749a6c17d116093a6ba95e442764060c76fd4a86c"
+	tests := []struct {
+		name       string
+		etag       string
+		sha256Hex  string
+		kms        bool
+		wantStatus string
+		wantReason string
+	}{
+		{"sha256", "abc-2", pngSha256, false, VerifyOk, "sha256"},
+		{"sha256 corrupt", pngMd5, "0000", false, VerifyCorrupt, "sha256"},
+		{"md5", pngMd5, "", false, VerifyOk, "md5"},
+		{"md5 corrupt", "abc", "", false, VerifyCorrupt, "md5"},
+		{"multipart", "abc-2", "", false, VerifyUnverified, "multipart upload without sha256"},
+		{"sse-kms sha256", "abc", pngSha256, true, VerifyOk, "sha256"},
+		{"sse-kms", "abc", "", true, VerifyUnverified, "sse-kms upload without sha256"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := &SyncFile{Key: local.Key, Size: 3, ETag: tt.etag}
+			s := &S3ServiceV2{}
+			if tt.kms {
+				s.Upload.Encryption = EncryptionSseKms
+			}
+			status, reason, err := verifyChecksum(local, remote, tt.sha256Hex, s.etagChecksum())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if status != tt.wantStatus || reason != tt.wantReason {
+				t.Errorf("verifyChecksum() = %q, %q, want %q, %q", status, reason, tt.wantStatus, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestVerifyObject(t *testing.T) {
+	_, server := s3test.NewServer(t)
+	s3Profile := &model.S3StaticProfile{
+		S3DefaultProfile: model.S3DefaultProfile{
+			S3Bucket:     "disclosures",
+			S3Hostname:   server.URL,
+			UsePathStyle: true,
+		},
+		S3ApiKey:    "apiKey",
+		S3SecretKey: "secretKey",
+	}
+	service, err := NewS3ServiceV2(s3Profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp := filepath.Join(t.TempDir(), "a-0.png")
+	if err = os.WriteFile(fp, []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	local := &SyncFile{Key: "images/a/a-0.png", Size: 3, path: fp}
+	if _, err = service.UploadFile(file, local.Key, defaultArtifactClass(ClassImages)); err != nil {
+		t.Fatal(err)
+	}
+	remote := &SyncFile{Key: local.Key, Size: 3}
+
+	result := &VerifyResult{Key: local.Key}
+	if err = service.verifyObject(result, local, remote); err != nil || result.Status != VerifyOk {
+		t.Errorf("verifyObject() = %v, status %q, want %q", err, result.Status, VerifyOk)
+	}
+
+	// the object was deleted after the listing
+	result = &VerifyResult{Key: "images/a/a-1.png"}
+	err = service.verifyObject(result, local, remote)
+	if err == nil || result.Status != VerifyError || result.Reason != err.Error() {
+		t.Errorf("verifyObject() = %v, status %q, reason %q, want %q with the error", err, result.Status,
+			result.Reason, VerifyError)
+	}
+}
@@ -0,0 +1,531 @@
+package search
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Filing is the disclosure index metadata returned with the pages of a filing
+type Filing struct {
+	DocId      int    `json:"docId"`
+	MemberId   string `json:"memberId"`
+	Member     string `json:"member"`
+	StateDst   string `json:"stateDst"`
+	Year       int    `json:"year"`
+	FilingType string `json:"filingType"`
+	FilingDate string `json:"filingDate"`
+	SourcePdf  string `json:"sourcePdf"`
+}
+
+// Page is the text of one page of a filing, read from the PDF text layer or from the OCR output.
+// Page numbers start at 0, as in page image names. Text is empty for pages of a loaded index,
+// their text is read from the text file at TextOffset when a snippet is built.
+type Page struct {
+	DocId      int
+	Page       int
+	Method     string
+	Text       string
+	TextOffset int64
+	TextLength int
+}
+
+// Posting lists the token positions of a term on one page
+type Posting struct {
+	Page      int32
+	Positions []int32
+}
+
+// Hit is a page matching a query
+type Hit struct {
+	*Filing
+	Page    int     `json:"page"`
+	Method  string  `json:"method"`
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet"`
+	// pageIdx and start are the page and token position the snippet is built from
+	pageIdx int32
+	start   int
+}
+
+// Query filters the filings searched, zero values match every filing
+type Query struct {
+	Text        string
+	Years       []int
+	FilingTypes []string
+	MemberId    string
+	Limit       int
+}
+
+// Index is an inverted index of the words on every page of the disclosure PDFs.
+// Each term maps to the pages it's on and its positions on the page, so quoted phrases
+// can be matched. The page texts are kept in a separate file to build snippets of the hits,
+// so searches don't read them and updates only append the texts of new pages.
+type Index struct {
+	Filings  map[int]*Filing
+	Pages    []*Page
+	Postings map[string][]Posting
+	// textPath is the text file of a saved or loaded index
+	textPath string
+}
+
+func NewIndex() *Index {
+	return &Index{
+		Filings:  make(map[int]*Filing),
+		Pages:    make([]*Page, 0),
+		Postings: make(map[string][]Posting),
+	}
+}
+
+// AddFiling adds or replaces the metadata of a filing
+func (ix *Index) AddFiling(f *Filing) {
+	ix.Filings[f.DocId] = f
+}
+
+// Contains returns true if the filing is indexed
+func (ix *Index) Contains(docId int) bool {
+	_, ok := ix.Filings[docId]
+	return ok
+}
+
+// AddPage indexes the words of a page. Pages must be added after the metadata of their filing.
+func (ix *Index) AddPage(p *Page) {
+	pageIdx := int32(len(ix.Pages))
+	ix.Pages = append(ix.Pages, p)
+	positions := make(map[string][]int32)
+	for i, token := range tokenize(p.Text) {
+		positions[token.term] = append(positions[token.term], int32(i))
+	}
+	for term, termPositions := range positions {
+		ix.Postings[term] = append(ix.Postings[term], Posting{Page: pageIdx, Positions: termPositions})
+	}
+}
+
+// Search returns the pages that contain every term and quoted phrase of the query, best match first.
+// Pages are scored by the tf-idf of the query terms, so pages that mention rare words often rank highest.
+func (ix *Index) Search(q Query) ([]*Hit, error) {
+	terms, phrases := parseQuery(q.Text)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	// pages matching every term, with the sum of the term scores
+	var scores map[int32]float64
+	for _, term := range terms {
+		postings := ix.Postings[term]
+		idf := math.Log(1 + float64(len(ix.Pages))/float64(len(postings)+1))
+		termScores := make(map[int32]float64, len(postings))
+		for _, posting := range postings {
+			if scores != nil {
+				if _, ok := scores[posting.Page]; !ok {
+					continue
+				}
+			}
+			termScores[posting.Page] = scores[posting.Page] + float64(len(posting.Positions))*idf
+		}
+		scores = termScores
+		if len(scores) == 0 {
+			return nil, nil
+		}
+	}
+
+	filter := newFilter(q)
+	hits := make([]*Hit, 0)
+	for pageIdx, score := range scores {
+		page := ix.Pages[pageIdx]
+		filing := ix.Filings[page.DocId]
+		if filing == nil || !filter.match(filing) {
+			continue
+		}
+		start := 0
+		if len(phrases) > 0 {
+			var ok bool
+			if start, ok = ix.matchPhrases(pageIdx, phrases); !ok {
+				continue
+			}
+		} else {
+			start = ix.firstPosition(pageIdx, terms)
+		}
+		hits = append(hits, &Hit{
+			Filing:  filing,
+			Page:    page.Page,
+			Method:  page.Method,
+			Score:   score,
+			pageIdx: pageIdx,
+			start:   start,
+		})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if hits[i].DocId != hits[j].DocId {
+			return hits[i].DocId < hits[j].DocId
+		}
+		return hits[i].Page < hits[j].Page
+	})
+	if q.Limit > 0 && len(hits) > q.Limit {
+		hits = hits[:q.Limit]
+	}
+	return hits, ix.addSnippets(hits, phraseLength(phrases))
+}
+
+// addSnippets sets the snippet of every hit, reading the page texts of a loaded index from its text file
+func (ix *Index) addSnippets(hits []*Hit, length int) error {
+	var texts *os.File
+	defer func() {
+		if texts != nil {
+			_ = texts.Close()
+		}
+	}()
+	for _, hit := range hits {
+		page := ix.Pages[hit.pageIdx]
+		if page.Text == "" && page.TextLength > 0 && texts == nil {
+			var err error
+			if texts, err = os.Open(ix.textPath); err != nil {
+				return err
+			}
+		}
+		text, err := page.text(texts)
+		if err != nil {
+			return err
+		}
+		hit.Snippet = snippet(text, hit.start, length)
+	}
+	return nil
+}
+
+// matchPhrases returns the position of the first phrase if every phrase is on the page
+func (ix *Index) matchPhrases(pageIdx int32, phrases [][]string) (int, bool) {
+	first := -1
+	for _, phrase := range phrases {
+		position, ok := ix.phrasePosition(pageIdx, phrase)
+		if !ok {
+			return 0, false
+		}
+		if first < 0 {
+			first = position
+		}
+	}
+	return first, true
+}
+
+// phrasePosition returns the first position of the phrase on the page
+func (ix *Index) phrasePosition(pageIdx int32, phrase []string) (int, bool) {
+	positions := make([]map[int32]bool, len(phrase))
+	for i, term := range phrase {
+		positions[i] = make(map[int32]bool)
+		for _, position := range ix.positions(pageIdx, term) {
+			positions[i][position] = true
+		}
+	}
+	for _, start := range ix.positions(pageIdx, phrase[0]) {
+		match := true
+		for i := 1; i < len(phrase) && match; i++ {
+			match = positions[i][start+int32(i)]
+		}
+		if match {
+			return int(start), true
+		}
+	}
+	return 0, false
+}
+
+// firstPosition returns the first position of any of the terms on the page
+func (ix *Index) firstPosition(pageIdx int32, terms []string) int {
+	first := int32(math.MaxInt32)
+	for _, term := range terms {
+		if positions := ix.positions(pageIdx, term); len(positions) > 0 && positions[0] < first {
+			first = positions[0]
+		}
+	}
+	return int(first)
+}
+
+func (ix *Index) positions(pageIdx int32, term string) []int32 {
+	postings := ix.Postings[term]
+	// postings are in the order pages were added
+	i := sort.Search(len(postings), func(i int) bool {
+		return postings[i].Page >= pageIdx
+	})
+	if i < len(postings) && postings[i].Page == pageIdx {
+		return postings[i].Positions
+	}
+	return nil
+}
+
+// TextPath returns the file the page texts of the index saved to path are written to
+func TextPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".text"
+}
+
+// Save writes the index to a gzip compressed gob file, and the page texts to the file of TextPath.
+// The texts of pages added since the index was loaded are appended to the text file, it is only rewritten
+// when every page was added in memory. Rewritten files are written to temporary files first and renamed
+// over the old ones, so an interrupted save leaves the old index intact.
+func (ix *Index) Save(path string) error {
+	textPath := TextPath(path)
+	pages, tmpTextPath, err := ix.saveTexts(textPath)
+	if err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	err = writeGob(tmpPath, &Index{Filings: ix.Filings, Pages: pages, Postings: ix.Postings})
+	if err == nil && tmpTextPath != "" {
+		err = os.Rename(tmpTextPath, textPath)
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		if tmpTextPath != "" {
+			_ = os.Remove(tmpTextPath)
+		}
+		return err
+	}
+	ix.Pages, ix.textPath = pages, textPath
+	return nil
+}
+
+// writeGob writes the index to a gzip compressed gob file
+func writeGob(path string, ix *Index) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := gzip.NewWriter(f)
+	err = gob.NewEncoder(w).Encode(ix)
+	if err == nil {
+		err = w.Close()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// saveTexts writes the texts of the pages in memory to the text file. It returns copies of the pages with the
+// offsets of their texts instead of the texts, and the temporary file to rename over the text file if it is
+// rewritten. Texts of a loaded index are appended to its text file, the offsets of the loaded pages stay valid.
+func (ix *Index) saveTexts(textPath string) ([]*Page, string, error) {
+	writePath, flag := textPath+".tmp", os.O_CREATE|os.O_WRONLY|os.O_TRUNC
+	if ix.textPath == textPath {
+		writePath, flag = textPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND
+	} else if ix.textPath != "" {
+		// the index is saved to another path, the texts of the loaded pages are copied to the new text file
+		if err := ix.readTexts(); err != nil {
+			return nil, "", err
+		}
+	}
+	f, err := os.OpenFile(writePath, flag, 0644)
+	if err != nil {
+		return nil, "", err
+	}
+	pages, err := writeTexts(f, ix.Pages)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if writePath == textPath {
+		return pages, "", err
+	}
+	if err != nil {
+		_ = os.Remove(writePath)
+	}
+	return pages, writePath, err
+}
+
+// writeTexts appends the texts of the pages in memory to the file
+func writeTexts(f *os.File, pages []*Page) ([]*Page, error) {
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	saved := make([]*Page, len(pages))
+	for i, page := range pages {
+		p := *page
+		saved[i] = &p
+		if p.Text == "" {
+			continue
+		}
+		if _, err = io.WriteString(f, p.Text); err != nil {
+			return nil, err
+		}
+		p.TextOffset, p.TextLength, p.Text = offset, len(p.Text), ""
+		offset += int64(p.TextLength)
+	}
+	return saved, nil
+}
+
+// readTexts reads the texts of every page from the text file into memory
+func (ix *Index) readTexts() error {
+	texts, err := os.Open(ix.textPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = texts.Close()
+	}()
+	for _, page := range ix.Pages {
+		if page.Text, err = page.text(texts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// text returns the text of the page, from the text file if it isn't in memory
+func (p *Page) text(texts io.ReaderAt) (string, error) {
+	if p.Text != "" || p.TextLength == 0 {
+		return p.Text, nil
+	}
+	buf := make([]byte, p.TextLength)
+	if _, err := texts.ReadAt(buf, p.TextOffset); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// Load reads an index written by Save, the page texts are read from the text file when searching
+func Load(path string) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	ix := NewIndex()
+	if err = gob.NewDecoder(r).Decode(ix); err != nil {
+		return nil, err
+	}
+	ix.textPath = TextPath(path)
+	return ix, nil
+}
+
+// filter matches filings against the year, filing type and member of a query
+type filter struct {
+	years       map[int]bool
+	filingTypes map[string]bool
+	memberId    string
+}
+
+func newFilter(q Query) *filter {
+	f := &filter{
+		years:       make(map[int]bool),
+		filingTypes: make(map[string]bool),
+		memberId:    q.MemberId,
+	}
+	for _, year := range q.Years {
+		f.years[year] = true
+	}
+	for _, filingType := range q.FilingTypes {
+		f.filingTypes[strings.ToUpper(filingType)] = true
+	}
+	return f
+}
+
+func (f *filter) match(filing *Filing) bool {
+	if len(f.years) > 0 && !f.years[filing.Year] {
+		return false
+	}
+	if len(f.filingTypes) > 0 && !f.filingTypes[filing.FilingType] {
+		return false
+	}
+	return f.memberId == "" || f.memberId == filing.MemberId
+}
+
+// token is a lower cased word and its byte offsets in the text
+type token struct {
+	term       string
+	start, end int
+}
+
+func tokenize(text string) []token {
+	tokens := make([]token, 0)
+	start := -1
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, token{term: strings.ToLower(text[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{term: strings.ToLower(text[start:]), start: start, end: len(text)})
+	}
+	return tokens
+}
+
+// parseQuery returns every term of the query, and the terms of each quoted phrase
+func parseQuery(text string) ([]string, [][]string) {
+	terms := make([]string, 0)
+	phrases := make([][]string, 0)
+	for i, part := range strings.Split(text, `"`) {
+		partTerms := make([]string, 0)
+		for _, t := range tokenize(part) {
+			partTerms = append(partTerms, t.term)
+		}
+		terms = append(terms, partTerms...)
+		// odd parts are between quotes
+		if i%2 == 1 && len(partTerms) > 1 {
+			phrases = append(phrases, partTerms)
+		}
+	}
+	return terms, phrases
+}
+
+func phraseLength(phrases [][]string) int {
+	if len(phrases) == 0 {
+		return 1
+	}
+	return len(phrases[0])
+}
+
+// snippetWords is the number of words shown before and after the match in a snippet
+const snippetWords = 8
+
+// snippet returns the words around the match at the token position, with the match in [brackets]
+func snippet(text string, position, length int) string {
+	tokens := tokenize(text)
+	if position >= len(tokens) {
+		return ""
+	}
+	end := min(position+length, len(tokens))
+	from := max(0, position-snippetWords)
+	to := min(len(tokens), end+snippetWords)
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("... ")
+	}
+	b.WriteString(text[tokens[from].start:tokens[position].start])
+	b.WriteString("[")
+	b.WriteString(text[tokens[position].start:tokens[end-1].end])
+	b.WriteString("]")
+	b.WriteString(text[tokens[end-1].end:tokens[to-1].end])
+	if to < len(tokens) {
+		b.WriteString(" ...")
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
@@ -0,0 +1,185 @@
+package search
+
+import (
+	"bytes"
+	"github.com/paulschick/disclosureupdater/model"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func testIndex() *Index {
+	ix := NewIndex()
+	ix.AddFiling(&Filing{DocId: 20012345, MemberId: "doe-jane-ca", Member: "Jane Doe", Year: 2023, FilingType: "P"})
+	ix.AddFiling(&Filing{DocId: 8220001, MemberId: "smith-james-oh", Member: "James Smith", Year: 2021, FilingType: "O"})
+	ix.AddPage(&Page{DocId: 20012345, Page: 0, Method: model.ExtractionText,
+		Text: "SP Apple Inc. - Common Stock (AAPL) [ST] P 01/03/2023 01/20/2023 $1,001 - $15,000"})
+	ix.AddPage(&Page{DocId: 20012345, Page: 1, Method: model.ExtractionText,
+		Text: "Microsoft Corporation (MSFT) [ST] S 02/03/2023 02/10/2023 $15,001 - $50,000"})
+	ix.AddPage(&Page{DocId: 8220001, Page: 2, Method: model.ExtractionOcr,
+		Text: "Schedule A: Assets\nInc. Apple Orchard LLC, 40 acres\nApple Inc. stock held in IRA"})
+	return ix
+}
+
+func TestIndex_Search(t *testing.T) {
+	type page struct {
+		docId int
+		page  int
+	}
+	tests := []struct {
+		name  string
+		query Query
+		want  []page
+	}{
+		{"single term", Query{Text: "aapl"}, []page{{20012345, 0}}},
+		{"every term on the page", Query{Text: "apple stock"}, []page{{8220001, 2}, {20012345, 0}}},
+		{"phrase", Query{Text: `"apple inc"`}, []page{{8220001, 2}, {20012345, 0}}},
+		{"phrase in order", Query{Text: `"inc apple"`}, []page{{8220001, 2}}},
+		{"year filter", Query{Text: "apple", Years: []int{2023}}, []page{{20012345, 0}}},
+		{"filing type filter", Query{Text: "apple", FilingTypes: []string{"o"}}, []page{{8220001, 2}}},
+		{"member filter", Query{Text: "stock", MemberId: "doe-jane-ca"}, []page{{20012345, 0}}},
+		{"limit", Query{Text: "st", Limit: 1}, []page{{20012345, 0}}},
+		{"no match", Query{Text: "apple tesla"}, nil},
+		{"empty query", Query{Text: " - "}, nil},
+	}
+	ix := testIndex()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, err := ix.Search(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []page
+			for _, hit := range hits {
+				got = append(got, page{hit.DocId, hit.Page})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query.Text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndex_SearchSnippet(t *testing.T) {
+	hits, err := testIndex().Search(Query{Text: `"apple inc" stock`, Years: []int{2021}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 {
+		t.Fatalf("Search() returned %d hits, want 1", len(hits))
+	}
+	want := "... A: Assets Inc. Apple Orchard LLC, 40 acres [Apple Inc]. stock held in IRA"
+	if hits[0].Snippet != want {
+		t.Errorf("Snippet = %q, want %q", hits[0].Snippet, want)
+	}
+	if hits[0].Member != "James Smith" || hits[0].Method != model.ExtractionOcr {
+		t.Errorf("hit = %+v, want the filing metadata and extraction method", hits[0])
+	}
+}
+
+func TestIndex_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.gob")
+	ix := testIndex()
+	if err := ix.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, ix) {
+		t.Errorf("Load() returned a different index")
+	}
+}
+
+// TestIndex_SaveUpdate adds a filing to a loaded index, the texts of the loaded pages are kept in the text file
+func TestIndex_SaveUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.gob")
+	if err := testIndex().Save(path); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(TextPath(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ix, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, page := range ix.Pages {
+		if page.Text != "" {
+			t.Errorf("Load() read the text of page %d of %d", page.Page, page.DocId)
+		}
+	}
+	ix.AddFiling(&Filing{DocId: 20099999, MemberId: "roe-john-tx", Member: "John Roe", Year: 2024, FilingType: "P"})
+	ix.AddPage(&Page{DocId: 20099999, Page: 0, Method: model.ExtractionText,
+		Text: "Apple Inc. - Common Stock (AAPL) [ST] S 03/01/2024 03/05/2024 $1,001 - $15,000"})
+	if err = ix.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	after, err := os.ReadFile(TextPath(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(after, before) || len(after) == len(before) {
+		t.Errorf("Save() didn't append the new page to the text file")
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits, err := loaded.Search(Query{Text: "aapl"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int]string{
+		20012345: "SP Apple Inc. - Common Stock ([AAPL]) [ST] P 01/03/2023 01/20/2023 ...",
+		20099999: "Apple Inc. - Common Stock ([AAPL]) [ST] S 03/01/2024 03/05/2024 ...",
+	}
+	if len(hits) != len(want) {
+		t.Fatalf("Search() returned %d hits, want %d", len(hits), len(want))
+	}
+	for _, hit := range hits {
+		if hit.Snippet != want[hit.DocId] {
+			t.Errorf("Snippet of %d = %q, want %q", hit.DocId, hit.Snippet, want[hit.DocId])
+		}
+	}
+}
+
+// TestIndex_SaveFailure keeps the saved index when a save fails
+func TestIndex_SaveFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.gob")
+	if err := testIndex().Save(path); err != nil {
+		t.Fatal(err)
+	}
+	// the temporary file can't be created
+	if err := os.Mkdir(path+".tmp", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewIndex().Save(path); err == nil {
+		t.Fatal("Save() error = nil, want an error")
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits, err := loaded.Search(Query{Text: "msft"})
+	if err != nil || len(hits) != 1 || hits[0].Snippet == "" {
+		t.Errorf("Search() = %v, %v, want the page of the saved index", hits, err)
+	}
+}
+
+func TestOcrText(t *testing.T) {
+	words := []*model.OcrResult{
+		{BlockNum: 1, ParNum: 1, LineNum: 1, Word: "Periodic"},
+		{BlockNum: 1, ParNum: 1, LineNum: 1, Word: "Transaction"},
+		{BlockNum: 1, ParNum: 1, LineNum: 1, Word: ""},
+		{BlockNum: 2, ParNum: 1, LineNum: 1, Word: "Apple"},
+		{BlockNum: 2, ParNum: 1, LineNum: 2, Word: "Inc."},
+	}
+	if got, want := OcrText(words), "Periodic Transaction\nApple\nInc."; got != want {
+		t.Errorf("OcrText() = %q, want %q", got, want)
+	}
+}
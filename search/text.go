@@ -0,0 +1,27 @@
+package search
+
+import (
+	"github.com/paulschick/disclosureupdater/model"
+	"strings"
+)
+
+// OcrText joins the words of an OCR'd page into text, one line per Tesseract line
+func OcrText(words []*model.OcrResult) string {
+	var b strings.Builder
+	var block, par, line int
+	for i, w := range words {
+		if w.Word == "" {
+			continue
+		}
+		if i > 0 && b.Len() > 0 {
+			if w.BlockNum != block || w.ParNum != par || w.LineNum != line {
+				b.WriteString("\n")
+			} else {
+				b.WriteString(" ")
+			}
+		}
+		block, par, line = w.BlockNum, w.ParNum, w.LineNum
+		b.WriteString(w.Word)
+	}
+	return b.String()
+}
@@ -0,0 +1,63 @@
+// Package storage reads and writes artifacts on local disk, an S3-compatible store or in memory through the
+// same interface, so commands can use either target and tests don't need a bucket.
+package storage
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrNotExist is returned by Get and Stat for keys without an object, errors.Is(err, os.ErrNotExist) is true for it
+var ErrNotExist = fs.ErrNotExist
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	Key      string
+	Size     int64
+	Modified time.Time
+	// ETag is the hex md5 of single part uploads and of in-memory objects, empty for local files
+	ETag        string
+	ContentType string
+	Metadata    map[string]string
+}
+
+// PutOptions are the optional attributes of a new object. Local files don't store them.
+type PutOptions struct {
+	ContentType  string
+	StorageClass string
+	Metadata     map[string]string
+}
+
+// Backend stores objects by slash separated keys. Put and Get stream the content, List calls fn for every
+// object whose key starts with the prefix, and stops at the first error fn returns.
+// Deleting a missing key isn't an error, as in S3.
+type Backend interface {
+	Put(ctx context.Context, key string, r io.Reader, opts *PutOptions) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	List(ctx context.Context, prefix string, fn func(*ObjectInfo) error) error
+	Delete(ctx context.Context, key string) error
+}
+
+// cleanKey returns the key without leading slashes and dot segments, an empty key for the root
+func cleanKey(key string) string {
+	key = path.Clean("/" + strings.ReplaceAll(key, "\\", "/"))
+	return strings.TrimPrefix(key, "/")
+}
+
+// Write streams the output of write to the key. The output is piped to Put, so it isn't buffered in memory
+// or on disk, and Put fails with the error of write.
+func Write(ctx context.Context, b Backend, key string, opts *PutOptions, write func(io.Writer) error) error {
+	pr, pw := io.Pipe()
+	go func() {
+		_ = pw.CloseWithError(write(pw))
+	}()
+	err := b.Put(ctx, key, pr, opts)
+	// unblocks write if Put returned before reading everything
+	_ = pr.CloseWithError(err)
+	return err
+}
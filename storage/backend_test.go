@@ -0,0 +1,202 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/paulschick/disclosureupdater/internal/s3test"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testBackend runs the behavior every backend shares
+func testBackend(t *testing.T, b Backend) {
+	ctx := context.Background()
+	if _, err := b.Stat(ctx, "exports/index.csv"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Stat() of a missing key error = %v, want os.ErrNotExist", err)
+	}
+	if _, err := b.Get(ctx, "exports/index.csv"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Get() of a missing key error = %v, want os.ErrNotExist", err)
+	}
+
+	objects := map[string]string{
+		"exports/index.csv":      "docId,year\n",
+		"exports/transactions":   "[]",
+		"images/a/a-0.png":       "png",
+		"/images/a/../b/b-0.png": "png",
+	}
+	for key, data := range objects {
+		if err := b.Put(ctx, key, strings.NewReader(data), &PutOptions{ContentType: "text/csv"}); err != nil {
+			t.Fatalf("Put(%q) error = %v", key, err)
+		}
+	}
+	r, err := b.Get(ctx, "exports/index.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(r)
+	_ = r.Close()
+	if err != nil || string(data) != "docId,year\n" {
+		t.Errorf("Get() = %q, %v", data, err)
+	}
+	info, err := b.Stat(ctx, "exports/index.csv")
+	if err != nil || info.Key != "exports/index.csv" || info.Size != 11 || info.Modified.IsZero() {
+		t.Errorf("Stat() = %+v, %v", info, err)
+	}
+
+	var keys []string
+	err = b.List(ctx, "images/", func(info *ObjectInfo) error {
+		keys = append(keys, info.Key)
+		return nil
+	})
+	if want := []string{"images/a/a-0.png", "images/b/b-0.png"}; err != nil || !reflect.DeepEqual(keys, want) {
+		t.Errorf("List() = %v, %v, want %v", keys, err, want)
+	}
+	stop := errors.New("stop")
+	if err = b.List(ctx, "", func(*ObjectInfo) error { return stop }); !errors.Is(err, stop) {
+		t.Errorf("List() error = %v, want the error of fn", err)
+	}
+
+	if err = b.Delete(ctx, "exports/index.csv"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = b.Stat(ctx, "exports/index.csv"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Stat() after Delete() error = %v, want os.ErrNotExist", err)
+	}
+	if err = b.Delete(ctx, "exports/index.csv"); err != nil {
+		t.Errorf("Delete() of a missing key error = %v", err)
+	}
+
+	if err = Write(ctx, b, "exports/streamed.jsonl", nil, func(w io.Writer) error {
+		_, err := io.WriteString(w, "{}\n")
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if info, err = b.Stat(ctx, "exports/streamed.jsonl"); err != nil || info.Size != 3 {
+		t.Errorf("Stat() after Write() = %+v, %v", info, err)
+	}
+	failed := errors.New("encoding failed")
+	if err = Write(ctx, b, "exports/failed.jsonl", nil, func(w io.Writer) error {
+		_, _ = io.WriteString(w, "{")
+		return failed
+	}); !errors.Is(err, failed) {
+		t.Errorf("Write() error = %v, want the error of write", err)
+	}
+	if _, err = b.Stat(ctx, "exports/failed.jsonl"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Write() that failed left an object, Stat() error = %v", err)
+	}
+}
+
+func TestLocal(t *testing.T) {
+	testBackend(t, NewLocal(t.TempDir()))
+}
+
+func TestS3(t *testing.T) {
+	fake, server := s3test.NewServer(t)
+	client := s3.New(s3.Options{
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+		Region:       "us-east-1",
+		Credentials:  credentials.NewStaticCredentialsProvider("apiKey", "secretKey", ""),
+	})
+	b := NewS3(client, manager.NewUploader(client), "bucket", "/house/exports/")
+	testBackend(t, b)
+
+	// keys are relative to the prefix, objects outside of it aren't listed
+	if keys := fake.Keys(); !reflect.DeepEqual(keys, []string{"bucket/house/exports/exports/streamed.jsonl",
+		"bucket/house/exports/exports/transactions", "bucket/house/exports/images/a/a-0.png",
+		"bucket/house/exports/images/b/b-0.png"}) {
+		t.Errorf("objects = %v", keys)
+	}
+	outside := NewS3(client, manager.NewUploader(client), "bucket", "house/other")
+	if err := outside.Put(context.Background(), "a.csv", strings.NewReader("a"), nil); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	err := b.List(context.Background(), "", func(info *ObjectInfo) error {
+		keys = append(keys, info.Key)
+		return nil
+	})
+	if want := []string{"exports/streamed.jsonl", "exports/transactions", "images/a/a-0.png",
+		"images/b/b-0.png"}; err != nil || !reflect.DeepEqual(keys, want) {
+		t.Errorf("List() = %v, %v, want %v", keys, err, want)
+	}
+	if got := b.URL("/exports/index.csv"); got != "s3://bucket/house/exports/exports/index.csv" {
+		t.Errorf("URL() = %q", got)
+	}
+	if got := fake.Object("bucket", "house/exports/exports/transactions").Header.Get("Content-Type"); got != "text/csv" {
+		t.Errorf("Content-Type = %q, want text/csv", got)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	m := NewMemory()
+	testBackend(t, m)
+
+	ctx := context.Background()
+	metadata := map[string]string{"sha256": "abc"}
+	if err := m.Put(ctx, "a.pdf", strings.NewReader("pdf"), &PutOptions{ContentType: "application/pdf",
+		Metadata: metadata}); err != nil {
+		t.Fatal(err)
+	}
+	metadata["sha256"] = "changed"
+	info, err := m.Stat(ctx, "a.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ContentType != "application/pdf" || info.Metadata["sha256"] != "abc" || info.ETag != "437175ba4191210ee004e1d937494d09" {
+		t.Errorf("Stat() = %+v", info)
+	}
+}
+
+func TestNamed(t *testing.T) {
+	m := NewMemory()
+	key := func(name string) string {
+		return "keys/" + strings.ToUpper(name)
+	}
+	testBackend(t, NewNamed(m, key, nil))
+
+	// objects are found by the names Named was created with, others aren't listed
+	ctx := context.Background()
+	if err := m.Put(ctx, "keys/A.PDF", strings.NewReader("pdf"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Put(ctx, "keys/B.PDF", strings.NewReader("pdf"), nil); err != nil {
+		t.Fatal(err)
+	}
+	n := NewNamed(m, key, []string{"a.pdf", "c.pdf"})
+	var names []string
+	err := n.List(ctx, "", func(info *ObjectInfo) error {
+		names = append(names, info.Key)
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(names, []string{"a.pdf"}) {
+		t.Errorf("List() = %v, %v, want [a.pdf]", names, err)
+	}
+	if info, err := n.Stat(ctx, "a.pdf"); err != nil || info.Key != "a.pdf" || info.Size != 3 {
+		t.Errorf("Stat() = %+v, %v", info, err)
+	}
+}
+
+func TestCleanKey(t *testing.T) {
+	tests := map[string]string{
+		"":                "",
+		"/":               "",
+		"a/b.csv":         "a/b.csv",
+		"/a//b.csv":       "a/b.csv",
+		"../../etc/a.csv": "etc/a.csv",
+		`a\b.csv`:         "a/b.csv",
+	}
+	for key, want := range tests {
+		if got := cleanKey(key); got != want {
+			t.Errorf("cleanKey(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local stores objects as files under Root, keys are paths relative to it
+type Local struct {
+	Root string
+}
+
+func NewLocal(root string) *Local {
+	return &Local{Root: root}
+}
+
+func (l *Local) path(key string) string {
+	return filepath.Join(l.Root, filepath.FromSlash(cleanKey(key)))
+}
+
+// Put writes the object to a temporary file first, so an interrupted write doesn't leave a partial file
+func (l *Local) Put(_ context.Context, key string, r io.Reader, _ *PutOptions) error {
+	fp := l.path(key)
+	if err := os.MkdirAll(filepath.Dir(fp), os.ModePerm); err != nil {
+		return err
+	}
+	tmpFp := fp + ".tmp"
+	f, err := os.Create(tmpFp)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpFp, fp)
+	}
+	if err != nil {
+		_ = os.Remove(tmpFp)
+	}
+	return err
+}
+
+func (l *Local) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	return os.Open(l.path(key))
+}
+
+func (l *Local) Stat(_ context.Context, key string) (*ObjectInfo, error) {
+	info, err := os.Stat(l.path(key))
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, &fs.PathError{Op: "stat", Path: l.path(key), Err: ErrNotExist}
+	}
+	return &ObjectInfo{Key: cleanKey(key), Size: info.Size(), Modified: info.ModTime()}, nil
+}
+
+// List walks the files under Root in lexical order, a missing Root has no objects
+func (l *Local) List(_ context.Context, prefix string, fn func(*ObjectInfo) error) error {
+	err := filepath.WalkDir(l.Root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) && p == l.Root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(l.Root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return fn(&ObjectInfo{Key: key, Size: info.Size(), Modified: info.ModTime()})
+	})
+	return err
+}
+
+func (l *Local) Delete(_ context.Context, key string) error {
+	err := os.Remove(l.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
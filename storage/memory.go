@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory stores objects in memory, it's safe for concurrent use
+type Memory struct {
+	mu      sync.RWMutex
+	objects map[string]*memoryObject
+}
+
+type memoryObject struct {
+	data []byte
+	info ObjectInfo
+}
+
+func NewMemory() *Memory {
+	return &Memory{objects: make(map[string]*memoryObject)}
+}
+
+func (m *Memory) Put(_ context.Context, key string, r io.Reader, opts *PutOptions) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	sum := md5.Sum(data)
+	info := ObjectInfo{
+		Key:      cleanKey(key),
+		Size:     int64(len(data)),
+		Modified: time.Now().UTC(),
+		ETag:     hex.EncodeToString(sum[:]),
+	}
+	if opts != nil {
+		info.ContentType = opts.ContentType
+		info.Metadata = maps.Clone(opts.Metadata)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[info.Key] = &memoryObject{data: data, info: info}
+	return nil
+}
+
+func (m *Memory) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	obj, ok := m.objects[cleanKey(key)]
+	if !ok {
+		return nil, &fs.PathError{Op: "get", Path: key, Err: ErrNotExist}
+	}
+	return io.NopCloser(bytes.NewReader(obj.data)), nil
+}
+
+func (m *Memory) Stat(_ context.Context, key string) (*ObjectInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	obj, ok := m.objects[cleanKey(key)]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: key, Err: ErrNotExist}
+	}
+	info := obj.info
+	info.Metadata = maps.Clone(obj.info.Metadata)
+	return &info, nil
+}
+
+// List calls fn without holding the lock, so fn can modify the backend
+func (m *Memory) List(_ context.Context, prefix string, fn func(*ObjectInfo) error) error {
+	m.mu.RLock()
+	infos := make([]*ObjectInfo, 0, len(m.objects))
+	for key, obj := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			info := obj.info
+			info.Metadata = maps.Clone(obj.info.Metadata)
+			infos = append(infos, &info)
+		}
+	}
+	m.mu.RUnlock()
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Key < infos[j].Key
+	})
+	for _, info := range infos {
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, cleanKey(key))
+	return nil
+}
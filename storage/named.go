@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Named stores objects of Backend under keys built from their names by Key, for key schemes that can't be
+// turned back into the names, like the key template of disclosure PDFs. List only returns the objects of the
+// names Named was created with or has stored, other objects of Backend have no name.
+type Named struct {
+	Backend Backend
+	Key     func(name string) string
+	mu      sync.RWMutex
+	names   map[string]string
+}
+
+func NewNamed(b Backend, key func(name string) string, names []string) *Named {
+	n := &Named{Backend: b, Key: key, names: make(map[string]string, len(names))}
+	for _, name := range names {
+		n.add(name)
+	}
+	return n
+}
+
+func (n *Named) add(name string) string {
+	name = cleanKey(name)
+	key := cleanKey(n.Key(name))
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.names[key] = name
+	return key
+}
+
+// Name returns the name of an object by its key in Backend
+func (n *Named) Name(key string) (string, bool) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	name, ok := n.names[cleanKey(key)]
+	return name, ok
+}
+
+func (n *Named) key(name string) string {
+	return n.Key(cleanKey(name))
+}
+
+func (n *Named) Put(ctx context.Context, name string, r io.Reader, opts *PutOptions) error {
+	return n.Backend.Put(ctx, n.add(name), r, opts)
+}
+
+func (n *Named) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	return n.Backend.Get(ctx, n.key(name))
+}
+
+func (n *Named) Stat(ctx context.Context, name string) (*ObjectInfo, error) {
+	info, err := n.Backend.Stat(ctx, n.key(name))
+	if err != nil {
+		return nil, err
+	}
+	info.Key = cleanKey(name)
+	return info, nil
+}
+
+// List lists every object of Backend and calls fn in name order for the ones with a name
+func (n *Named) List(ctx context.Context, prefix string, fn func(*ObjectInfo) error) error {
+	infos := make([]*ObjectInfo, 0)
+	err := n.Backend.List(ctx, "", func(info *ObjectInfo) error {
+		name, ok := n.Name(info.Key)
+		if ok && strings.HasPrefix(name, prefix) {
+			info.Key = name
+			infos = append(infos, info)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Key < infos[j].Key
+	})
+	for _, info := range infos {
+		if err = fn(info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *Named) Delete(ctx context.Context, name string) error {
+	return n.Backend.Delete(ctx, n.key(name))
+}
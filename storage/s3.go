@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// S3 stores objects in a bucket under Prefix, keys are relative to it.
+// Put streams the content through the transfer manager, which uploads large objects in parts.
+type S3 struct {
+	Client   *s3.Client
+	Uploader *manager.Uploader
+	Bucket   string
+	Prefix   string
+	// PrepareUpload is called with the input of every Put after the options are set, e.g. to set its encryption
+	PrepareUpload func(input *s3.PutObjectInput)
+}
+
+func NewS3(client *s3.Client, uploader *manager.Uploader, bucket, prefix string) *S3 {
+	return &S3{
+		Client:   client,
+		Uploader: uploader,
+		Bucket:   bucket,
+		Prefix:   cleanKey(prefix),
+	}
+}
+
+// URL returns the s3:// URL of the object
+func (b *S3) URL(key string) string {
+	return "s3://" + b.Bucket + "/" + b.objectKey(key)
+}
+
+// objectKey returns the key in the bucket
+func (b *S3) objectKey(key string) string {
+	return path.Join(b.Prefix, cleanKey(key))
+}
+
+// relKey returns the key relative to the prefix
+func (b *S3) relKey(objectKey string) string {
+	if b.Prefix == "" {
+		return objectKey
+	}
+	return strings.TrimPrefix(objectKey, b.Prefix+"/")
+}
+
+func (b *S3) Put(ctx context.Context, key string, r io.Reader, opts *PutOptions) error {
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(b.Bucket),
+		Key:    aws.String(b.objectKey(key)),
+		Body:   r,
+	}
+	if opts != nil {
+		if opts.ContentType != "" {
+			input.ContentType = aws.String(opts.ContentType)
+		}
+		input.StorageClass = types.StorageClass(opts.StorageClass)
+		input.Metadata = opts.Metadata
+	}
+	if b.PrepareUpload != nil {
+		b.PrepareUpload(input)
+	}
+	_, err := b.Uploader.Upload(ctx, input)
+	return err
+}
+
+func (b *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := b.Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.Bucket),
+		Key:    aws.String(b.objectKey(key)),
+	})
+	if err != nil {
+		return nil, notExist("get", key, err)
+	}
+	return out.Body, nil
+}
+
+func (b *S3) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	out, err := b.Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(b.Bucket),
+		Key:    aws.String(b.objectKey(key)),
+	})
+	if err != nil {
+		return nil, notExist("stat", key, err)
+	}
+	return &ObjectInfo{
+		Key:         cleanKey(key),
+		Size:        aws.ToInt64(out.ContentLength),
+		Modified:    aws.ToTime(out.LastModified),
+		ETag:        strings.Trim(aws.ToString(out.ETag), `"`),
+		ContentType: aws.ToString(out.ContentType),
+		Metadata:    out.Metadata,
+	}, nil
+}
+
+// List lists the objects page by page, content type and metadata are only returned by Stat
+func (b *S3) List(ctx context.Context, prefix string, fn func(*ObjectInfo) error) error {
+	listPrefix := prefix
+	if b.Prefix != "" {
+		listPrefix = b.Prefix + "/" + prefix
+	}
+	p := s3.NewListObjectsV2Paginator(b.Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(b.Bucket),
+		Prefix: aws.String(listPrefix),
+	})
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, obj := range page.Contents {
+			err = fn(&ObjectInfo{
+				Key:      b.relKey(aws.ToString(obj.Key)),
+				Size:     aws.ToInt64(obj.Size),
+				Modified: aws.ToTime(obj.LastModified),
+				ETag:     strings.Trim(aws.ToString(obj.ETag), `"`),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (b *S3) Delete(ctx context.Context, key string) error {
+	_, err := b.Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(b.Bucket),
+		Key:    aws.String(b.objectKey(key)),
+	})
+	return err
+}
+
+// notExist wraps the error of a missing object in ErrNotExist. HeadObject returns NotFound, as the response
+// has no body, GetObject NoSuchKey.
+func notExist(op, key string, err error) error {
+	var apiError smithy.APIError
+	if errors.As(err, &apiError) {
+		switch apiError.ErrorCode() {
+		case "NotFound", "NoSuchKey":
+			return &fs.PathError{Op: op, Path: key, Err: ErrNotExist}
+		}
+	}
+	return err
+}